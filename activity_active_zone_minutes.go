@@ -1,8 +1,9 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"time"
 )
 
 // ActiveZoneMinutesDay contains the active zone minutes for a given day
@@ -10,49 +11,99 @@ type ActiveZoneMinutesDay struct {
 	ActivitiesActiveZoneMinutes []struct {
 		DateTime string `json:"dateTime"`
 		Value    struct {
-			FatBurnActiveZoneMinutes int `json:"fatBurnActiveZoneMinutes"`
+			FatBurnActiveZoneMinutes int `json:"fatBurnActiveZoneMinutes,omitempty"`
+			CardioActiveZoneMinutes  int `json:"cardioActiveZoneMinutes,omitempty"`
+			PeakActiveZoneMinutes    int `json:"peakActiveZoneMinutes,omitempty"`
 			ActiveZoneMinutes        int `json:"activeZoneMinutes"`
 		} `json:"value"`
 	} `json:"activities-active-zone-minutes"`
 }
 
 // ActiveZoneMinutesLogByDay returns the active zone minutes log by a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) ActiveZoneMinutesLogByDay(day string) (ActiveZoneMinutesDay, error) {
-	// If not day is given assume today
-	if day == "" {
-		day = "today"
+	return m.ActiveZoneMinutesLogByDayContext(context.Background(), day)
+}
+
+// ActiveZoneMinutesLogByDayContext is like ActiveZoneMinutesLogByDay but uses ctx for the request
+func (m *Session) ActiveZoneMinutesLogByDayContext(ctx context.Context, day string) (ActiveZoneMinutesDay, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return ActiveZoneMinutesDay{}, err
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/heart/date/%s/1d.json", day))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/heart/date/%s/1d.json", m.apiURL, day))
 	if err != nil {
 		return ActiveZoneMinutesDay{}, err
 	}
 
 	azm := ActiveZoneMinutesDay{}
-	if err := json.Unmarshal(contents, &azm); err != nil {
+	if err := m.decode("ActiveZoneMinutesLogByDay", contents, &azm); err != nil {
 		return ActiveZoneMinutesDay{}, err
 	}
 
 	return azm, nil
 }
 
+// ActiveZoneMinutesLogOn is like ActiveZoneMinutesLogByDay but takes a Date
+func (m *Session) ActiveZoneMinutesLogOn(day Date) (ActiveZoneMinutesDay, error) {
+	return m.ActiveZoneMinutesLogOnContext(context.Background(), day)
+}
+
+// ActiveZoneMinutesLogOnContext is like ActiveZoneMinutesLogOn but uses ctx for the request
+func (m *Session) ActiveZoneMinutesLogOnContext(ctx context.Context, day Date) (ActiveZoneMinutesDay, error) {
+	if err := checkDates(day); err != nil {
+		return ActiveZoneMinutesDay{}, err
+	}
+	return m.ActiveZoneMinutesLogByDayContext(ctx, day.String())
+}
+
 // ActiveZoneMinutesLogByDateRange returns the active zone minutes log by a given date
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysActiveZoneMinutes days are split into multiple requests
 func (m *Session) ActiveZoneMinutesLogByDateRange(startDay string, endDay string) (ActiveZoneMinutesDay, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/heart/date/%s/%s.json", startDay, endDay))
+	return m.ActiveZoneMinutesLogByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// ActiveZoneMinutesLogByDateRangeContext is like ActiveZoneMinutesLogByDateRange but uses ctx for the request
+func (m *Session) ActiveZoneMinutesLogByDateRangeContext(ctx context.Context, startDay string, endDay string) (ActiveZoneMinutesDay, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return ActiveZoneMinutesDay{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysActiveZoneMinutes, m.activeZoneMinutesLogByDateRange)
+}
+
+// ActiveZoneMinutesLogBetween is like ActiveZoneMinutesLogByDateRange but takes Dates
+func (m *Session) ActiveZoneMinutesLogBetween(startDay Date, endDay Date) (ActiveZoneMinutesDay, error) {
+	return m.ActiveZoneMinutesLogBetweenContext(context.Background(), startDay, endDay)
+}
+
+// ActiveZoneMinutesLogBetweenContext is like ActiveZoneMinutesLogBetween but uses ctx for the request
+func (m *Session) ActiveZoneMinutesLogBetweenContext(ctx context.Context, startDay Date, endDay Date) (ActiveZoneMinutesDay, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return ActiveZoneMinutesDay{}, err
+	}
+	return m.ActiveZoneMinutesLogByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// activeZoneMinutesLogByDateRange requests a single date range
+func (m *Session) activeZoneMinutesLogByDateRange(ctx context.Context, startDay string, endDay string) (ActiveZoneMinutesDay, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/heart/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return ActiveZoneMinutesDay{}, err
 	}
 
 	azm := ActiveZoneMinutesDay{}
-	if err := json.Unmarshal(contents, &azm); err != nil {
+	if err := m.decode("ActiveZoneMinutesLogByDateRange", contents, &azm); err != nil {
 		return ActiveZoneMinutesDay{}, err
 	}
 
 	return azm, nil
 }
 
+// ActiveZoneMinutesIntraday contains the active zone minutes for a given day in intraday accuracy
 type ActiveZoneMinutesIntraday struct {
 	ActivitiesActiveZoneMinutesIntraday []struct {
 		DateTime string `json:"dateTime"`
@@ -60,6 +111,8 @@ type ActiveZoneMinutesIntraday struct {
 			Minute string `json:"minute"`
 			Value  struct {
 				FatBurnActiveZoneMinutes int `json:"fatBurnActiveZoneMinutes,omitempty"`
+				CardioActiveZoneMinutes  int `json:"cardioActiveZoneMinutes,omitempty"`
+				PeakActiveZoneMinutes    int `json:"peakActiveZoneMinutes,omitempty"`
 				ActiveZoneMinutes        int `json:"activeZoneMinutes"`
 			} `json:"value,omitempty"`
 		} `json:"minutes"`
@@ -70,9 +123,14 @@ type ActiveZoneMinutesIntraday struct {
 // date must be in the format yyyy-MM-dd
 // resolution can be 1min, 5min, or 15min 1min is default
 func (m *Session) ActiveZoneMinutesIntraday(day string, resolution string) (ActiveZoneMinutesIntraday, error) {
-	// If not day is given assume today
-	if day == "" {
-		day = "today"
+	return m.ActiveZoneMinutesIntradayContext(context.Background(), day, resolution)
+}
+
+// ActiveZoneMinutesIntradayContext is like ActiveZoneMinutesIntraday but uses ctx for the request
+func (m *Session) ActiveZoneMinutesIntradayContext(ctx context.Context, day string, resolution string) (ActiveZoneMinutesIntraday, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return ActiveZoneMinutesIntraday{}, err
 	}
 
 	// default to 1sec if resolution dos not match to 1min
@@ -80,37 +138,113 @@ func (m *Session) ActiveZoneMinutesIntraday(day string, resolution string) (Acti
 		resolution = "1min"
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/active-zone-minutes/date/%s/1d/%s.json", day, resolution))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/active-zone-minutes/date/%s/1d/%s.json", m.apiURL, day, resolution))
 	if err != nil {
 		return ActiveZoneMinutesIntraday{}, err
 	}
 
 	azm := ActiveZoneMinutesIntraday{}
-	if err := json.Unmarshal(contents, &azm); err != nil {
+	if err := m.decode("ActiveZoneMinutesIntraday", contents, &azm); err != nil {
 		return ActiveZoneMinutesIntraday{}, err
 	}
 
 	return azm, nil
 }
 
+// ActiveZoneMinutesIntradayOn is like ActiveZoneMinutesIntraday but takes a Date
+func (m *Session) ActiveZoneMinutesIntradayOn(day Date, resolution string) (ActiveZoneMinutesIntraday, error) {
+	return m.ActiveZoneMinutesIntradayOnContext(context.Background(), day, resolution)
+}
+
+// ActiveZoneMinutesIntradayOnContext is like ActiveZoneMinutesIntradayOn but uses ctx for the request
+func (m *Session) ActiveZoneMinutesIntradayOnContext(ctx context.Context, day Date, resolution string) (ActiveZoneMinutesIntraday, error) {
+	if err := checkDates(day); err != nil {
+		return ActiveZoneMinutesIntraday{}, err
+	}
+	return m.ActiveZoneMinutesIntradayContext(ctx, day.String(), resolution)
+}
+
 // ActiveZoneMinutesIntradayByDateRange returns the active zone minutes log intraday by a given date in the given resolution
 // date must be in the format yyyy-MM-dd
 // resolution can be 1min, 5min, or 15min 1min is default
+// ranges longer than MaxDaysIntraday days are split into multiple requests
 func (m *Session) ActiveZoneMinutesIntradayByDateRange(startDay string, endDay string, resolution string) (ActiveZoneMinutesIntraday, error) {
+	return m.ActiveZoneMinutesIntradayByDateRangeContext(context.Background(), startDay, endDay, resolution)
+}
+
+// ActiveZoneMinutesIntradayByDateRangeContext is like ActiveZoneMinutesIntradayByDateRange but uses ctx for the request
+func (m *Session) ActiveZoneMinutesIntradayByDateRangeContext(ctx context.Context, startDay string, endDay string, resolution string) (ActiveZoneMinutesIntraday, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return ActiveZoneMinutesIntraday{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysIntraday, func(ctx context.Context, startDay string, endDay string) (ActiveZoneMinutesIntraday, error) {
+		return m.activeZoneMinutesIntradayByDateRange(ctx, startDay, endDay, resolution)
+	})
+}
+
+// ActiveZoneMinutesIntradayBetween is like ActiveZoneMinutesIntradayByDateRange but takes Dates
+func (m *Session) ActiveZoneMinutesIntradayBetween(startDay Date, endDay Date, resolution string) (ActiveZoneMinutesIntraday, error) {
+	return m.ActiveZoneMinutesIntradayBetweenContext(context.Background(), startDay, endDay, resolution)
+}
+
+// ActiveZoneMinutesIntradayBetweenContext is like ActiveZoneMinutesIntradayBetween but uses ctx for the request
+func (m *Session) ActiveZoneMinutesIntradayBetweenContext(ctx context.Context, startDay Date, endDay Date, resolution string) (ActiveZoneMinutesIntraday, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return ActiveZoneMinutesIntraday{}, err
+	}
+	return m.ActiveZoneMinutesIntradayByDateRangeContext(ctx, startDay.String(), endDay.String(), resolution)
+}
+
+// activeZoneMinutesIntradayByDateRange requests a single date range
+func (m *Session) activeZoneMinutesIntradayByDateRange(ctx context.Context, startDay string, endDay string, resolution string) (ActiveZoneMinutesIntraday, error) {
 	// default to 1sec if resolution dos not match to 1min
 	if resolution != "5min" && resolution != "15min" {
 		resolution = "1min"
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/active-zone-minutes/date/%s/%s/%s.json", startDay, endDay, resolution))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/active-zone-minutes/date/%s/%s/%s.json", m.apiURL, startDay, endDay, resolution))
 	if err != nil {
 		return ActiveZoneMinutesIntraday{}, err
 	}
 
 	azm := ActiveZoneMinutesIntraday{}
-	if err := json.Unmarshal(contents, &azm); err != nil {
+	if err := m.decode("ActiveZoneMinutesIntradayByDateRange", contents, &azm); err != nil {
 		return ActiveZoneMinutesIntraday{}, err
 	}
 
 	return azm, nil
 }
+
+// Series returns the active zone minutes and fat burn active zone minutes as normalized time series
+// loc is the timezone of the user
+func (a ActiveZoneMinutesDay) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, day := range a.ActivitiesActiveZoneMinutes {
+		t, err := b.parseTime(day.DateTime)
+		if err != nil {
+			return nil, err
+		}
+		b.add(MetricActiveZoneMinutes, false, t, float64(day.Value.ActiveZoneMinutes), UnitMinutes)
+		b.add(MetricFatBurnActiveZoneMinutes, false, t, float64(day.Value.FatBurnActiveZoneMinutes), UnitMinutes)
+	}
+	return b.result(), nil
+}
+
+// Series returns the intraday active zone minutes and fat burn active zone minutes as normalized time series
+// loc is the timezone of the user
+func (a ActiveZoneMinutesIntraday) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, day := range a.ActivitiesActiveZoneMinutesIntraday {
+		for _, minute := range day.Minutes {
+			t, err := b.parseTime(minute.Minute)
+			if err != nil {
+				return nil, err
+			}
+			b.add(MetricActiveZoneMinutes, true, t, float64(minute.Value.ActiveZoneMinutes), UnitMinutes)
+			b.add(MetricFatBurnActiveZoneMinutes, true, t, float64(minute.Value.FatBurnActiveZoneMinutes), UnitMinutes)
+		}
+	}
+	return b.result(), nil
+}
@@ -1,7 +1,7 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 )
 
 // ActivitiesFrequent contains an activity from frequent call
@@ -16,13 +16,18 @@ type ActivitiesFrequent struct {
 
 // ActivityFrequent returns a list of frequent user activities
 func (m *Session) ActivityFrequent() ([]ActivitiesFrequent, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/activities/frequent.json")
+	return m.ActivityFrequentContext(context.Background())
+}
+
+// ActivityFrequentContext is like ActivityFrequent but uses ctx for the request
+func (m *Session) ActivityFrequentContext(ctx context.Context) ([]ActivitiesFrequent, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/activities/frequent.json")
 	if err != nil {
 		return []ActivitiesFrequent{}, err
 	}
 
 	activities := []ActivitiesFrequent{}
-	if err := json.Unmarshal(contents, &activities); err != nil {
+	if err := m.decode("ActivityFrequent", contents, &activities); err != nil {
 		return []ActivitiesFrequent{}, err
 	}
 
@@ -31,13 +36,18 @@ func (m *Session) ActivityFrequent() ([]ActivitiesFrequent, error) {
 
 // ActivityRecent returns a list of fRecent user activities
 func (m *Session) ActivityRecent() ([]ActivitiesFrequent, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/activities/recent.json")
+	return m.ActivityRecentContext(context.Background())
+}
+
+// ActivityRecentContext is like ActivityRecent but uses ctx for the request
+func (m *Session) ActivityRecentContext(ctx context.Context) ([]ActivitiesFrequent, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/activities/recent.json")
 	if err != nil {
 		return []ActivitiesFrequent{}, err
 	}
 
 	activities := []ActivitiesFrequent{}
-	if err := json.Unmarshal(contents, &activities); err != nil {
+	if err := m.decode("ActivityRecent", contents, &activities); err != nil {
 		return []ActivitiesFrequent{}, err
 	}
 
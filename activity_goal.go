@@ -1,7 +1,7 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strconv"
 )
@@ -9,27 +9,33 @@ import (
 // ActivitiesGoal contains the activities goal of an user
 type ActivitiesGoal struct {
 	Goals struct {
-		ActiveMinutes int     `json:"activeMinutes,omitempty"`
-		CaloriesOut   int     `json:"caloriesOut,omitempty"`
-		Distance      float64 `json:"distance"`
-		Floors        int     `json:"floors"`
-		Steps         int     `json:"steps"`
+		ActiveMinutes     int     `json:"activeMinutes,omitempty"`
+		ActiveZoneMinutes int     `json:"activeZoneMinutes,omitempty"`
+		CaloriesOut       int     `json:"caloriesOut,omitempty"`
+		Distance          float64 `json:"distance"`
+		Floors            int     `json:"floors"`
+		Steps             int     `json:"steps"`
 	} `json:"goals"`
 }
 
 // ActivitiesGoal returns user set activities goal
 func (m *Session) ActivitiesGoal(period string) (ActivitiesGoal, error) {
+	return m.ActivitiesGoalContext(context.Background(), period)
+}
+
+// ActivitiesGoalContext is like ActivitiesGoal but uses ctx for the request
+func (m *Session) ActivitiesGoalContext(ctx context.Context, period string) (ActivitiesGoal, error) {
 	if period != "weekly" {
 		period = "daily"
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/goals/%s.json", period))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/goals/%s.json", m.apiURL, period))
 	if err != nil {
 		return ActivitiesGoal{}, err
 	}
 
 	summary := ActivitiesGoal{}
-	if err := json.Unmarshal(contents, &summary); err != nil {
+	if err := m.decode("ActivitiesGoal", contents, &summary); err != nil {
 		return ActivitiesGoal{}, err
 	}
 
@@ -38,6 +44,11 @@ func (m *Session) ActivitiesGoal(period string) (ActivitiesGoal, error) {
 
 // SetActivitiesGoal sets a new activities goal on daily or weekly basis
 func (m *Session) SetActivitiesGoal(period string, goals ActivitiesGoal) (ActivitiesGoal, error) {
+	return m.SetActivitiesGoalContext(context.Background(), period, goals)
+}
+
+// SetActivitiesGoalContext is like SetActivitiesGoal but uses ctx for the request
+func (m *Session) SetActivitiesGoalContext(ctx context.Context, period string, goals ActivitiesGoal) (ActivitiesGoal, error) {
 	if period != "weekly" {
 		period = "daily"
 	}
@@ -60,13 +71,13 @@ func (m *Session) SetActivitiesGoal(period string, goals ActivitiesGoal) (Activi
 		goalsData["distance"] = fmt.Sprintf("%f", goals.Goals.Distance)
 	}
 
-	contents, err := m.makePOSTRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/goals/%s.json", period), goalsData)
+	contents, err := m.makePOSTRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/goals/%s.json", m.apiURL, period), goalsData)
 	if err != nil {
 		return ActivitiesGoal{}, err
 	}
 
 	summary := ActivitiesGoal{}
-	if err := json.Unmarshal(contents, &summary); err != nil {
+	if err := m.decode("SetActivitiesGoal", contents, &summary); err != nil {
 		return ActivitiesGoal{}, err
 	}
 
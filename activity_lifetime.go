@@ -1,6 +1,8 @@
 package fitbit
 
-import "encoding/json"
+import (
+	"context"
+)
 
 // ActivitiesLifetime contains the account lifetime statistics
 type ActivitiesLifetime struct {
@@ -55,13 +57,18 @@ type ActivitiesLifetime struct {
 // ActivitiesLifetime returns the summary of activities and made exercises
 // date must be in the format yyyy-MM-dd
 func (m *Session) ActivitiesLifetime() (ActivitiesLifetime, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/activities.json")
+	return m.ActivitiesLifetimeContext(context.Background())
+}
+
+// ActivitiesLifetimeContext is like ActivitiesLifetime but uses ctx for the request
+func (m *Session) ActivitiesLifetimeContext(ctx context.Context) (ActivitiesLifetime, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/activities.json")
 	if err != nil {
 		return ActivitiesLifetime{}, err
 	}
 
 	summary := ActivitiesLifetime{}
-	if err := json.Unmarshal(contents, &summary); err != nil {
+	if err := m.decode("ActivitiesLifetime", contents, &summary); err != nil {
 		return ActivitiesLifetime{}, err
 	}
 
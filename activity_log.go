@@ -1,9 +1,10 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 	"time"
@@ -11,35 +12,38 @@ import (
 
 // ActivityLog returns the activity log for the given configuration
 func (m *Session) ActivityLog(params LogListParameters) (ActivitiesLogList, error) {
-	parameterList := url.Values{}
+	return m.ActivityLogContext(context.Background(), params)
+}
 
-	//nolint:gocritic
-	if params.BeforeDate != "" {
-		parameterList.Add("beforeDate", params.BeforeDate)
-		parameterList.Add("sort", "desc")
-	} else if params.AfterDate != "" {
-		parameterList.Add("afterDate", params.BeforeDate)
-		parameterList.Add("sort", "asc")
-	} else {
-		return ActivitiesLogList{}, errors.New("beforeDate or afterDate must be given")
+// ActivityLogContext is like ActivityLog but uses ctx for the request
+func (m *Session) ActivityLogContext(ctx context.Context, params LogListParameters) (ActivitiesLogList, error) {
+	parameterList, err := params.query(20)
+	if err != nil {
+		return ActivitiesLogList{}, err
 	}
 
-	if params.Limit > 0 {
-		if params.Limit > 20 {
-			return ActivitiesLogList{}, errors.New("limit must be 20 or less")
-		}
-		parameterList.Add("limit", strconv.Itoa(params.Limit))
-	}
+	return m.activityLogPage(ctx, parameterList)
+}
 
-	parameterList.Add("offset", strconv.Itoa(params.Offset))
+// ActivityLogIter returns an iterator over all activities of the activity log starting at the given parameters
+// Following pages are requested on demand until all activities are returned or the iteration is stopped
+func (m *Session) ActivityLogIter(ctx context.Context, params LogListParameters) iter.Seq2[ActivityLogEntry, error] {
+	parameterList, err := params.query(20)
+	return paginate(ctx, parameterList, err, func(ctx context.Context, query url.Values) ([]ActivityLogEntry, string, error) {
+		page, err := m.activityLogPage(ctx, query)
+		return page.Activities, page.Pagination.Next, err
+	})
+}
 
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/activities/list.json?" + parameterList.Encode())
+// activityLogPage requests a single page of the activity log list
+func (m *Session) activityLogPage(ctx context.Context, parameterList url.Values) (ActivitiesLogList, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/activities/list.json?"+parameterList.Encode())
 	if err != nil {
 		return ActivitiesLogList{}, err
 	}
 
 	activityResponse := ActivitiesLogList{}
-	if err := json.Unmarshal(contents, &activityResponse); err != nil {
+	if err := m.decode("ActivityLog", contents, &activityResponse); err != nil {
 		return ActivitiesLogList{}, err
 	}
 
@@ -54,68 +58,36 @@ type LogListParameters struct {
 	Offset     int
 }
 
+// query returns the query parameters of a log list request, limit is the maximum limit of the endpoint
+func (params LogListParameters) query(limit int) (url.Values, error) {
+	parameterList := url.Values{}
+
+	//nolint:gocritic
+	if params.BeforeDate != "" {
+		parameterList.Add("beforeDate", params.BeforeDate)
+		parameterList.Add("sort", "desc")
+	} else if params.AfterDate != "" {
+		parameterList.Add("afterDate", params.AfterDate)
+		parameterList.Add("sort", "asc")
+	} else {
+		return nil, errors.New("beforeDate or afterDate must be given")
+	}
+
+	if params.Limit > 0 {
+		if params.Limit > limit {
+			return nil, fmt.Errorf("limit must be %d or less", limit)
+		}
+		parameterList.Add("limit", strconv.Itoa(params.Limit))
+	}
+
+	parameterList.Add("offset", strconv.Itoa(params.Offset))
+
+	return parameterList, nil
+}
+
 // ActivitiesLogList contains the activity log list
 type ActivitiesLogList struct {
-	Activities []struct {
-		ActiveDuration    int `json:"activeDuration"`
-		ActiveZoneMinutes struct {
-			MinutesInHeartRateZones []struct {
-				MinuteMultiplier int    `json:"minuteMultiplier"`
-				Minutes          int    `json:"minutes"`
-				Order            int    `json:"order"`
-				Type             string `json:"type"`
-				ZoneName         string `json:"zoneName"`
-			} `json:"minutesInHeartRateZones"`
-		} `json:"activeZoneMinutes"`
-		ActivityLevel []struct {
-			Minutes int    `json:"minutes"`
-			Name    string `json:"name"`
-		} `json:"activityLevel"`
-		ActivityName          string    `json:"activityName"`
-		ActivityTypeID        int       `json:"activityTypeId"`
-		Calories              int       `json:"calories"`
-		CaloriesLink          string    `json:"caloriesLink"`
-		Distance              float64   `json:"distance"`
-		DistanceUnit          string    `json:"distanceUnit"`
-		Duration              int       `json:"duration"`
-		ElevationGain         float64   `json:"elevationGain"`
-		HasActiveZoneMinutes  bool      `json:"hasActiveZoneMinutes"`
-		LastModified          time.Time `json:"lastModified"`
-		LogID                 int64     `json:"logId"`
-		LogType               string    `json:"logType"`
-		ManualValuesSpecified struct {
-			Calories bool `json:"calories"`
-			Distance bool `json:"distance"`
-			Steps    bool `json:"steps"`
-		} `json:"manualValuesSpecified"`
-		OriginalDuration  int       `json:"originalDuration"`
-		OriginalStartTime time.Time `json:"originalStartTime"`
-		PoolLength        int       `json:"poolLength,omitempty"`
-		PoolLengthUnit    string    `json:"poolLengthUnit,omitempty"`
-		Source            struct {
-			ID              string   `json:"id"`
-			Name            string   `json:"name"`
-			TrackerFeatures []string `json:"trackerFeatures"`
-			Type            string   `json:"type"`
-			URL             string   `json:"url"`
-		} `json:"source"`
-		Speed            float64   `json:"speed"`
-		StartTime        time.Time `json:"startTime"`
-		SwimLengths      int       `json:"swimLengths,omitempty"`
-		AverageHeartRate int       `json:"averageHeartRate,omitempty"`
-		DetailsLink      string    `json:"detailsLink,omitempty"`
-		HeartRateLink    string    `json:"heartRateLink,omitempty"`
-		HeartRateZones   []struct {
-			CaloriesOut float64 `json:"caloriesOut"`
-			Max         int     `json:"max"`
-			Min         int     `json:"min"`
-			Minutes     int     `json:"minutes"`
-			Name        string  `json:"name"`
-		} `json:"heartRateZones,omitempty"`
-		Pace    float64 `json:"pace,omitempty"`
-		Steps   int     `json:"steps,omitempty"`
-		TcxLink string  `json:"tcxLink,omitempty"`
-	} `json:"activities"`
+	Activities []ActivityLogEntry `json:"activities"`
 	Pagination struct {
 		BeforeDate string `json:"beforeDate"`
 		Limit      int    `json:"limit"`
@@ -126,10 +98,78 @@ type ActivitiesLogList struct {
 	} `json:"pagination"`
 }
 
+// ActivityLogEntry contains a single activity of the activity log list
+type ActivityLogEntry struct {
+	ActiveDuration    int `json:"activeDuration"`
+	ActiveZoneMinutes struct {
+		MinutesInHeartRateZones []struct {
+			MinuteMultiplier int    `json:"minuteMultiplier"`
+			Minutes          int    `json:"minutes"`
+			Order            int    `json:"order"`
+			Type             string `json:"type"`
+			ZoneName         string `json:"zoneName"`
+		} `json:"minutesInHeartRateZones"`
+		TotalMinutes int `json:"totalMinutes"`
+	} `json:"activeZoneMinutes"`
+	ActivityLevel []struct {
+		Minutes int    `json:"minutes"`
+		Name    string `json:"name"`
+	} `json:"activityLevel"`
+	ActivityName          string    `json:"activityName"`
+	ActivityTypeID        int       `json:"activityTypeId"`
+	Calories              int       `json:"calories"`
+	CaloriesLink          string    `json:"caloriesLink"`
+	Distance              float64   `json:"distance"`
+	DistanceUnit          string    `json:"distanceUnit"`
+	Duration              int       `json:"duration"`
+	ElevationGain         float64   `json:"elevationGain"`
+	HasActiveZoneMinutes  bool      `json:"hasActiveZoneMinutes"`
+	LastModified          time.Time `json:"lastModified"`
+	LogID                 int64     `json:"logId"`
+	LogType               string    `json:"logType"`
+	ManualValuesSpecified struct {
+		Calories bool `json:"calories"`
+		Distance bool `json:"distance"`
+		Steps    bool `json:"steps"`
+	} `json:"manualValuesSpecified"`
+	OriginalDuration  int       `json:"originalDuration"`
+	OriginalStartTime time.Time `json:"originalStartTime"`
+	PoolLength        int       `json:"poolLength,omitempty"`
+	PoolLengthUnit    string    `json:"poolLengthUnit,omitempty"`
+	Source            struct {
+		ID              string   `json:"id"`
+		Name            string   `json:"name"`
+		TrackerFeatures []string `json:"trackerFeatures"`
+		Type            string   `json:"type"`
+		URL             string   `json:"url"`
+	} `json:"source"`
+	Speed            float64   `json:"speed"`
+	StartTime        time.Time `json:"startTime"`
+	SwimLengths      int       `json:"swimLengths,omitempty"`
+	AverageHeartRate int       `json:"averageHeartRate,omitempty"`
+	DetailsLink      string    `json:"detailsLink,omitempty"`
+	HeartRateLink    string    `json:"heartRateLink,omitempty"`
+	HeartRateZones   []struct {
+		CaloriesOut float64 `json:"caloriesOut"`
+		Max         int     `json:"max"`
+		Min         int     `json:"min"`
+		Minutes     int     `json:"minutes"`
+		Name        string  `json:"name"`
+	} `json:"heartRateZones,omitempty"`
+	Pace    float64 `json:"pace,omitempty"`
+	Steps   int     `json:"steps,omitempty"`
+	TcxLink string  `json:"tcxLink,omitempty"`
+}
+
 // LogActivity logs a new activity
 // date must be in the format yyyy-MM-dd
 // TODO: TESTME
 func (m *Session) LogActivity(activity NewActivity) (NewActivityResponse, error) {
+	return m.LogActivityContext(context.Background(), activity)
+}
+
+// LogActivityContext is like LogActivity but uses ctx for the request
+func (m *Session) LogActivityContext(ctx context.Context, activity NewActivity) (NewActivityResponse, error) {
 	postData := make(map[string]string)
 
 	//nolint:gocritic
@@ -151,6 +191,9 @@ func (m *Session) LogActivity(activity NewActivity) (NewActivityResponse, error)
 	} else {
 		return NewActivityResponse{}, errors.New("startTime must be given")
 	}
+	if err := checkTimeOfDay(activity.StartTime); err != nil {
+		return NewActivityResponse{}, err
+	}
 
 	if activity.DurationMillis > 0 {
 		postData["durationMillis"] = strconv.FormatInt(activity.DurationMillis, 10)
@@ -163,6 +206,9 @@ func (m *Session) LogActivity(activity NewActivity) (NewActivityResponse, error)
 	} else {
 		return NewActivityResponse{}, errors.New("date must be given")
 	}
+	if err := checkPostDate(activity.Date); err != nil {
+		return NewActivityResponse{}, err
+	}
 
 	if activity.Distance > 0 {
 		postData["distance"] = strconv.FormatFloat(activity.Distance, 'f', 3, 64)
@@ -171,13 +217,13 @@ func (m *Session) LogActivity(activity NewActivity) (NewActivityResponse, error)
 		postData["distanceUnit"] = activity.DistanceUnit
 	}
 
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1/user/-/activities.json", postData)
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1/user/-/activities.json", postData)
 	if err != nil {
 		return NewActivityResponse{}, err
 	}
 
 	activityResponse := NewActivityResponse{}
-	if err := json.Unmarshal(contents, &activityResponse); err != nil {
+	if err := m.decode("LogActivity", contents, &activityResponse); err != nil {
 		return NewActivityResponse{}, err
 	}
 
@@ -198,27 +244,43 @@ type NewActivity struct {
 	DistanceUnit   string  `json:"distanceUnit,omitempty"` // Steps units are available only for "Walking" (activityId=90013) and "Running" (activityId=90009) directory activities and their intensity levels.
 }
 
+// SetStart sets Date and StartTime of the activity, a zero date is rejected by LogActivity
+func (a *NewActivity) SetStart(date Date, start TimeOfDay) {
+	a.Date = date.String()
+	a.StartTime = start.String()
+}
+
 // NewActivityResponse contains the response from a new activity request
 type NewActivityResponse struct {
 	ActivityLog struct {
-		ActivityID       int     `json:"activityId"`
-		ActivityParentID int     `json:"activityParentId"`
-		Calories         int     `json:"calories"`
-		Description      string  `json:"description"`
-		Distance         float64 `json:"distance"`
-		Duration         int     `json:"duration"`
-		IsFavorite       bool    `json:"isFavorite"`
-		LogID            int64   `json:"logId"`
-		Name             string  `json:"name"`
-		StartTime        string  `json:"startTime"`
-		Steps            int     `json:"steps"`
+		ActivityID           int       `json:"activityId"`
+		ActivityParentID     int       `json:"activityParentId"`
+		ActivityParentName   string    `json:"activityParentName"`
+		Calories             int       `json:"calories"`
+		Description          string    `json:"description"`
+		Distance             float64   `json:"distance"`
+		Duration             int       `json:"duration"`
+		HasActiveZoneMinutes bool      `json:"hasActiveZoneMinutes"`
+		HasStartTime         bool      `json:"hasStartTime"`
+		IsFavorite           bool      `json:"isFavorite"`
+		LastModified         time.Time `json:"lastModified"`
+		LogID                int64     `json:"logId"`
+		Name                 string    `json:"name"`
+		StartDate            string    `json:"startDate"`
+		StartTime            string    `json:"startTime"`
+		Steps                int       `json:"steps"`
 	} `json:"activityLog"`
 }
 
 // RemoveActivity deletes an existing activity by activity log id
 // TODO: TESTME
 func (m *Session) RemoveActivity(id int) error {
-	_, err := m.makeDELETERequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/%d.json", id))
+	return m.RemoveActivityContext(context.Background(), id)
+}
+
+// RemoveActivityContext is like RemoveActivity but uses ctx for the request
+func (m *Session) RemoveActivityContext(ctx context.Context, id int) error {
+	_, err := m.makeDELETERequest(ctx, fmt.Sprintf("%s/1/user/-/activities/%d.json", m.apiURL, id))
 	if err != nil {
 		return err
 	}
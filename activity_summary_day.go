@@ -1,7 +1,7 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"time"
 )
@@ -66,17 +66,40 @@ type ActivitiesSummaryDay struct {
 }
 
 // ActivitiesDaySummary returns the summary of activities and made exercises
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) ActivitiesDaySummary(day string) (ActivitiesSummaryDay, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/date/%s.json", day))
+	return m.ActivitiesDaySummaryContext(context.Background(), day)
+}
+
+// ActivitiesDaySummaryContext is like ActivitiesDaySummary but uses ctx for the request
+func (m *Session) ActivitiesDaySummaryContext(ctx context.Context, day string) (ActivitiesSummaryDay, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return ActivitiesSummaryDay{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return ActivitiesSummaryDay{}, err
 	}
 
 	summary := ActivitiesSummaryDay{}
-	if err := json.Unmarshal(contents, &summary); err != nil {
+	if err := m.decode("ActivitiesDaySummary", contents, &summary); err != nil {
 		return ActivitiesSummaryDay{}, err
 	}
 
 	return summary, nil
 }
+
+// ActivitiesDaySummaryOn is like ActivitiesDaySummary but takes a Date
+func (m *Session) ActivitiesDaySummaryOn(day Date) (ActivitiesSummaryDay, error) {
+	return m.ActivitiesDaySummaryOnContext(context.Background(), day)
+}
+
+// ActivitiesDaySummaryOnContext is like ActivitiesDaySummaryOn but uses ctx for the request
+func (m *Session) ActivitiesDaySummaryOnContext(ctx context.Context, day Date) (ActivitiesSummaryDay, error) {
+	if err := checkDates(day); err != nil {
+		return ActivitiesSummaryDay{}, err
+	}
+	return m.ActivitiesDaySummaryContext(ctx, day.String())
+}
@@ -1,18 +1,24 @@
 package fitbit
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
 
 // ActivityTCX returns the activity TCX for the given activity
 func (m *Session) ActivityTCX(logID int64) ([]byte, error) {
+	return m.ActivityTCXContext(context.Background(), logID)
+}
+
+// ActivityTCXContext is like ActivityTCX but uses ctx for the request
+func (m *Session) ActivityTCXContext(ctx context.Context, logID int64) ([]byte, error) {
 	if logID == 0 {
 		return nil, errors.New("logID must be given")
 	}
 
 	// Fetch data from Fitbit
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/%d.tcx?includePartialTCX=true", logID))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/%d.tcx?includePartialTCX=true", m.apiURL, logID))
 	if err != nil {
 		return nil, err
 	}
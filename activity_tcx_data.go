@@ -1,62 +1,390 @@
 package fitbit
 
-import "encoding/xml"
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
 
+// Namespaces used within TCX files
+const (
+	TCXNamespace                  = "http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2"
+	TCXActivityExtensionNamespace = "http://www.garmin.com/xmlschemas/ActivityExtension/v2"
+	xsiNamespace                  = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// ReadTCX parses a TCX file as returned by ActivityTCX or exported by Garmin devices
 func ReadTCX(data []byte) (GarminTrainingCenterDatabasev2, error) {
 	activity := GarminTrainingCenterDatabasev2{}
 	err := xml.Unmarshal(data, &activity)
 	return activity, err
 }
 
-// ActivityTCX contains the activity TCX for a given activity
+// WriteTCX encodes the TCX data including the XML header
+func WriteTCX(tcx GarminTrainingCenterDatabasev2) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(tcx); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// GarminTrainingCenterDatabasev2 contains the content of a TCX file
 // Fitbit is using http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2 for the TCX format
 // Also available at https://www8.garmin.com/xmlschemas/TrainingCenterDatabasev2.xsd
-// TODO: Extend struct below to include all available fields
 type GarminTrainingCenterDatabasev2 struct {
-	XMLName    xml.Name `xml:"TrainingCenterDatabase"`
-	Text       string   `xml:",chardata"`
-	Xmlns      string   `xml:"xmlns,attr"`
-	Activities struct {
-		Text     string `xml:",chardata"`
-		Activity struct {
-			Text  string `xml:",chardata"`
-			Sport string `xml:"Sport,attr"`
-			ID    string `xml:"Id"`
-			Lap   []struct {
-				Text             string `xml:",chardata"`
-				StartTime        string `xml:"StartTime,attr"`
-				TotalTimeSeconds string `xml:"TotalTimeSeconds"`
-				DistanceMeters   string `xml:"DistanceMeters"`
-				Calories         string `xml:"Calories"`
-				Intensity        string `xml:"Intensity"`
-				TriggerMethod    string `xml:"TriggerMethod"`
-				Track            struct {
-					Text       string `xml:",chardata"`
-					Trackpoint []struct {
-						Text     string `xml:",chardata"`
-						Time     string `xml:"Time"`
-						Position struct {
-							Text             string `xml:",chardata"`
-							LatitudeDegrees  string `xml:"LatitudeDegrees"`
-							LongitudeDegrees string `xml:"LongitudeDegrees"`
-						} `xml:"Position"`
-						AltitudeMeters string `xml:"AltitudeMeters"`
-						DistanceMeters string `xml:"DistanceMeters"`
-						HeartRateBpm   struct {
-							Text  string `xml:",chardata"`
-							Value string `xml:"Value"`
-						} `xml:"HeartRateBpm"`
-					} `xml:"Trackpoint"`
-				} `xml:"Track"`
-			} `xml:"Lap"`
-			Creator struct {
-				Text      string `xml:",chardata"`
-				Type      string `xml:"type,attr"`
-				Xsi       string `xml:"xsi,attr"`
-				Name      string `xml:"Name"`
-				UnitID    string `xml:"UnitId"`
-				ProductID string `xml:"ProductID"`
-			} `xml:"Creator"`
-		} `xml:"Activity"`
-	} `xml:"Activities"`
+	XMLName    xml.Name       `xml:"TrainingCenterDatabase"`
+	Folders    *TCXRawElement `xml:"Folders,omitempty"` // Folders are not modeled
+	Activities *TCXActivities `xml:"Activities,omitempty"`
+	Workouts   *TCXRawElement `xml:"Workouts,omitempty"` // Workouts are not modeled
+	Courses    *TCXCourses    `xml:"Courses,omitempty"`
+	Author     *TCXSource     `xml:"Author,omitempty"`
+	Extensions *TCXExtensions `xml:"Extensions,omitempty"`
+}
+
+// MarshalXML writes the database with the TCX namespaces declared on the root element
+func (t GarminTrainingCenterDatabasev2) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	// the alias type prevents a recursive call of MarshalXML
+	type database GarminTrainingCenterDatabasev2
+	start = xml.StartElement{
+		Name: xml.Name{Local: "TrainingCenterDatabase"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: TCXNamespace},
+			{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		},
+	}
+	return e.EncodeElement(database(t), start)
+}
+
+// TCXType is the xsi:type attribute used by TCX files for abstract types like sources
+type TCXType string
+
+// MarshalXMLAttr writes the type as xsi:type attribute, the xsi namespace is declared on the root element
+func (t TCXType) MarshalXMLAttr(_ xml.Name) (xml.Attr, error) {
+	if t == "" {
+		return xml.Attr{}, nil
+	}
+	return xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: string(t)}, nil
+}
+
+// tcxNaiveTimeLayout is the layout of xsd:dateTime values without zone offset
+const tcxNaiveTimeLayout = "2006-01-02T15:04:05.999999999"
+
+// TCXTime is a xsd:dateTime value of a TCX file
+// Some exporters write times without zone offset, they are interpreted as UTC and written again without offset
+type TCXTime struct {
+	time.Time
+	NoZone bool // NoZone is set if the time was read without zone offset
+}
+
+// parseTCXTime parses a xsd:dateTime value with or without zone offset
+func parseTCXTime(value string) (TCXTime, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return TCXTime{Time: t}, nil
+	}
+	t, err := time.ParseInLocation(tcxNaiveTimeLayout, value, time.UTC)
+	if err != nil {
+		return TCXTime{}, fmt.Errorf("parsing TCX time %q: %w", value, err)
+	}
+	return TCXTime{Time: t, NoZone: true}, nil
+}
+
+// String returns the time in the format it was read
+func (t TCXTime) String() string {
+	if t.NoZone {
+		return t.UTC().Format(tcxNaiveTimeLayout)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// UnmarshalXML reads the time of an element
+func (t *TCXTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var value string
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	parsed, err := parseTCXTime(value)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// MarshalXML writes the time as an element
+func (t TCXTime) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(t.String(), start)
+}
+
+// UnmarshalXMLAttr reads the time of an attribute
+func (t *TCXTime) UnmarshalXMLAttr(attr xml.Attr) error {
+	parsed, err := parseTCXTime(attr.Value)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// MarshalXMLAttr writes the time as an attribute
+func (t TCXTime) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: t.String()}, nil
+}
+
+// TCXActivities contains all activities and multi sport sessions
+type TCXActivities struct {
+	Activity          []TCXActivity          `xml:"Activity"`
+	MultiSportSession []TCXMultiSportSession `xml:"MultiSportSession"`
+}
+
+// TCXActivity contains a single activity
+type TCXActivity struct {
+	Sport      string         `xml:"Sport,attr"` // Running, Biking or Other
+	ID         TCXTime        `xml:"Id"`         // ID is the start time of the activity
+	Lap        []TCXLap       `xml:"Lap"`
+	Notes      string         `xml:"Notes,omitempty"`
+	Training   *TCXTraining   `xml:"Training,omitempty"`
+	Creator    *TCXSource     `xml:"Creator,omitempty"`
+	Extensions *TCXExtensions `xml:"Extensions,omitempty"`
+}
+
+// TCXMultiSportSession contains multiple activities of different sports with optional transitions
+type TCXMultiSportSession struct {
+	ID         TCXTime        `xml:"Id"`
+	FirstSport TCXFirstSport  `xml:"FirstSport"`
+	NextSport  []TCXNextSport `xml:"NextSport"`
+	Notes      string         `xml:"Notes,omitempty"`
+}
+
+// TCXFirstSport contains the first activity of a multi sport session
+type TCXFirstSport struct {
+	Activity TCXActivity `xml:"Activity"`
+}
+
+// TCXNextSport contains a following activity of a multi sport session
+type TCXNextSport struct {
+	Transition *TCXLap     `xml:"Transition,omitempty"`
+	Activity   TCXActivity `xml:"Activity"`
+}
+
+// TCXLap contains a single lap of an activity
+type TCXLap struct {
+	StartTime           TCXTime        `xml:"StartTime,attr"`
+	TotalTimeSeconds    float64        `xml:"TotalTimeSeconds"`
+	DistanceMeters      float64        `xml:"DistanceMeters"`
+	MaximumSpeed        *float64       `xml:"MaximumSpeed,omitempty"` // meters per second
+	Calories            int            `xml:"Calories"`
+	AverageHeartRateBpm *TCXHeartRate  `xml:"AverageHeartRateBpm,omitempty"`
+	MaximumHeartRateBpm *TCXHeartRate  `xml:"MaximumHeartRateBpm,omitempty"`
+	Intensity           string         `xml:"Intensity"` // Active or Resting
+	Cadence             *int           `xml:"Cadence,omitempty"`
+	TriggerMethod       string         `xml:"TriggerMethod"` // Manual, Distance, Location, Time or HeartRate
+	Track               []TCXTrack     `xml:"Track"`
+	Notes               string         `xml:"Notes,omitempty"`
+	Extensions          *TCXExtensions `xml:"Extensions,omitempty"`
+}
+
+// TCXTrack contains the track points of a lap or course
+type TCXTrack struct {
+	Trackpoint []TCXTrackpoint `xml:"Trackpoint"`
+}
+
+// TCXTrackpoint contains a single measurement of a track, all values except the time are optional
+type TCXTrackpoint struct {
+	Time           TCXTime        `xml:"Time"`
+	Position       *TCXPosition   `xml:"Position,omitempty"`
+	AltitudeMeters *float64       `xml:"AltitudeMeters,omitempty"`
+	DistanceMeters *float64       `xml:"DistanceMeters,omitempty"`
+	HeartRateBpm   *TCXHeartRate  `xml:"HeartRateBpm,omitempty"`
+	Cadence        *int           `xml:"Cadence,omitempty"`
+	SensorState    string         `xml:"SensorState,omitempty"` // Present or Absent
+	Extensions     *TCXExtensions `xml:"Extensions,omitempty"`
+}
+
+// TCXPosition contains a position in degrees
+type TCXPosition struct {
+	LatitudeDegrees  float64 `xml:"LatitudeDegrees"`
+	LongitudeDegrees float64 `xml:"LongitudeDegrees"`
+}
+
+// TCXHeartRate contains a heart rate in beats per minute
+type TCXHeartRate struct {
+	Type  TCXType `xml:"type,attr,omitempty"`
+	Value int     `xml:"Value"`
+}
+
+// TCXTraining contains information about the training of an activity
+type TCXTraining struct {
+	VirtualPartner      bool                    `xml:"VirtualPartner,attr"`
+	QuickWorkoutResults *TCXQuickWorkoutResults `xml:"QuickWorkoutResults,omitempty"`
+	Plan                *TCXPlan                `xml:"Plan,omitempty"`
+}
+
+// TCXQuickWorkoutResults contains the results of a quick workout
+type TCXQuickWorkoutResults struct {
+	TotalTimeSeconds float64 `xml:"TotalTimeSeconds"`
+	DistanceMeters   float64 `xml:"DistanceMeters"`
+}
+
+// TCXPlan contains the plan a training was based on
+type TCXPlan struct {
+	Type            string         `xml:"Type,attr"` // Workout or Course
+	IntervalWorkout bool           `xml:"IntervalWorkout,attr"`
+	Name            string         `xml:"Name,omitempty"`
+	Extensions      *TCXExtensions `xml:"Extensions,omitempty"`
+}
+
+// TCXSource describes the device or application which created the data
+// Type is Device_t for devices and Application_t for applications
+type TCXSource struct {
+	Type       TCXType     `xml:"type,attr,omitempty"`
+	Name       string      `xml:"Name"`
+	UnitID     *uint32     `xml:"UnitId,omitempty"`     // UnitID is only set for devices
+	ProductID  *uint16     `xml:"ProductID,omitempty"`  // ProductID is only set for devices
+	Version    *TCXVersion `xml:"Version,omitempty"`    // Version is only set for devices
+	Build      *TCXBuild   `xml:"Build,omitempty"`      // Build is only set for applications
+	LangID     string      `xml:"LangID,omitempty"`     // LangID is only set for applications
+	PartNumber string      `xml:"PartNumber,omitempty"` // PartNumber is only set for applications
+}
+
+// TCXVersion contains the version of a device or application
+type TCXVersion struct {
+	VersionMajor int  `xml:"VersionMajor"`
+	VersionMinor int  `xml:"VersionMinor"`
+	BuildMajor   *int `xml:"BuildMajor,omitempty"`
+	BuildMinor   *int `xml:"BuildMinor,omitempty"`
+}
+
+// TCXBuild contains the build information of an application
+type TCXBuild struct {
+	Version TCXVersion `xml:"Version"`
+	Type    string     `xml:"Type,omitempty"` // Internal, Alpha, Beta or Release
+	Time    string     `xml:"Time,omitempty"`
+	Builder string     `xml:"Builder,omitempty"`
+}
+
+// TCXCourses contains all courses
+type TCXCourses struct {
+	Course     []TCXCourse    `xml:"Course"`
+	Extensions *TCXExtensions `xml:"Extensions,omitempty"`
+}
+
+// TCXCourse contains a single course
+type TCXCourse struct {
+	Name        string           `xml:"Name"`
+	Lap         []TCXCourseLap   `xml:"Lap"`
+	Track       []TCXTrack       `xml:"Track"`
+	Notes       string           `xml:"Notes,omitempty"`
+	CoursePoint []TCXCoursePoint `xml:"CoursePoint"`
+	Creator     *TCXSource       `xml:"Creator,omitempty"`
+	Extensions  *TCXExtensions   `xml:"Extensions,omitempty"`
+}
+
+// TCXCourseLap contains a single lap of a course
+type TCXCourseLap struct {
+	TotalTimeSeconds    float64        `xml:"TotalTimeSeconds"`
+	DistanceMeters      float64        `xml:"DistanceMeters"`
+	BeginPosition       *TCXPosition   `xml:"BeginPosition,omitempty"`
+	BeginAltitudeMeters *float64       `xml:"BeginAltitudeMeters,omitempty"`
+	EndPosition         *TCXPosition   `xml:"EndPosition,omitempty"`
+	EndAltitudeMeters   *float64       `xml:"EndAltitudeMeters,omitempty"`
+	AverageHeartRateBpm *TCXHeartRate  `xml:"AverageHeartRateBpm,omitempty"`
+	MaximumHeartRateBpm *TCXHeartRate  `xml:"MaximumHeartRateBpm,omitempty"`
+	Intensity           string         `xml:"Intensity"`
+	Cadence             *int           `xml:"Cadence,omitempty"`
+	Extensions          *TCXExtensions `xml:"Extensions,omitempty"`
+}
+
+// TCXCoursePoint contains a point of interest of a course
+type TCXCoursePoint struct {
+	Name           string         `xml:"Name"`
+	Time           TCXTime        `xml:"Time"`
+	Position       TCXPosition    `xml:"Position"`
+	AltitudeMeters *float64       `xml:"AltitudeMeters,omitempty"`
+	PointType      string         `xml:"PointType"` // Generic, Summit, Valley, Water, Food, Danger, Left, Right, Straight, ...
+	Notes          string         `xml:"Notes,omitempty"`
+	Extensions     *TCXExtensions `xml:"Extensions,omitempty"`
+}
+
+// TCXExtensions contains the extensions of an element
+// The Garmin activity extensions TPX and LX are parsed, all other extensions are kept as TCXRawElement
+type TCXExtensions struct {
+	TPX   *TCXTrackpointExtension `xml:"http://www.garmin.com/xmlschemas/ActivityExtension/v2 TPX,omitempty"`
+	LX    *TCXLapExtension        `xml:"http://www.garmin.com/xmlschemas/ActivityExtension/v2 LX,omitempty"`
+	Other []TCXRawElement         `xml:",any"`
+}
+
+// TCXRawElement contains an unknown element with its attributes, text and child elements
+// Names are kept with their namespace instead of the prefix to write the element with valid namespace declarations
+type TCXRawElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr      `xml:",any,attr"`
+	Text     string          `xml:",chardata"`
+	Children []TCXRawElement `xml:",any"`
+}
+
+// UnmarshalXML reads the element without namespace declarations, they are added again by the encoder
+// whitespace used for indentation of child elements is dropped
+func (r *TCXRawElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	// the alias type prevents a recursive call of UnmarshalXML
+	type element TCXRawElement
+	if err := d.DecodeElement((*element)(r), &start); err != nil {
+		return err
+	}
+	attrs := r.Attrs[:0]
+	for _, attr := range r.Attrs {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	r.Attrs = attrs
+	if len(r.Attrs) == 0 {
+		r.Attrs = nil
+	}
+	if strings.TrimSpace(r.Text) == "" {
+		r.Text = ""
+	}
+	return nil
+}
+
+// TCXTrackpointExtension contains the Garmin activity extension of a track point
+type TCXTrackpointExtension struct {
+	CadenceSensor string   `xml:"CadenceSensor,attr,omitempty"` // Footpod or Bike
+	Speed         *float64 `xml:"Speed,omitempty"`              // meters per second
+	RunCadence    *int     `xml:"RunCadence,omitempty"`
+	Watts         *int     `xml:"Watts,omitempty"`
+}
+
+// TCXLapExtension contains the Garmin activity extension of a lap
+type TCXLapExtension struct {
+	AvgSpeed       *float64 `xml:"AvgSpeed,omitempty"` // meters per second
+	MaxBikeCadence *int     `xml:"MaxBikeCadence,omitempty"`
+	AvgRunCadence  *int     `xml:"AvgRunCadence,omitempty"`
+	MaxRunCadence  *int     `xml:"MaxRunCadence,omitempty"`
+	Steps          *int     `xml:"Steps,omitempty"`
+	AvgWatts       *int     `xml:"AvgWatts,omitempty"`
+	MaxWatts       *int     `xml:"MaxWatts,omitempty"`
+}
+
+// tcxActivities returns all activities of the TCX data, activities of multi sport sessions are appended in order
+func tcxActivities(tcx GarminTrainingCenterDatabasev2) []TCXActivity {
+	if tcx.Activities == nil {
+		return nil
+	}
+	activities := append([]TCXActivity(nil), tcx.Activities.Activity...)
+	for _, session := range tcx.Activities.MultiSportSession {
+		activities = append(activities, session.FirstSport.Activity)
+		for _, next := range session.NextSport {
+			activities = append(activities, next.Activity)
+		}
+	}
+	return activities
 }
@@ -0,0 +1,123 @@
+package fitbit_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+func TestTCXRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/activity.tcx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcx, err := fitbit.ReadTCX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	activity := tcx.Activities.Activity[0]
+	if len(activity.Lap[0].Track[0].Trackpoint) != 3 || *activity.Lap[0].Track[0].Trackpoint[0].Extensions.TPX.RunCadence != 84 {
+		t.Error("track points or their extensions were not parsed")
+	}
+	if len(activity.Extensions.Other) != 1 || activity.Extensions.Other[0].XMLName.Local != "ActivityGoals" {
+		t.Fatalf("unknown extension was not kept: %+v", activity.Extensions.Other)
+	}
+
+	written, err := fitbit.WriteTCX(tcx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNamespacesDeclared(t, written)
+
+	again, err := fitbit.ReadTCX(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tcx, again) {
+		t.Errorf("TCX changed by writing and reading it again:\n%s", written)
+	}
+}
+
+// assertNamespacesDeclared fails if an element or attribute uses a prefix which is not declared
+// the decoder keeps undeclared prefixes as namespace instead of resolving them to an url
+func assertNamespacesDeclared(t *testing.T, data []byte) {
+	t.Helper()
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		names := []xml.Name{start.Name}
+		for _, attr := range start.Attr {
+			if attr.Name.Space != "xmlns" {
+				names = append(names, attr.Name)
+			}
+		}
+		for _, name := range names {
+			if name.Space != "" && !strings.Contains(name.Space, "://") {
+				t.Errorf("undeclared prefix %s:%s", name.Space, name.Local)
+			}
+		}
+	}
+}
+
+func TestTCXTimesWithoutZoneOffset(t *testing.T) {
+	data, err := os.ReadFile("testdata/activity_naive.tcx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcx, err := fitbit.ReadTCX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	activity := tcx.Activities.Activity[0]
+	expected := time.Date(2019, 1, 3, 20, 8, 23, 0, time.UTC)
+	if !activity.ID.Equal(expected) || !activity.ID.NoZone || !activity.Lap[0].StartTime.Equal(expected) {
+		t.Errorf("expected %s interpreted as UTC, got %s and %s", expected, activity.ID, activity.Lap[0].StartTime)
+	}
+	point := activity.Lap[0].Track[0].Trackpoint[1]
+	if !point.Time.Equal(expected.Add(60500*time.Millisecond)) || !point.Time.NoZone {
+		t.Errorf("unexpected time of the second track point %s", point.Time)
+	}
+
+	written, err := fitbit.WriteTCX(tcx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{`StartTime="2019-01-03T20:08:23"`, "<Id>2019-01-03T20:08:23</Id>", "<Time>2019-01-03T20:09:23.5</Time>"} {
+		if !bytes.Contains(written, []byte(value)) {
+			t.Errorf("times are not written without zone offset, missing %s in:\n%s", value, written)
+		}
+	}
+	again, err := fitbit.ReadTCX(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tcx, again) {
+		t.Errorf("TCX changed by writing and reading it again:\n%s", written)
+	}
+}
+
+func TestTCXInvalidTime(t *testing.T) {
+	_, err := fitbit.ReadTCX([]byte(`<TrainingCenterDatabase><Activities><Activity Sport="Other"><Id>yesterday</Id></Activity></Activities></TrainingCenterDatabase>`))
+	if err == nil {
+		t.Error("invalid time was accepted")
+	}
+}
@@ -1,9 +1,10 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"time"
 )
 
 // ActivitiesLog contains user activity logs, only one dataset is used and the other ones are empty
@@ -55,6 +56,16 @@ type ActivitiesIntradaySingleRecord struct {
 // activity is type of data to be fetched and returned
 // fetchRange defines the timespan the data reaches back from day
 func (m *Session) ActivitiesLogByDay(day string, activity string, fetchRange string) (ActivitiesLog, error) {
+	return m.ActivitiesLogByDayContext(context.Background(), day, activity, fetchRange)
+}
+
+// ActivitiesLogByDayContext is like ActivitiesLogByDay but uses ctx for the request
+func (m *Session) ActivitiesLogByDayContext(ctx context.Context, day string, activity string, fetchRange string) (ActivitiesLog, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return ActivitiesLog{}, err
+	}
+
 	// Supported activities: https://dev.fitbit.com/build/reference/web-api/activity/#resource-path-options:~:text=1y-,Resource%20Path%20Options
 	switch activity {
 	case "calories", "steps", "distance", "floors", "elevation", "minutesSedentary", "minutesLightlyActive", "minutesFairlyActive", "minutesVeryActive", "activityCalories":
@@ -72,23 +83,46 @@ func (m *Session) ActivitiesLogByDay(day string, activity string, fetchRange str
 		fetchRange = "1d"
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/%s/date/%s/%s.json", activity, day, fetchRange))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/%s/date/%s/%s.json", m.apiURL, activity, day, fetchRange))
 	if err != nil {
 		return ActivitiesLog{}, err
 	}
 
 	summary := ActivitiesLog{}
-	if err := json.Unmarshal(contents, &summary); err != nil {
+	if err := m.decode("ActivitiesLogByDay", contents, &summary); err != nil {
 		return ActivitiesLog{}, err
 	}
 
 	return summary, nil
 }
 
+// ActivitiesLogOn is like ActivitiesLogByDay but takes a Date
+func (m *Session) ActivitiesLogOn(day Date, activity string, fetchRange string) (ActivitiesLog, error) {
+	return m.ActivitiesLogOnContext(context.Background(), day, activity, fetchRange)
+}
+
+// ActivitiesLogOnContext is like ActivitiesLogOn but uses ctx for the request
+func (m *Session) ActivitiesLogOnContext(ctx context.Context, day Date, activity string, fetchRange string) (ActivitiesLog, error) {
+	if err := checkDates(day); err != nil {
+		return ActivitiesLog{}, err
+	}
+	return m.ActivitiesLogByDayContext(ctx, day.String(), activity, fetchRange)
+}
+
 // ActivitiesLogInterdayByDay returns the interday activities recorded for a given day and type
 // date must be in the format yyyy-MM-dd and describes the end date
 // activity is type of data to be fetched and returned
 func (m *Session) ActivitiesLogInterdayByDay(day string, activity string) (ActivitiesInterdayLog, error) {
+	return m.ActivitiesLogInterdayByDayContext(context.Background(), day, activity)
+}
+
+// ActivitiesLogInterdayByDayContext is like ActivitiesLogInterdayByDay but uses ctx for the request
+func (m *Session) ActivitiesLogInterdayByDayContext(ctx context.Context, day string, activity string) (ActivitiesInterdayLog, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return ActivitiesInterdayLog{}, err
+	}
+
 	// Supported activities: https://dev.fitbit.com/build/reference/web-api/activity/#resource-path-options:~:text=1y-,Resource%20Path%20Options
 	switch activity {
 	case "calories", "steps", "distance", "floors", "elevation":
@@ -97,15 +131,95 @@ func (m *Session) ActivitiesLogInterdayByDay(day string, activity string) (Activ
 		return ActivitiesInterdayLog{}, errors.New("unknown activity given")
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/%s/date/%s/1d/1min.json", activity, day))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/%s/date/%s/1d/1min.json", m.apiURL, activity, day))
 	if err != nil {
 		return ActivitiesInterdayLog{}, err
 	}
 
 	interday := ActivitiesInterdayLog{}
-	if err := json.Unmarshal(contents, &interday); err != nil {
+	if err := m.decode("ActivitiesLogInterdayByDay", contents, &interday); err != nil {
 		return ActivitiesInterdayLog{}, err
 	}
 
 	return interday, nil
 }
+
+// ActivitiesLogInterdayOn is like ActivitiesLogInterdayByDay but takes a Date
+func (m *Session) ActivitiesLogInterdayOn(day Date, activity string) (ActivitiesInterdayLog, error) {
+	return m.ActivitiesLogInterdayOnContext(context.Background(), day, activity)
+}
+
+// ActivitiesLogInterdayOnContext is like ActivitiesLogInterdayOn but uses ctx for the request
+func (m *Session) ActivitiesLogInterdayOnContext(ctx context.Context, day Date, activity string) (ActivitiesInterdayLog, error) {
+	if err := checkDates(day); err != nil {
+		return ActivitiesInterdayLog{}, err
+	}
+	return m.ActivitiesLogInterdayByDayContext(ctx, day.String(), activity)
+}
+
+// Series returns the activity log as normalized time series, one series per contained activity
+// loc is the timezone of the user, units the unit system of the session used to request the log
+func (a ActivitiesLog) Series(loc *time.Location, units UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, data := range []struct {
+		metric  string
+		unit    string
+		records []ActivitiesLogSingleRecord
+	}{
+		{MetricSteps, "", a.ActivitiesTrackerSteps},
+		{MetricCalories, UnitKilocalories, a.ActivitiesTrackerCalories},
+		{MetricDistance, units.distance(), a.ActivitiesTrackerDistance},
+		{MetricFloors, "", a.ActivitiesTrackerFloors},
+		{MetricElevation, units.elevation(), a.ActivitiesTrackerElevation},
+		{MetricMinutesSedentary, UnitMinutes, a.ActivitiesTrackerMinutesSedentary},
+		{MetricMinutesLightlyActive, UnitMinutes, a.ActivitiesTrackerMinutesLightlyActive},
+		{MetricMinutesFairlyActive, UnitMinutes, a.ActivitiesTrackerMinutesFairlyActive},
+		{MetricMinutesVeryActive, UnitMinutes, a.ActivitiesTrackerMinutesVeryActive},
+		{MetricActivityCalories, UnitKilocalories, a.ActivitiesTrackerActivityCalories},
+	} {
+		for _, record := range data.records {
+			if err := b.addString(data.metric, false, record.DateTime, record.Value, data.unit); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.result(), nil
+}
+
+// Series returns the daily and intraday values of the log as normalized time series
+// loc is the timezone of the user, units the unit system of the session used to request the log
+func (a ActivitiesInterdayLog) Series(loc *time.Location, units UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, data := range []struct {
+		metric   string
+		unit     string
+		records  []ActivitiesLogSingleRecord
+		intraday ActivitiesIntradaySingleRecord
+	}{
+		{MetricCalories, UnitKilocalories, a.ActivitiesCalories, a.ActivitiesCaloriesIntraday},
+		{MetricSteps, "", a.ActivitiesSteps, a.ActivitiesStepsIntraday},
+		{MetricDistance, units.distance(), a.ActivitiesDistance, a.ActivitiesDistanceIntraday},
+		{MetricFloors, "", a.ActivitiesFloors, a.ActivitiesFloorsIntraday},
+		{MetricElevation, units.elevation(), a.ActivitiesElevation, a.ActivitiesElevationIntraday},
+	} {
+		for _, record := range data.records {
+			if err := b.addString(data.metric, false, record.DateTime, record.Value, data.unit); err != nil {
+				return nil, err
+			}
+		}
+		// the intraday dataset belongs to the day of the first summary record
+		if len(data.records) == 0 || len(data.intraday.Dataset) == 0 {
+			continue
+		}
+		add, err := b.intraday(data.metric, data.records[0].DateTime, data.unit)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range data.intraday.Dataset {
+			if err := add(entry.Time, entry.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.result(), nil
+}
@@ -1,7 +1,7 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 )
 
 // ActivitiesTypes contains a list of activities
@@ -39,13 +39,18 @@ type ActivitiesTypes struct {
 
 // ActivityTypes returns a list of activities available, even user created ones
 func (m *Session) ActivityTypes() (ActivitiesTypes, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/activities.json")
+	return m.ActivityTypesContext(context.Background())
+}
+
+// ActivityTypesContext is like ActivityTypes but uses ctx for the request
+func (m *Session) ActivityTypesContext(ctx context.Context) (ActivitiesTypes, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/activities.json")
 	if err != nil {
 		return ActivitiesTypes{}, err
 	}
 
 	activities := ActivitiesTypes{}
-	if err := json.Unmarshal(contents, &activities); err != nil {
+	if err := m.decode("ActivityTypes", contents, &activities); err != nil {
 		return ActivitiesTypes{}, err
 	}
 
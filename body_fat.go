@@ -1,8 +1,9 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"time"
 )
 
 // BodyFat contains one or multiple records, similar to BodyFat but without weight
@@ -17,31 +18,83 @@ type BodyFat struct {
 }
 
 // BodyFatLogByDay returns the fat log by a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) BodyFatLogByDay(day string) (BodyFat, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/body/log/fat/date/%s.json", day))
+	return m.BodyFatLogByDayContext(context.Background(), day)
+}
+
+// BodyFatLogByDayContext is like BodyFatLogByDay but uses ctx for the request
+func (m *Session) BodyFatLogByDayContext(ctx context.Context, day string) (BodyFat, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return BodyFat{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/body/log/fat/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return BodyFat{}, err
 	}
 
 	fat := BodyFat{}
-	if err := json.Unmarshal(contents, &fat); err != nil {
+	if err := m.decode("BodyFatLogByDay", contents, &fat); err != nil {
 		return BodyFat{}, err
 	}
 
 	return fat, nil
 }
 
+// BodyFatLogOn is like BodyFatLogByDay but takes a Date
+func (m *Session) BodyFatLogOn(day Date) (BodyFat, error) {
+	return m.BodyFatLogOnContext(context.Background(), day)
+}
+
+// BodyFatLogOnContext is like BodyFatLogOn but uses ctx for the request
+func (m *Session) BodyFatLogOnContext(ctx context.Context, day Date) (BodyFat, error) {
+	if err := checkDates(day); err != nil {
+		return BodyFat{}, err
+	}
+	return m.BodyFatLogByDayContext(ctx, day.String())
+}
+
 // BodyFatLogByDateRange returns the weight log of a given time range by date
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysBodyFat days are split into multiple requests
 func (m *Session) BodyFatLogByDateRange(startDay string, endDay string) (BodyFat, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/body/log/fat/date/%s/%s.json", startDay, endDay))
+	return m.BodyFatLogByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// BodyFatLogByDateRangeContext is like BodyFatLogByDateRange but uses ctx for the request
+func (m *Session) BodyFatLogByDateRangeContext(ctx context.Context, startDay string, endDay string) (BodyFat, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return BodyFat{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysBodyFat, m.bodyFatLogByDateRange)
+}
+
+// BodyFatLogBetween is like BodyFatLogByDateRange but takes Dates
+func (m *Session) BodyFatLogBetween(startDay Date, endDay Date) (BodyFat, error) {
+	return m.BodyFatLogBetweenContext(context.Background(), startDay, endDay)
+}
+
+// BodyFatLogBetweenContext is like BodyFatLogBetween but uses ctx for the request
+func (m *Session) BodyFatLogBetweenContext(ctx context.Context, startDay Date, endDay Date) (BodyFat, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return BodyFat{}, err
+	}
+	return m.BodyFatLogByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// bodyFatLogByDateRange requests a single date range
+func (m *Session) bodyFatLogByDateRange(ctx context.Context, startDay string, endDay string) (BodyFat, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/body/log/fat/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return BodyFat{}, err
 	}
 
 	fat := BodyFat{}
-	if err := json.Unmarshal(contents, &fat); err != nil {
+	if err := m.decode("BodyFatLogByDateRange", contents, &fat); err != nil {
 		return BodyFat{}, err
 	}
 
@@ -51,7 +104,16 @@ func (m *Session) BodyFatLogByDateRange(startDay string, endDay string) (BodyFat
 // AddBodyFat adds a new body weight record
 // date must be in the format yyyy-MM-dd
 func (m *Session) AddBodyFat(day string, fat float64) (BodyFat, error) {
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1/user/-/body/log/fat.json", map[string]string{
+	return m.AddBodyFatContext(context.Background(), day, fat)
+}
+
+// AddBodyFatContext is like AddBodyFat but uses ctx for the request
+func (m *Session) AddBodyFatContext(ctx context.Context, day string, fat float64) (BodyFat, error) {
+	if err := checkPostDate(day); err != nil {
+		return BodyFat{}, err
+	}
+
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1/user/-/body/log/fat.json", map[string]string{
 		"date": day,
 		"fat":  fmt.Sprintf("%f", fat),
 	})
@@ -60,19 +122,48 @@ func (m *Session) AddBodyFat(day string, fat float64) (BodyFat, error) {
 	}
 
 	fatResponse := BodyFat{}
-	if err := json.Unmarshal(contents, &fatResponse); err != nil {
+	if err := m.decode("AddBodyFat", contents, &fatResponse); err != nil {
 		return BodyFat{}, err
 	}
 
 	return fatResponse, nil
 }
 
+// AddBodyFatOn is like AddBodyFat but takes a Date
+func (m *Session) AddBodyFatOn(day Date, fat float64) (BodyFat, error) {
+	return m.AddBodyFatOnContext(context.Background(), day, fat)
+}
+
+// AddBodyFatOnContext is like AddBodyFatOn but uses ctx for the request
+func (m *Session) AddBodyFatOnContext(ctx context.Context, day Date, fat float64) (BodyFat, error) {
+	if err := checkDates(day); err != nil {
+		return BodyFat{}, err
+	}
+	return m.AddBodyFatContext(ctx, day.String(), fat)
+}
+
 // RemoveBodyFat removes a existing record by it's log ID
 func (m *Session) RemoveBodyFat(logID int64) error {
-	_, err := m.makeDELETERequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/body/log/fat/%d.json", logID))
+	return m.RemoveBodyFatContext(context.Background(), logID)
+}
+
+// RemoveBodyFatContext is like RemoveBodyFat but uses ctx for the request
+func (m *Session) RemoveBodyFatContext(ctx context.Context, logID int64) error {
+	_, err := m.makeDELETERequest(ctx, fmt.Sprintf("%s/1/user/-/body/log/fat/%d.json", m.apiURL, logID))
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// Series returns the body fat of all records as normalized time series, loc is the timezone of the user
+func (f BodyFat) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, entry := range f.Fat {
+		if err := b.addAt(MetricBodyFat, false, entry.Date+" "+entry.Time, entry.Fat, UnitPercent); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
@@ -1,7 +1,7 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 )
 
@@ -25,13 +25,18 @@ type BodyFatGoal struct {
 
 // BodyWeightGoal requests the weight goal of the user
 func (m *Session) BodyWeightGoal() (BodyWeightGoal, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/body/log/weight/goal.json")
+	return m.BodyWeightGoalContext(context.Background())
+}
+
+// BodyWeightGoalContext is like BodyWeightGoal but uses ctx for the request
+func (m *Session) BodyWeightGoalContext(ctx context.Context) (BodyWeightGoal, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/body/log/weight/goal.json")
 	if err != nil {
 		return BodyWeightGoal{}, err
 	}
 
 	weightGoal := BodyWeightGoal{}
-	if err := json.Unmarshal(contents, &weightGoal); err != nil {
+	if err := m.decode("BodyWeightGoal", contents, &weightGoal); err != nil {
 		return BodyWeightGoal{}, err
 	}
 
@@ -40,7 +45,16 @@ func (m *Session) BodyWeightGoal() (BodyWeightGoal, error) {
 
 // SetBodyWeightGoal sets the users body fat goal
 func (m *Session) SetBodyWeightGoal(startDate string, startWeight float64, weightGoal float64) (BodyWeightGoal, error) {
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1/user/-/body/log/weight/goal.json", map[string]string{
+	return m.SetBodyWeightGoalContext(context.Background(), startDate, startWeight, weightGoal)
+}
+
+// SetBodyWeightGoalContext is like SetBodyWeightGoal but uses ctx for the request
+func (m *Session) SetBodyWeightGoalContext(ctx context.Context, startDate string, startWeight float64, weightGoal float64) (BodyWeightGoal, error) {
+	if err := checkPostDate(startDate); err != nil {
+		return BodyWeightGoal{}, err
+	}
+
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1/user/-/body/log/weight/goal.json", map[string]string{
 		"startDate":   startDate,
 		"startWeight": fmt.Sprintf("%f", startWeight),
 		"weight":      fmt.Sprintf("%f", weightGoal),
@@ -50,22 +64,40 @@ func (m *Session) SetBodyWeightGoal(startDate string, startWeight float64, weigh
 	}
 
 	weightGoalResponse := BodyWeightGoal{}
-	if err := json.Unmarshal(contents, &weightGoalResponse); err != nil {
+	if err := m.decode("SetBodyWeightGoal", contents, &weightGoalResponse); err != nil {
 		return BodyWeightGoal{}, err
 	}
 
 	return weightGoalResponse, nil
 }
 
+// SetBodyWeightGoalFrom is like SetBodyWeightGoal but takes a Date
+func (m *Session) SetBodyWeightGoalFrom(startDate Date, startWeight float64, weightGoal float64) (BodyWeightGoal, error) {
+	return m.SetBodyWeightGoalFromContext(context.Background(), startDate, startWeight, weightGoal)
+}
+
+// SetBodyWeightGoalFromContext is like SetBodyWeightGoalFrom but uses ctx for the request
+func (m *Session) SetBodyWeightGoalFromContext(ctx context.Context, startDate Date, startWeight float64, weightGoal float64) (BodyWeightGoal, error) {
+	if err := checkDates(startDate); err != nil {
+		return BodyWeightGoal{}, err
+	}
+	return m.SetBodyWeightGoalContext(ctx, startDate.String(), startWeight, weightGoal)
+}
+
 // BodyFatGoal requests the fat goal of the user
 func (m *Session) BodyFatGoal() (BodyFatGoal, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/body/log/fat/goal.json")
+	return m.BodyFatGoalContext(context.Background())
+}
+
+// BodyFatGoalContext is like BodyFatGoal but uses ctx for the request
+func (m *Session) BodyFatGoalContext(ctx context.Context) (BodyFatGoal, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/body/log/fat/goal.json")
 	if err != nil {
 		return BodyFatGoal{}, err
 	}
 
 	fatGoal := BodyFatGoal{}
-	if err := json.Unmarshal(contents, &fatGoal); err != nil {
+	if err := m.decode("BodyFatGoal", contents, &fatGoal); err != nil {
 		return BodyFatGoal{}, err
 	}
 
@@ -74,7 +106,12 @@ func (m *Session) BodyFatGoal() (BodyFatGoal, error) {
 
 // SetBodyFatGoal sets the users body fat goal
 func (m *Session) SetBodyFatGoal(targetFat float64) (FoodGoal, error) {
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1/user/-/body/log/fat/goal.json", map[string]string{
+	return m.SetBodyFatGoalContext(context.Background(), targetFat)
+}
+
+// SetBodyFatGoalContext is like SetBodyFatGoal but uses ctx for the request
+func (m *Session) SetBodyFatGoalContext(ctx context.Context, targetFat float64) (FoodGoal, error) {
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1/user/-/body/log/fat/goal.json", map[string]string{
 		"fat": fmt.Sprintf("%f", targetFat),
 	})
 	if err != nil {
@@ -82,7 +119,7 @@ func (m *Session) SetBodyFatGoal(targetFat float64) (FoodGoal, error) {
 	}
 
 	foodgoal := FoodGoal{}
-	if err := json.Unmarshal(contents, &foodgoal); err != nil {
+	if err := m.decode("SetBodyFatGoal", contents, &foodgoal); err != nil {
 		return FoodGoal{}, err
 	}
 
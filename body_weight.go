@@ -1,8 +1,9 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"time"
 )
 
 // BodyWeight contains one or multiple records
@@ -19,31 +20,83 @@ type BodyWeight struct {
 }
 
 // BodyWeightLogByDay returns the weight log by a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) BodyWeightLogByDay(day string) (BodyWeight, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/body/log/weight/date/%s.json", day))
+	return m.BodyWeightLogByDayContext(context.Background(), day)
+}
+
+// BodyWeightLogByDayContext is like BodyWeightLogByDay but uses ctx for the request
+func (m *Session) BodyWeightLogByDayContext(ctx context.Context, day string) (BodyWeight, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return BodyWeight{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/body/log/weight/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return BodyWeight{}, err
 	}
 
 	weight := BodyWeight{}
-	if err := json.Unmarshal(contents, &weight); err != nil {
+	if err := m.decode("BodyWeightLogByDay", contents, &weight); err != nil {
 		return BodyWeight{}, err
 	}
 
 	return weight, nil
 }
 
+// BodyWeightLogOn is like BodyWeightLogByDay but takes a Date
+func (m *Session) BodyWeightLogOn(day Date) (BodyWeight, error) {
+	return m.BodyWeightLogOnContext(context.Background(), day)
+}
+
+// BodyWeightLogOnContext is like BodyWeightLogOn but uses ctx for the request
+func (m *Session) BodyWeightLogOnContext(ctx context.Context, day Date) (BodyWeight, error) {
+	if err := checkDates(day); err != nil {
+		return BodyWeight{}, err
+	}
+	return m.BodyWeightLogByDayContext(ctx, day.String())
+}
+
 // BodyWeightLogByDateRange returns the weight log of a given time range by date
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysBodyWeight days are split into multiple requests
 func (m *Session) BodyWeightLogByDateRange(startDay string, endDay string) (BodyWeight, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/body/log/weight/date/%s/%s.json", startDay, endDay))
+	return m.BodyWeightLogByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// BodyWeightLogByDateRangeContext is like BodyWeightLogByDateRange but uses ctx for the request
+func (m *Session) BodyWeightLogByDateRangeContext(ctx context.Context, startDay string, endDay string) (BodyWeight, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return BodyWeight{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysBodyWeight, m.bodyWeightLogByDateRange)
+}
+
+// BodyWeightLogBetween is like BodyWeightLogByDateRange but takes Dates
+func (m *Session) BodyWeightLogBetween(startDay Date, endDay Date) (BodyWeight, error) {
+	return m.BodyWeightLogBetweenContext(context.Background(), startDay, endDay)
+}
+
+// BodyWeightLogBetweenContext is like BodyWeightLogBetween but uses ctx for the request
+func (m *Session) BodyWeightLogBetweenContext(ctx context.Context, startDay Date, endDay Date) (BodyWeight, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return BodyWeight{}, err
+	}
+	return m.BodyWeightLogByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// bodyWeightLogByDateRange requests a single date range
+func (m *Session) bodyWeightLogByDateRange(ctx context.Context, startDay string, endDay string) (BodyWeight, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/body/log/weight/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return BodyWeight{}, err
 	}
 
 	weight := BodyWeight{}
-	if err := json.Unmarshal(contents, &weight); err != nil {
+	if err := m.decode("BodyWeightLogByDateRange", contents, &weight); err != nil {
 		return BodyWeight{}, err
 	}
 
@@ -53,7 +106,16 @@ func (m *Session) BodyWeightLogByDateRange(startDay string, endDay string) (Body
 // AddBodyWeight adds a new body weight record
 // date must be in the format yyyy-MM-dd
 func (m *Session) AddBodyWeight(day string, weight float64) (BodyWeight, error) {
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1/user/-/body/log/weight.json", map[string]string{
+	return m.AddBodyWeightContext(context.Background(), day, weight)
+}
+
+// AddBodyWeightContext is like AddBodyWeight but uses ctx for the request
+func (m *Session) AddBodyWeightContext(ctx context.Context, day string, weight float64) (BodyWeight, error) {
+	if err := checkPostDate(day); err != nil {
+		return BodyWeight{}, err
+	}
+
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1/user/-/body/log/weight.json", map[string]string{
 		"date":   day,
 		"weight": fmt.Sprintf("%f", weight),
 	})
@@ -62,19 +124,56 @@ func (m *Session) AddBodyWeight(day string, weight float64) (BodyWeight, error)
 	}
 
 	weightResponse := BodyWeight{}
-	if err := json.Unmarshal(contents, &weightResponse); err != nil {
+	if err := m.decode("AddBodyWeight", contents, &weightResponse); err != nil {
 		return BodyWeight{}, err
 	}
 
 	return weightResponse, nil
 }
 
+// AddBodyWeightOn is like AddBodyWeight but takes a Date
+func (m *Session) AddBodyWeightOn(day Date, weight float64) (BodyWeight, error) {
+	return m.AddBodyWeightOnContext(context.Background(), day, weight)
+}
+
+// AddBodyWeightOnContext is like AddBodyWeightOn but uses ctx for the request
+func (m *Session) AddBodyWeightOnContext(ctx context.Context, day Date, weight float64) (BodyWeight, error) {
+	if err := checkDates(day); err != nil {
+		return BodyWeight{}, err
+	}
+	return m.AddBodyWeightContext(ctx, day.String(), weight)
+}
+
 // RemoveBodyWeight removes a existing record by it's log ID
 func (m *Session) RemoveBodyWeight(logID int64) error {
-	_, err := m.makeDELETERequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/body/log/weight/%d.json", logID))
+	return m.RemoveBodyWeightContext(context.Background(), logID)
+}
+
+// RemoveBodyWeightContext is like RemoveBodyWeight but uses ctx for the request
+func (m *Session) RemoveBodyWeightContext(ctx context.Context, logID int64) error {
+	_, err := m.makeDELETERequest(ctx, fmt.Sprintf("%s/1/user/-/body/log/weight/%d.json", m.apiURL, logID))
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// Series returns the weight, BMI and body fat of all records as normalized time series
+// loc is the timezone of the user, units the unit system of the session used to request the log
+func (w BodyWeight) Series(loc *time.Location, units UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, entry := range w.Weight {
+		t, err := b.parseTime(entry.Date + " " + entry.Time)
+		if err != nil {
+			return nil, err
+		}
+		b.add(MetricWeight, false, t, entry.Weight, units.weight())
+		b.add(MetricBMI, false, t, entry.Bmi, UnitKgPerM2)
+		// body fat is only returned if it was measured
+		if entry.Fat != 0 {
+			b.add(MetricBodyFat, false, t, entry.Fat, UnitPercent)
+		}
+	}
+	return b.result(), nil
+}
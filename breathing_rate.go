@@ -1,8 +1,9 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"time"
 )
 
 type BreathingRate struct {
@@ -15,31 +16,83 @@ type BreathingRate struct {
 }
 
 // BreathingRateLogByDay returns the breathing rate log (summary) by a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) BreathingRateLogByDay(day string) (BreathingRate, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/br/date/%s.json", day))
+	return m.BreathingRateLogByDayContext(context.Background(), day)
+}
+
+// BreathingRateLogByDayContext is like BreathingRateLogByDay but uses ctx for the request
+func (m *Session) BreathingRateLogByDayContext(ctx context.Context, day string) (BreathingRate, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return BreathingRate{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/br/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return BreathingRate{}, err
 	}
 
 	br := BreathingRate{}
-	if err := json.Unmarshal(contents, &br); err != nil {
+	if err := m.decode("BreathingRateLogByDay", contents, &br); err != nil {
 		return BreathingRate{}, err
 	}
 
 	return br, nil
 }
 
+// BreathingRateLogOn is like BreathingRateLogByDay but takes a Date
+func (m *Session) BreathingRateLogOn(day Date) (BreathingRate, error) {
+	return m.BreathingRateLogOnContext(context.Background(), day)
+}
+
+// BreathingRateLogOnContext is like BreathingRateLogOn but uses ctx for the request
+func (m *Session) BreathingRateLogOnContext(ctx context.Context, day Date) (BreathingRate, error) {
+	if err := checkDates(day); err != nil {
+		return BreathingRate{}, err
+	}
+	return m.BreathingRateLogByDayContext(ctx, day.String())
+}
+
 // BreathingRateLogByDateRange returns the breathing rate summary log of a given time range by date
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysBreathingRate days are split into multiple requests
 func (m *Session) BreathingRateLogByDateRange(startDay string, endDay string) (BreathingRate, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/br/date/%s/%s.json", startDay, endDay))
+	return m.BreathingRateLogByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// BreathingRateLogByDateRangeContext is like BreathingRateLogByDateRange but uses ctx for the request
+func (m *Session) BreathingRateLogByDateRangeContext(ctx context.Context, startDay string, endDay string) (BreathingRate, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return BreathingRate{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysBreathingRate, m.breathingRateLogByDateRange)
+}
+
+// BreathingRateLogBetween is like BreathingRateLogByDateRange but takes Dates
+func (m *Session) BreathingRateLogBetween(startDay Date, endDay Date) (BreathingRate, error) {
+	return m.BreathingRateLogBetweenContext(context.Background(), startDay, endDay)
+}
+
+// BreathingRateLogBetweenContext is like BreathingRateLogBetween but uses ctx for the request
+func (m *Session) BreathingRateLogBetweenContext(ctx context.Context, startDay Date, endDay Date) (BreathingRate, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return BreathingRate{}, err
+	}
+	return m.BreathingRateLogByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// breathingRateLogByDateRange requests a single date range
+func (m *Session) breathingRateLogByDateRange(ctx context.Context, startDay string, endDay string) (BreathingRate, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/br/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return BreathingRate{}, err
 	}
 
 	br := BreathingRate{}
-	if err := json.Unmarshal(contents, &br); err != nil {
+	if err := m.decode("BreathingRateLogByDateRange", contents, &br); err != nil {
 		return BreathingRate{}, err
 	}
 
@@ -67,33 +120,113 @@ type BreathingRateIntraday struct {
 }
 
 // BreathingRateLogByDayIntraday returns the breathing rate log by a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) BreathingRateLogByDayIntraday(day string) (BreathingRateIntraday, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/br/date/%s/all.json", day))
+	return m.BreathingRateLogByDayIntradayContext(context.Background(), day)
+}
+
+// BreathingRateLogByDayIntradayContext is like BreathingRateLogByDayIntraday but uses ctx for the request
+func (m *Session) BreathingRateLogByDayIntradayContext(ctx context.Context, day string) (BreathingRateIntraday, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return BreathingRateIntraday{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/br/date/%s/all.json", m.apiURL, day))
 	if err != nil {
 		return BreathingRateIntraday{}, err
 	}
 
 	br := BreathingRateIntraday{}
-	if err := json.Unmarshal(contents, &br); err != nil {
+	if err := m.decode("BreathingRateLogByDayIntraday", contents, &br); err != nil {
 		return BreathingRateIntraday{}, err
 	}
 
 	return br, nil
 }
 
+// BreathingRateLogIntradayOn is like BreathingRateLogByDayIntraday but takes a Date
+func (m *Session) BreathingRateLogIntradayOn(day Date) (BreathingRateIntraday, error) {
+	return m.BreathingRateLogIntradayOnContext(context.Background(), day)
+}
+
+// BreathingRateLogIntradayOnContext is like BreathingRateLogIntradayOn but uses ctx for the request
+func (m *Session) BreathingRateLogIntradayOnContext(ctx context.Context, day Date) (BreathingRateIntraday, error) {
+	if err := checkDates(day); err != nil {
+		return BreathingRateIntraday{}, err
+	}
+	return m.BreathingRateLogByDayIntradayContext(ctx, day.String())
+}
+
 // BreathingRateLogByDateRangeIntraday returns the breathing rate log of a given time range by date
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysBreathingRate days are split into multiple requests
 func (m *Session) BreathingRateLogByDateRangeIntraday(startDay string, endDay string) (BreathingRateIntraday, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/br/date/%s/%s/all.json", startDay, endDay))
+	return m.BreathingRateLogByDateRangeIntradayContext(context.Background(), startDay, endDay)
+}
+
+// BreathingRateLogByDateRangeIntradayContext is like BreathingRateLogByDateRangeIntraday but uses ctx for the request
+func (m *Session) BreathingRateLogByDateRangeIntradayContext(ctx context.Context, startDay string, endDay string) (BreathingRateIntraday, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return BreathingRateIntraday{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysBreathingRate, m.breathingRateLogByDateRangeIntraday)
+}
+
+// BreathingRateLogIntradayBetween is like BreathingRateLogByDateRangeIntraday but takes Dates
+func (m *Session) BreathingRateLogIntradayBetween(startDay Date, endDay Date) (BreathingRateIntraday, error) {
+	return m.BreathingRateLogIntradayBetweenContext(context.Background(), startDay, endDay)
+}
+
+// BreathingRateLogIntradayBetweenContext is like BreathingRateLogIntradayBetween but uses ctx for the request
+func (m *Session) BreathingRateLogIntradayBetweenContext(ctx context.Context, startDay Date, endDay Date) (BreathingRateIntraday, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return BreathingRateIntraday{}, err
+	}
+	return m.BreathingRateLogByDateRangeIntradayContext(ctx, startDay.String(), endDay.String())
+}
+
+// breathingRateLogByDateRangeIntraday requests a single date range
+func (m *Session) breathingRateLogByDateRangeIntraday(ctx context.Context, startDay string, endDay string) (BreathingRateIntraday, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/br/date/%s/%s/all.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return BreathingRateIntraday{}, err
 	}
 
 	br := BreathingRateIntraday{}
-	if err := json.Unmarshal(contents, &br); err != nil {
+	if err := m.decode("BreathingRateLogByDateRangeIntraday", contents, &br); err != nil {
 		return BreathingRateIntraday{}, err
 	}
 
 	return br, nil
 }
+
+// Series returns the breathing rate as normalized time series, loc is the timezone of the user
+func (br BreathingRate) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, entry := range br.Br {
+		if err := b.addAt(MetricBreathingRate, false, entry.DateTime, entry.Value.BreathingRate, UnitBreathsPerMin); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
+
+// Series returns the breathing rate of the full sleep and of each sleep stage as normalized time series
+// loc is the timezone of the user
+func (br BreathingRateIntraday) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, entry := range br.Br {
+		t, err := b.parseTime(entry.DateTime)
+		if err != nil {
+			return nil, err
+		}
+		b.add(MetricBreathingRate, false, t, entry.Value.FullSleepSummary.BreathingRate, UnitBreathsPerMin)
+		b.add(MetricBreathingRateDeepSleep, false, t, entry.Value.DeepSleepSummary.BreathingRate, UnitBreathsPerMin)
+		b.add(MetricBreathingRateLightSleep, false, t, entry.Value.LightSleepSummary.BreathingRate, UnitBreathsPerMin)
+		b.add(MetricBreathingRateRemSleep, false, t, entry.Value.RemSleepSummary.BreathingRate, UnitBreathsPerMin)
+	}
+	return b.result(), nil
+}
@@ -1,8 +1,9 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"time"
 )
 
 // CardioFitnessScoreLog contains the cardio fitness score (VO2Max) for a given date
@@ -18,31 +19,95 @@ type CardioFitnessScoreLog struct {
 // CardioFitnessScoreByDay returns the cardio fitness score (VO2Max) for a given date
 // date must be in the format yyyy-MM-dd, scope ScopeCardioFitness must be granted
 func (m *Session) CardioFitnessScoreByDay(date string) (CardioFitnessScoreLog, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/cardioscore/date/%s.json", date))
+	return m.CardioFitnessScoreByDayContext(context.Background(), date)
+}
+
+// CardioFitnessScoreByDayContext is like CardioFitnessScoreByDay but uses ctx for the request
+func (m *Session) CardioFitnessScoreByDayContext(ctx context.Context, date string) (CardioFitnessScoreLog, error) {
+	date, err := checkDate(date)
+	if err != nil {
+		return CardioFitnessScoreLog{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/cardioscore/date/%s.json", m.apiURL, date))
 	if err != nil {
 		return CardioFitnessScoreLog{}, err
 	}
 
 	summary := CardioFitnessScoreLog{}
-	if err := json.Unmarshal(contents, &summary); err != nil {
+	if err := m.decode("CardioFitnessScoreByDay", contents, &summary); err != nil {
 		return CardioFitnessScoreLog{}, err
 	}
 
 	return summary, nil
 }
 
+// CardioFitnessScoreOn is like CardioFitnessScoreByDay but takes a Date
+func (m *Session) CardioFitnessScoreOn(date Date) (CardioFitnessScoreLog, error) {
+	return m.CardioFitnessScoreOnContext(context.Background(), date)
+}
+
+// CardioFitnessScoreOnContext is like CardioFitnessScoreOn but uses ctx for the request
+func (m *Session) CardioFitnessScoreOnContext(ctx context.Context, date Date) (CardioFitnessScoreLog, error) {
+	if err := checkDates(date); err != nil {
+		return CardioFitnessScoreLog{}, err
+	}
+	return m.CardioFitnessScoreByDayContext(ctx, date.String())
+}
+
 // CardioFitnessScoreByDateRange returns the cardio fitness score (VO2Max) for the given date range
 // date must be in the format yyyy-MM-dd, scope ScopeCardioFitness must be granted
+// ranges longer than MaxDaysCardioFitness days are split into multiple requests
 func (m *Session) CardioFitnessScoreByDateRange(startDate, endDate string) (CardioFitnessScoreLog, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/cardioscore/date/%s/%s.json", startDate, endDate))
+	return m.CardioFitnessScoreByDateRangeContext(context.Background(), startDate, endDate)
+}
+
+// CardioFitnessScoreByDateRangeContext is like CardioFitnessScoreByDateRange but uses ctx for the request
+func (m *Session) CardioFitnessScoreByDateRangeContext(ctx context.Context, startDate, endDate string) (CardioFitnessScoreLog, error) {
+	startDate, endDate, err := checkDateRange(startDate, endDate)
+	if err != nil {
+		return CardioFitnessScoreLog{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDate, endDate, MaxDaysCardioFitness, m.cardioFitnessScoreByDateRange)
+}
+
+// CardioFitnessScoreBetween is like CardioFitnessScoreByDateRange but takes Dates
+func (m *Session) CardioFitnessScoreBetween(startDate Date, endDate Date) (CardioFitnessScoreLog, error) {
+	return m.CardioFitnessScoreBetweenContext(context.Background(), startDate, endDate)
+}
+
+// CardioFitnessScoreBetweenContext is like CardioFitnessScoreBetween but uses ctx for the request
+func (m *Session) CardioFitnessScoreBetweenContext(ctx context.Context, startDate Date, endDate Date) (CardioFitnessScoreLog, error) {
+	if err := checkDates(startDate, endDate); err != nil {
+		return CardioFitnessScoreLog{}, err
+	}
+	return m.CardioFitnessScoreByDateRangeContext(ctx, startDate.String(), endDate.String())
+}
+
+// cardioFitnessScoreByDateRange requests a single date range
+func (m *Session) cardioFitnessScoreByDateRange(ctx context.Context, startDate, endDate string) (CardioFitnessScoreLog, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/cardioscore/date/%s/%s.json", m.apiURL, startDate, endDate))
 	if err != nil {
 		return CardioFitnessScoreLog{}, err
 	}
 
 	summary := CardioFitnessScoreLog{}
-	if err := json.Unmarshal(contents, &summary); err != nil {
+	if err := m.decode("CardioFitnessScoreByDateRange", contents, &summary); err != nil {
 		return CardioFitnessScoreLog{}, err
 	}
 
 	return summary, nil
 }
+
+// Series returns the VO2 Max as normalized time series, a range is returned as the middle of the range
+// loc is the timezone of the user
+func (c CardioFitnessScoreLog) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, entry := range c.CardioScore {
+		if err := b.addString(MetricVO2Max, false, entry.DateTime, entry.Value.Vo2Max, UnitVO2Max); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
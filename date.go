@@ -0,0 +1,193 @@
+package fitbit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// timeOfDayLayout is the time format used by the Fitbit API
+const timeOfDayLayout = "15:04"
+
+// Date is a calendar date without time and location as used by the Fitbit API
+// The zero value is not a valid date and is rejected by all methods taking a Date, see Session.Today for the current date
+type Date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+// NewDate returns the date of the given year, month and day, values out of range are normalized like time.Date
+func NewDate(year int, month time.Month, day int) Date {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// DateOf returns the calendar date of t within the location of t
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{year: year, month: month, day: day}
+}
+
+// ParseDate parses a date in the format yyyy-MM-dd
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, must be in the format yyyy-MM-dd", value)
+	}
+	return DateOf(t), nil
+}
+
+// IsZero returns true if the date is the zero value
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// String returns the date in the format yyyy-MM-dd
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.year, d.month, d.day)
+}
+
+// Time returns the start of the date in the given location
+func (d Date) Time(loc *time.Location) time.Time {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, loc)
+}
+
+// AddDays returns the date n days after d, n can be negative
+func (d Date) AddDays(n int) Date {
+	return DateOf(d.Time(time.UTC).AddDate(0, 0, n))
+}
+
+// Before returns true if d is before other
+func (d Date) Before(other Date) bool {
+	return d.Time(time.UTC).Before(other.Time(time.UTC))
+}
+
+// TimeOfDay is a time within a day with minute precision as used by the Fitbit API
+type TimeOfDay struct {
+	hour   int
+	minute int
+}
+
+// NewTimeOfDay returns the time of day of the given hour and minute
+func NewTimeOfDay(hour int, minute int) (TimeOfDay, error) {
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return TimeOfDay{}, fmt.Errorf("invalid time of day %02d:%02d", hour, minute)
+	}
+	return TimeOfDay{hour: hour, minute: minute}, nil
+}
+
+// TimeOfDayOf returns the time of day of t within the location of t
+func TimeOfDayOf(t time.Time) TimeOfDay {
+	return TimeOfDay{hour: t.Hour(), minute: t.Minute()}
+}
+
+// ParseTimeOfDay parses a time in the format HH:mm
+func ParseTimeOfDay(value string) (TimeOfDay, error) {
+	t, err := time.Parse(timeOfDayLayout, value)
+	if err != nil {
+		return TimeOfDay{}, fmt.Errorf("invalid time of day %q, must be in the format HH:mm", value)
+	}
+	return TimeOfDayOf(t), nil
+}
+
+// String returns the time of day in the format HH:mm
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", t.hour, t.minute)
+}
+
+// Hour returns the hour of the time of day
+func (t TimeOfDay) Hour() int {
+	return t.hour
+}
+
+// Minute returns the minute of the time of day
+func (t TimeOfDay) Minute() int {
+	return t.minute
+}
+
+// SetLocation sets the timezone of the user used by Location and Today
+func (m *Session) SetLocation(loc *time.Location) {
+	m.mutex.Lock()
+	m.location = loc
+	m.mutex.Unlock()
+}
+
+// Location returns the timezone of the user
+// The timezone is requested from the profile of the user on first use if it was not set using SetLocation
+func (m *Session) Location(ctx context.Context) (*time.Location, error) {
+	m.mutex.RLock()
+	loc := m.location
+	m.mutex.RUnlock()
+	if loc != nil {
+		return loc, nil
+	}
+
+	profile, err := m.ProfileContext(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	loc, err = time.LoadLocation(profile.User.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	m.SetLocation(loc)
+	return loc, nil
+}
+
+// Today returns the current date within the timezone of the user
+func (m *Session) Today(ctx context.Context) (Date, error) {
+	loc, err := m.Location(ctx)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(time.Now().In(loc)), nil
+}
+
+// checkDate validates a date path parameter, an empty date is replaced by today
+func checkDate(day string) (string, error) {
+	if day == "" || day == "today" {
+		return "today", nil
+	}
+	date, err := ParseDate(day)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q, must be in the format yyyy-MM-dd or today", day)
+	}
+	return date.String(), nil
+}
+
+// checkDates returns an error if one of the dates is the zero value
+func checkDates(dates ...Date) error {
+	for _, date := range dates {
+		if date.IsZero() {
+			return errZeroDate
+		}
+	}
+	return nil
+}
+
+// checkDateRange validates the dates of a date range, empty dates are replaced by today
+func checkDateRange(startDay string, endDay string) (string, string, error) {
+	startDay, err := checkDate(startDay)
+	if err != nil {
+		return "", "", err
+	}
+	endDay, err = checkDate(endDay)
+	if err != nil {
+		return "", "", err
+	}
+	return startDay, endDay, nil
+}
+
+// checkPostDate validates a date sent within a request body which must be a date in the format yyyy-MM-dd
+func checkPostDate(day string) error {
+	if _, err := time.Parse(dateLayout, day); err != nil {
+		return fmt.Errorf("invalid date %q, must be in the format yyyy-MM-dd", day)
+	}
+	return nil
+}
+
+// checkTimeOfDay validates a time in the format HH:mm
+func checkTimeOfDay(value string) error {
+	_, err := ParseTimeOfDay(value)
+	return err
+}
@@ -0,0 +1,75 @@
+package fitbit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+func TestParseDate(t *testing.T) {
+	date, err := fitbit.ParseDate("2024-02-29")
+	if err != nil || date != fitbit.NewDate(2024, time.February, 29) {
+		t.Errorf("got %v, %v", date, err)
+	}
+	for _, value := range []string{"", "today", "2024-02-30", "29.02.2024"} {
+		if _, err := fitbit.ParseDate(value); err == nil {
+			t.Errorf("%q was parsed as date", value)
+		}
+	}
+	if date.AddDays(1).String() != "2024-03-01" {
+		t.Errorf("got %s", date.AddDays(1))
+	}
+}
+
+func TestZeroDateIsRejected(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	requests := len(server.Requests())
+
+	if _, err := session.HeartLogOn(fitbit.Date{}); err == nil {
+		t.Error("zero date was accepted")
+	}
+	if _, err := session.SleepBetween(fitbit.NewDate(2024, time.January, 1), fitbit.Date{}); err == nil {
+		t.Error("zero end date was accepted")
+	}
+	var activity fitbit.NewActivity
+	activity.ActivityID = 90013
+	activity.DurationMillis = 60000
+	activity.SetStart(fitbit.Date{}, fitbit.TimeOfDay{})
+	if _, err := session.LogActivity(activity); err == nil {
+		t.Error("activity with zero date was accepted")
+	}
+	if len(server.Requests()) != requests {
+		t.Error("request was sent for a zero date")
+	}
+}
+
+func TestDateVariants(t *testing.T) {
+	_, session := newTestSession(t, nil)
+	day := fitbit.NewDate(2024, time.January, 1)
+
+	heart, err := session.HeartLogOn(day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heart.ActivitiesHeart) != 1 || heart.ActivitiesHeart[0].DateTime != "2024-01-01" || heart.ActivitiesHeart[0].Value.RestingHeartRate != 58 {
+		t.Errorf("unexpected heart log %+v", heart.ActivitiesHeart)
+	}
+
+	heart, err = session.HeartLogBetween(day, day.AddDays(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heart.ActivitiesHeart) != 3 {
+		t.Errorf("%d days returned, expected 3", len(heart.ActivitiesHeart))
+	}
+
+	from, err := fitbit.NewTimeOfDay(8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, _ := fitbit.ParseTimeOfDay("09:30")
+	if _, err := session.HeartIntradayOn(day, "1min", from, to); err != nil {
+		t.Errorf("intraday: %v", err)
+	}
+}
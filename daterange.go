@@ -0,0 +1,264 @@
+package fitbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Maximum number of days of a single date range request, longer ranges are split into multiple requests
+// https://dev.fitbit.com/build/reference/web-api/
+const (
+	MaxDaysActiveZoneMinutes = 1095
+	MaxDaysBodyFat           = 30
+	MaxDaysBodyWeight        = 31
+	MaxDaysBreathingRate     = 30
+	MaxDaysCardioFitness     = 30
+	MaxDaysFoodWater         = 1095
+	MaxDaysHeartRate         = 1095
+	MaxDaysHRV               = 30
+	MaxDaysIntraday          = 1
+	MaxDaysSleep             = 100
+	MaxDaysSpO2              = 30
+	MaxDaysTemperature       = 30
+)
+
+// dateLayout is the date format used by the Fitbit API
+const dateLayout = "2006-01-02"
+
+// dateRange is a single date range of a split request
+type dateRange struct {
+	start string
+	end   string
+}
+
+// splitDateRange splits the date range into ranges of at most maxDays days
+// dates which can't be parsed like "today" are not split
+func splitDateRange(startDay string, endDay string, maxDays int) []dateRange {
+	start, errStart := time.Parse(dateLayout, startDay)
+	end, errEnd := time.Parse(dateLayout, endDay)
+	if errStart != nil || errEnd != nil || maxDays <= 0 || end.Before(start) {
+		return []dateRange{{start: startDay, end: endDay}}
+	}
+
+	var ranges []dateRange
+	for !start.After(end) {
+		chunkEnd := start.AddDate(0, 0, maxDays-1)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		ranges = append(ranges, dateRange{start: start.Format(dateLayout), end: chunkEnd.Format(dateLayout)})
+		start = chunkEnd.AddDate(0, 0, 1)
+	}
+	return ranges
+}
+
+// splitDateRangeToday splits the date range from start until "today" into ranges of at most maxDays days
+// The current date of the user is at most one day after the current date in UTC, the range is split up to that day
+// and the last range ends with "today" again, so it is valid in every timezone without requesting the timezone of the user
+func splitDateRangeToday(start time.Time, now time.Time, maxDays int) []dateRange {
+	latest := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	days := int(latest.Sub(start).Hours()/24) + 1
+	if days <= maxDays {
+		return []dateRange{{start: start.Format(dateLayout), end: "today"}}
+	}
+
+	// the first range takes the remaining days, so the last range has the full length
+	var ranges []dateRange
+	if first := days % maxDays; first > 0 {
+		ranges = append(ranges, dateRange{start: start.Format(dateLayout), end: start.AddDate(0, 0, first-1).Format(dateLayout)})
+		start = start.AddDate(0, 0, first)
+	}
+	ranges = append(ranges, splitDateRange(start.Format(dateLayout), latest.Format(dateLayout), maxDays)...)
+	ranges[len(ranges)-1].end = "today"
+	return ranges
+}
+
+// dateRanges splits the date range into ranges of at most maxDays days like splitDateRange, also if it contains "today"
+func (m *Session) dateRanges(ctx context.Context, startDay string, endDay string, maxDays int) ([]dateRange, error) {
+	if start, err := time.Parse(dateLayout, startDay); err == nil && endDay == "today" && maxDays >= 3 {
+		return splitDateRangeToday(start, time.Now().UTC(), maxDays), nil
+	}
+	startDay, endDay, err := m.resolveToday(ctx, startDay, endDay, maxDays)
+	if err != nil {
+		return nil, err
+	}
+	return splitDateRange(startDay, endDay, maxDays), nil
+}
+
+// resolveToday replaces "today" within the date range by the current date of the user if the range can be longer than maxDays
+// The timezone of the user is requested from the profile on first use, see Location
+// without the profile scope the current date in UTC is used instead
+func (m *Session) resolveToday(ctx context.Context, startDay string, endDay string, maxDays int) (string, string, error) {
+	if (startDay == "today") == (endDay == "today") {
+		return startDay, endDay, nil
+	}
+	other := startDay
+	if other == "today" {
+		other = endDay
+	}
+	// the current date of the user differs by at most one day from the current date in UTC
+	if date, err := time.Parse(dateLayout, other); err == nil {
+		days := time.Now().UTC().Sub(date).Hours() / 24
+		if days < 0 {
+			days = -days
+		}
+		if int(days)+2 <= maxDays {
+			return startDay, endDay, nil
+		}
+	}
+
+	today, err := m.Today(ctx)
+	if errors.Is(err, ErrInsufficientScope) {
+		today, err = DateOf(time.Now().UTC()), nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("resolving today: %w", err)
+	}
+	if startDay == "today" {
+		startDay = today.String()
+	}
+	if endDay == "today" {
+		endDay = today.String()
+	}
+	return startDay, endDay, nil
+}
+
+// fetchDateRange requests the date range using fetch, ranges longer than maxDays are split into multiple requests
+// which are sent with the configured concurrency and merged into a single response in chronological order
+func fetchDateRange[T any](ctx context.Context, m *Session, startDay string, endDay string, maxDays int,
+	fetch func(ctx context.Context, startDay string, endDay string) (T, error)) (T, error) {
+	ranges, err := m.dateRanges(ctx, startDay, endDay, maxDays)
+	if err != nil {
+		var empty T
+		return empty, err
+	}
+	if len(ranges) == 1 {
+		return fetch(ctx, ranges[0].start, ranges[0].end)
+	}
+
+	concurrency := m.config.ChunkConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// stop outstanding requests on the first error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]T, len(ranges))
+	flushes := make([]func(), 0, len(ranges))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+
+	for i, r := range ranges {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		chunkCtx, flush := withChunkRawResponse(ctx)
+		flushes = append(flushes, flush)
+
+		wg.Add(1)
+		go func(i int, r dateRange) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			result, err := fetch(chunkCtx, r.start, r.end)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = result
+		}(i, r)
+	}
+	wg.Wait()
+
+	// provide the raw responses of all chunks in chronological order
+	for _, flush := range flushes {
+		flush()
+	}
+
+	var merged T
+	if firstErr != nil {
+		return merged, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return merged, err
+	}
+	for _, result := range results {
+		mergeResponse(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(result))
+	}
+	return merged, nil
+}
+
+// mergeResponse merges src into dst, slices are appended and structs are merged by field
+// numbers within fields tagged with merge:"sum" like summaries of all days are added
+// other values of dst are only set if they are not set yet
+func mergeResponse(dst reflect.Value, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Slice:
+		dst.Set(reflect.AppendSlice(dst, src))
+	case reflect.Struct:
+		if !mergeableStruct(dst.Type()) {
+			if dst.IsZero() {
+				dst.Set(src)
+			}
+			return
+		}
+		for i := 0; i < dst.NumField(); i++ {
+			if dst.Type().Field(i).Tag.Get("merge") == "sum" {
+				sumResponse(dst.Field(i), src.Field(i))
+				continue
+			}
+			mergeResponse(dst.Field(i), src.Field(i))
+		}
+	default:
+		if dst.IsZero() {
+			dst.Set(src)
+		}
+	}
+}
+
+// sumResponse adds all numbers of src to dst, other values are merged like mergeResponse
+func sumResponse(dst reflect.Value, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dst.SetInt(dst.Int() + src.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		dst.SetUint(dst.Uint() + src.Uint())
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(dst.Float() + src.Float())
+	case reflect.Struct:
+		if !mergeableStruct(dst.Type()) {
+			mergeResponse(dst, src)
+			return
+		}
+		for i := 0; i < dst.NumField(); i++ {
+			sumResponse(dst.Field(i), src.Field(i))
+		}
+	default:
+		mergeResponse(dst, src)
+	}
+}
+
+// mergeableStruct returns true if all fields of the struct are exported, structs like time.Time are handled as single value
+func mergeableStruct(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
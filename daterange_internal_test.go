@@ -0,0 +1,80 @@
+package fitbit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitDateRange(t *testing.T) {
+	ranges := splitDateRange("2024-01-01", "2024-03-05", 30)
+	expected := []dateRange{
+		{start: "2024-01-01", end: "2024-01-30"},
+		{start: "2024-01-31", end: "2024-02-29"},
+		{start: "2024-03-01", end: "2024-03-05"},
+	}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("got %v, expected %v", ranges, expected)
+	}
+}
+
+func TestResolveToday(t *testing.T) {
+	session := New(Config{})
+	loc := time.FixedZone("UTC+14", 14*60*60)
+	session.SetLocation(loc)
+	today := DateOf(time.Now().In(loc))
+
+	// short ranges are not resolved to avoid requesting the timezone
+	start := today.AddDays(-5).String()
+	startDay, endDay, err := session.resolveToday(context.Background(), start, "today", 30)
+	if err != nil || startDay != start || endDay != "today" {
+		t.Errorf("short range resolved to %s - %s, %v", startDay, endDay, err)
+	}
+
+	start = today.AddDays(-40).String()
+	startDay, endDay, err = session.resolveToday(context.Background(), start, "today", 30)
+	if err != nil || startDay != start || endDay != today.String() {
+		t.Errorf("long range resolved to %s - %s, %v, expected today to be %s", startDay, endDay, err, today)
+	}
+}
+
+func TestSplitDateRangeToday(t *testing.T) {
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		start    string
+		expected []dateRange
+	}{
+		{"2024-03-01", []dateRange{{"2024-03-01", "today"}}},
+		{"2024-02-06", []dateRange{{"2024-02-06", "today"}}},
+		{"2024-02-05", []dateRange{{"2024-02-05", "2024-02-05"}, {"2024-02-06", "today"}}},
+		{"2024-01-01", []dateRange{{"2024-01-01", "2024-01-06"}, {"2024-01-07", "2024-02-05"}, {"2024-02-06", "today"}}},
+	}
+	for _, test := range tests {
+		start, _ := time.Parse(dateLayout, test.start)
+		if ranges := splitDateRangeToday(start, now, 30); !reflect.DeepEqual(ranges, test.expected) {
+			t.Errorf("range from %s was split into %v, expected %v", test.start, ranges, test.expected)
+		}
+	}
+}
+
+func TestMergeResponseSumsSummaries(t *testing.T) {
+	var first, second SleepDay
+	if err := json.Unmarshal([]byte(`{"sleep":[{"dateOfSleep":"2024-01-01"}],"summary":{"stages":{"deep":60},"totalMinutesAsleep":400},"meta":{"state":"pending"}}`), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"sleep":[{"dateOfSleep":"2024-01-02"}],"summary":{"stages":{"deep":70},"totalMinutesAsleep":420}}`), &second); err != nil {
+		t.Fatal(err)
+	}
+
+	var merged SleepDay
+	mergeResponse(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(first))
+	mergeResponse(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(second))
+	if merged.Summary.TotalMinutesAsleep != 820 || merged.Summary.Stages.Deep != 130 {
+		t.Errorf("summary was not summed: %+v", merged.Summary)
+	}
+	if len(merged.Sleep) != 2 || merged.Meta.State != "pending" {
+		t.Errorf("sleep entries or meta were not merged: %d, %q", len(merged.Sleep), merged.Meta.State)
+	}
+}
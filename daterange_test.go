@@ -0,0 +1,83 @@
+package fitbit_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+)
+
+func TestDateRangeEndingTodayIsSplit(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	start := fitbit.DateOf(time.Now()).AddDays(-40).String()
+
+	requests := len(server.Requests())
+	if _, err := session.BodyWeightLogByDateRange(start, "today"); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, request := range server.Requests()[requests:] {
+		if request.Path == "/1/user/-/profile.json" {
+			t.Error("the profile was requested to resolve today")
+		}
+		if strings.Contains(request.Path, "/body/log/weight/") {
+			paths = append(paths, request.Path)
+		}
+	}
+	if len(paths) != 2 {
+		t.Fatalf("range of 41 days was requested in %d requests, expected 2", len(paths))
+	}
+	// only the last range ends with today, which is valid in every timezone of the user
+	if !strings.HasPrefix(paths[0], "/1/user/-/body/log/weight/date/"+start+"/") || strings.Contains(paths[0], "today") {
+		t.Errorf("first range was requested as %s", paths[0])
+	}
+	if !strings.HasSuffix(paths[1], "/today.json") {
+		t.Errorf("last range was requested as %s", paths[1])
+	}
+}
+
+func TestHeartIntradayTodayWithoutProfileScope(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	server.Inject(fitbittest.Fault{Path: "/1/user/-/profile.json", StatusCode: http.StatusForbidden, ErrorType: "insufficient_scope"})
+
+	// the current date in UTC is used if the timezone can't be requested
+	yesterday := fitbit.DateOf(time.Now().UTC()).AddDays(-1).String()
+	_, err := session.HeartLogByDateRangeIntraday(yesterday, "today", "1min")
+	if err == nil || !strings.Contains(err.Error(), "longer than one day") {
+		t.Errorf("expected the range to be longer than one day, got %v", err)
+	}
+}
+
+func TestHeartIntradayDays(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	loc, _ := time.LoadLocation("Europe/Vienna")
+	server.Update("ABC123", func(u *fitbittest.User) {
+		for _, date := range []string{"2024-01-02", "2024-01-03"} {
+			day, _ := time.ParseInLocation("2006-01-02", date, loc)
+			u.Days[date] = &fitbittest.Day{HeartRate: []fitbittest.Sample{
+				{Time: day.Add(8 * time.Hour), Value: 60},
+				{Time: day.Add(8*time.Hour + time.Minute), Value: 62},
+			}}
+		}
+	})
+
+	if _, err := session.HeartLogByDateRangeIntraday("2024-01-02", "2024-01-03", "1min"); err == nil {
+		t.Error("intraday range of two days did not fail")
+	}
+
+	days, err := session.HeartLogIntradayDays("2024-01-02", "2024-01-03", "1min")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("%d days returned, expected 2", len(days))
+	}
+	for i, date := range []string{"2024-01-02", "2024-01-03"} {
+		if days[i].ActivitiesHeart[0].DateTime != date || len(days[i].ActivitiesHeartIntraday.Dataset) != 2 {
+			t.Errorf("day %d: %s with %d samples", i, days[i].ActivitiesHeart[0].DateTime, len(days[i].ActivitiesHeartIntraday.Dataset))
+		}
+	}
+}
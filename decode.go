@@ -0,0 +1,211 @@
+package fitbit
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// UnknownFieldsError is returned by DecodeStrict if the data contains fields which are not part of the target struct
+type UnknownFieldsError struct {
+	Endpoint string   // Endpoint is the name of the session method of the response, empty if returned by DecodeStrict
+	Fields   []string // Fields contains the paths of all unknown fields, e.g. activities-heart[].value.newField
+}
+
+// Error returns a readable representation of the error
+func (e *UnknownFieldsError) Error() string {
+	if e.Endpoint != "" {
+		return fmt.Sprintf("fitbit: unknown fields in response of %s: %s", e.Endpoint, strings.Join(e.Fields, ", "))
+	}
+	return fmt.Sprintf("fitbit: unknown fields %s", strings.Join(e.Fields, ", "))
+}
+
+// DecodeStrict decodes data into v like json.Unmarshal, but returns an *UnknownFieldsError
+// if data contains fields which are not captured by v. v is fully decoded in this case
+// Unlike json.Decoder.DisallowUnknownFields all unknown fields are reported instead of only the first one
+func DecodeStrict(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	fields, err := UnknownFields(data, v)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return &UnknownFieldsError{Fields: fields}
+	}
+	return nil
+}
+
+// decode decodes the response of endpoint into v
+// Unknown fields are reported to Config.UnknownFields and fail decoding if Config.StrictDecoding is set
+func (m *Session) decode(endpoint string, contents []byte, v interface{}) error {
+	if err := json.Unmarshal(contents, v); err != nil {
+		return err
+	}
+	if !m.config.StrictDecoding && m.config.UnknownFields == nil {
+		return nil
+	}
+
+	fields, err := UnknownFields(contents, v)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+	if m.config.UnknownFields != nil {
+		m.config.UnknownFields(endpoint, fields)
+	}
+	if m.config.StrictDecoding {
+		return &UnknownFieldsError{Endpoint: endpoint, Fields: fields}
+	}
+	return nil
+}
+
+// UnknownFields returns the sorted paths of all fields within data which are not captured by the type of v
+// Array elements are written as [] and map entries by their key, values decoded into interface{}
+// or types with a custom unmarshaler are not inspected
+func UnknownFields(data []byte, v interface{}) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	unknown := make(map[string]struct{})
+	collectUnknownFields(value, reflect.TypeOf(v), "", unknown)
+
+	fields := make([]string, 0, len(unknown))
+	for field := range unknown {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// collectUnknownFields adds the paths of all fields of value which are not captured by t to unknown
+func collectUnknownFields(value interface{}, t reflect.Type, path string, unknown map[string]struct{}) {
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for key, child := range object {
+			field, ok := lookupJSONField(fields, key)
+			if !ok {
+				unknown[joinFieldPath(path, key)] = struct{}{}
+				continue
+			}
+			collectUnknownFields(child, field.Type, joinFieldPath(path, key), unknown)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, child := range object {
+			collectUnknownFields(child, t.Elem(), joinFieldPath(path, key), unknown)
+		}
+	case reflect.Slice, reflect.Array:
+		array, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for _, child := range array {
+			collectUnknownFields(child, t.Elem(), path+"[]", unknown)
+		}
+	}
+}
+
+// jsonField is a struct field with its name within JSON
+type jsonField struct {
+	Name string
+	Type reflect.Type
+}
+
+// jsonFields returns all fields of the struct type t which are decoded by encoding/json including promoted fields of embedded structs
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	seen := make(map[string]struct{})
+	types := []reflect.Type{t}
+	for len(types) > 0 {
+		var embedded []reflect.Type
+		for _, current := range types {
+			for i := 0; i < current.NumField(); i++ {
+				field := current.Field(i)
+				tag := field.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, _, _ := strings.Cut(tag, ",")
+				if field.Anonymous && name == "" {
+					fieldType := field.Type
+					if fieldType.Kind() == reflect.Pointer {
+						fieldType = fieldType.Elem()
+					}
+					if fieldType.Kind() == reflect.Struct {
+						embedded = append(embedded, fieldType)
+						continue
+					}
+				}
+				if !field.IsExported() {
+					continue
+				}
+				if name == "" {
+					name = field.Name
+				}
+				// fields of outer structs hide fields of embedded structs with the same name
+				if _, ok := seen[name]; ok {
+					continue
+				}
+				seen[name] = struct{}{}
+				fields = append(fields, jsonField{Name: name, Type: field.Type})
+			}
+		}
+		types = embedded
+	}
+	return fields
+}
+
+// lookupJSONField returns the field matching key, preferring an exact match over a case-insensitive one like encoding/json
+func lookupJSONField(fields []jsonField, key string) (jsonField, bool) {
+	for _, field := range fields {
+		if field.Name == key {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.Name, key) {
+			return field, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// joinFieldPath appends key to the field path
+func joinFieldPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,133 @@
+package fitbit_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+// responseFixtures maps the fixtures within testdata/responses to the type their response is decoded into
+var responseFixtures = map[string]func() interface{}{
+	"activities_frequent":     func() interface{} { return &[]fitbit.ActivitiesFrequent{} },
+	"activities_goal":         func() interface{} { return &fitbit.ActivitiesGoal{} },
+	"activities_lifetime":     func() interface{} { return &fitbit.ActivitiesLifetime{} },
+	"activities_summary_day":  func() interface{} { return &fitbit.ActivitiesSummaryDay{} },
+	"activity_intraday":       func() interface{} { return &fitbit.ActivitiesInterdayLog{} },
+	"activity_log_list":       func() interface{} { return &fitbit.ActivitiesLogList{} },
+	"activity_new":            func() interface{} { return &fitbit.NewActivityResponse{} },
+	"activity_time_series":    func() interface{} { return &fitbit.ActivitiesLog{} },
+	"activity_types":          func() interface{} { return &fitbit.ActivitiesTypes{} },
+	"azm_day":                 func() interface{} { return &fitbit.ActiveZoneMinutesDay{} },
+	"azm_intraday":            func() interface{} { return &fitbit.ActiveZoneMinutesIntraday{} },
+	"badges":                  func() interface{} { return &fitbit.BadgesList{} },
+	"body_fat":                func() interface{} { return &fitbit.BodyFat{} },
+	"body_fat_goal":           func() interface{} { return &fitbit.BodyFatGoal{} },
+	"body_weight":             func() interface{} { return &fitbit.BodyWeight{} },
+	"body_weight_goal":        func() interface{} { return &fitbit.BodyWeightGoal{} },
+	"breathing_rate":          func() interface{} { return &fitbit.BreathingRate{} },
+	"breathing_rate_intraday": func() interface{} { return &fitbit.BreathingRateIntraday{} },
+	"cardio_score":            func() interface{} { return &fitbit.CardioFitnessScoreLog{} },
+	"devices":                 func() interface{} { return &[]fitbit.Device{} },
+	"ecg_log_list":            func() interface{} { return &fitbit.ECGLogList{} },
+	"food_add":                func() interface{} { return &fitbit.AddFoodLogResponse{} },
+	"food_entry":              func() interface{} { return &fitbit.FoodEntry{} },
+	"food_frequent":           func() interface{} { return &fitbit.FoodCollectionList{} },
+	"food_goal":               func() interface{} { return &fitbit.FoodGoal{} },
+	"food_locales":            func() interface{} { return &fitbit.FoodLocales{} },
+	"food_log":                func() interface{} { return &fitbit.FoodLog{} },
+	"food_log_range":          func() interface{} { return &fitbit.FoodWaterLogDateRange{} },
+	"food_search":             func() interface{} { return &fitbit.FoodSearchResult{} },
+	"food_units":              func() interface{} { return &fitbit.FoodUnits{} },
+	"friends":                 func() interface{} { return &fitbit.FriendsList{} },
+	"friends_invitations":     func() interface{} { return &fitbit.FriendsInvitations{} },
+	"friends_leaderboard":     func() interface{} { return &fitbit.FriendsLeaderboard{} },
+	"heart_day":               func() interface{} { return &fitbit.HeartDay{} },
+	"heart_intraday":          func() interface{} { return &fitbit.HeartIntraday{} },
+	"hrv_intraday":            func() interface{} { return &fitbit.HeartRateVariabilityIntraday{} },
+	"hrv_summary":             func() interface{} { return &fitbit.HeartRateVariabilitySummary{} },
+	"introspect":              func() interface{} { return &fitbit.IntrospectResponse{} },
+	"profile":                 func() interface{} { return &fitbit.Profile{} },
+	"sleep_day":               func() interface{} { return &fitbit.SleepDay{} },
+	"sleep_goal":              func() interface{} { return &fitbit.SleepGoal{} },
+	"sleep_log_list":          func() interface{} { return &fitbit.SleepLogList{} },
+	"spo2_day":                func() interface{} { return &fitbit.SpO2{} },
+	"spo2_intraday":           func() interface{} { return &fitbit.SpO2Intraday{} },
+	"spo2_range":              func() interface{} { return &[]fitbit.SpO2{} },
+	"subscription":            func() interface{} { return &fitbit.Subscription{} },
+	"subscriptions":           func() interface{} { return &fitbit.SubscriptionList{} },
+	"temperature_core":        func() interface{} { return &fitbit.TemperatureCore{} },
+	"temperature_skin":        func() interface{} { return &fitbit.TemperatureSkin{} },
+	"water_goal":              func() interface{} { return &fitbit.WaterGoal{} },
+	"water_log":               func() interface{} { return &fitbit.WaterLog{} },
+	"water_log_range":         func() interface{} { return &fitbit.FoodWaterLogDateRange{} },
+}
+
+func TestDecodeFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "responses", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]bool)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		found[name] = true
+
+		t.Run(name, func(t *testing.T) {
+			target, ok := responseFixtures[name]
+			if !ok {
+				t.Fatalf("no response type defined for fixture %s", file)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := target()
+			if err := fitbit.DecodeStrict(data, v); err != nil {
+				t.Fatalf("DecodeStrict: %v", err)
+			}
+			if reflect.ValueOf(v).Elem().IsZero() {
+				t.Errorf("decoded %T is empty", v)
+			}
+		})
+	}
+
+	for name := range responseFixtures {
+		if !found[name] {
+			t.Errorf("fixture testdata/responses/%s.json is missing", name)
+		}
+	}
+}
+
+func TestDecodeStrictReportsUnknownFields(t *testing.T) {
+	data := []byte(`{
+		"activities-heart": [{
+			"dateTime": "2024-01-01",
+			"value": {"restingHeartRate": 58, "heartRateZones": [{"name": "Peak", "zoneColor": "red"}], "newField": 1}
+		}],
+		"extra": []
+	}`)
+
+	var heart fitbit.HeartDay
+	err := fitbit.DecodeStrict(data, &heart)
+	var unknown *fitbit.UnknownFieldsError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected *UnknownFieldsError, got %v", err)
+	}
+	expected := []string{
+		"activities-heart[].value.heartRateZones[].zoneColor",
+		"activities-heart[].value.newField",
+		"extra",
+	}
+	if !reflect.DeepEqual(unknown.Fields, expected) {
+		t.Errorf("expected unknown fields %v, got %v", expected, unknown.Fields)
+	}
+	if len(heart.ActivitiesHeart) != 1 || heart.ActivitiesHeart[0].Value.RestingHeartRate != 58 {
+		t.Errorf("expected the known fields to be decoded, got %+v", heart)
+	}
+}
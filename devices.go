@@ -1,7 +1,7 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strconv"
 )
@@ -10,30 +10,35 @@ import (
 
 // Device contains information about a fitbit device
 type Device struct {
-	Battery       string        `json:"battery"`
-	BatteryLevel  int           `json:"batteryLevel,omitempty"`
-	DeviceVersion string        `json:"deviceVersion"`
-	Features      []interface{} `json:"features"`
-	ID            string        `json:"id"`
-	LastSyncTime  string        `json:"lastSyncTime"`
-	Mac           string        `json:"mac,omitempty"`
-	Type          string        `json:"type"`
+	Battery       string   `json:"battery"`
+	BatteryLevel  int      `json:"batteryLevel,omitempty"`
+	DeviceVersion string   `json:"deviceVersion"`
+	Features      []string `json:"features"`
+	ID            string   `json:"id"`
+	LastSyncTime  string   `json:"lastSyncTime"`
+	Mac           string   `json:"mac,omitempty"`
+	Type          string   `json:"type"`
 }
 
 // Devices returns
 func (m *Session) Devices(userID uint64) ([]Device, error) {
+	return m.DevicesContext(context.Background(), userID)
+}
+
+// DevicesContext is like Devices but uses ctx for the request
+func (m *Session) DevicesContext(ctx context.Context, userID uint64) ([]Device, error) {
 	// Default "-" is current logged in user
 	requestID := "-"
 	if userID > 0 {
 		requestID = strconv.FormatUint(userID, 10)
 	}
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/%s/devices.json", requestID))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/%s/devices.json", m.apiURL, requestID))
 	if err != nil {
 		return []Device{}, err
 	}
 
 	device := []Device{}
-	if err := json.Unmarshal(contents, &device); err != nil {
+	if err := m.decode("Devices", contents, &device); err != nil {
 		return []Device{}, err
 	}
 
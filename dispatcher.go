@@ -0,0 +1,293 @@
+package fitbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Collection types of subscription notifications
+// https://dev.fitbit.com/build/reference/web-api/developer-guide/using-subscriptions/#Subscriber-Notifications
+const (
+	CollectionActivities        = "activities"
+	CollectionBody              = "body"
+	CollectionFoods             = "foods"
+	CollectionSleep             = "sleep"
+	CollectionUserRevokedAccess = "userRevokedAccess"
+	CollectionDeleteUser        = "deleteUser"
+)
+
+// Dispatcher fetches the changed data of subscription notifications and passes it to the registered handlers
+// Notifications passed to Enqueue are fetched after Window by a fixed number of workers, notifications of the same
+// owner, collection type and date arriving until the fetch starts are coalesced into a single fetch
+type Dispatcher struct {
+	// Sessions returns the session of the owner of a notification (required), e.g. Manager.Session
+	Sessions func(ownerID string) (*Session, error)
+	// Window is the delay of Enqueue before fetching the data of a notification to coalesce duplicates (default: 1m)
+	Window time.Duration
+	// Workers is the number of notifications fetched concurrently by Enqueue (default: 4)
+	Workers int
+	// QueueSize is the maximum number of pending notifications of Enqueue, further notifications are dropped (default: 1000)
+	QueueSize int
+
+	// Handlers called with the fetched data of a notification, data of collection types without handler is not fetched
+	OnActivities        func(ctx context.Context, notification Subscription, summary ActivitiesSummaryDay)
+	OnBody              func(ctx context.Context, notification Subscription, weight BodyWeight, fat BodyFat)
+	OnFoods             func(ctx context.Context, notification Subscription, food FoodLog, water WaterLog)
+	OnSleep             func(ctx context.Context, notification Subscription, sleep SleepDay)
+	OnUserRevokedAccess func(ctx context.Context, notification Subscription)
+	OnDeleteUser        func(ctx context.Context, notification Subscription)
+	// OnError is called if the data of a notification can't be fetched
+	OnError func(ctx context.Context, notification Subscription, err error)
+
+	startOnce sync.Once
+	mutex     sync.Mutex
+	pending   map[string]*pendingNotification
+	closed    bool
+	ready     chan string
+	stop      chan struct{}
+	inflight  sync.WaitGroup
+}
+
+// pendingNotification is a notification waiting for its fetch
+type pendingNotification struct {
+	ctx          context.Context
+	notification Subscription
+	timer        *time.Timer
+}
+
+// Enqueue schedules the notifications to be dispatched in the background and returns immediately
+// It can be used as callback of SubscriberHandler, errors while fetching are passed to OnError
+// Returns ErrQueueFull if notifications were dropped because QueueSize notifications are pending
+func (d *Dispatcher) Enqueue(ctx context.Context, notifications []Subscription) error {
+	d.start()
+	window := d.Window
+	if window <= 0 {
+		window = time.Minute
+	}
+	// the request context ends with the notification request, keep its values only
+	ctx = context.WithoutCancel(ctx)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	dropped := 0
+	for _, notification := range notifications {
+		key := notificationKey(notification)
+		// the pending fetch starts after this notification and covers it
+		if _, ok := d.pending[key]; ok {
+			continue
+		}
+		if len(d.pending) >= cap(d.ready) {
+			dropped++
+			continue
+		}
+		d.inflight.Add(1)
+		d.pending[key] = &pendingNotification{
+			ctx:          ctx,
+			notification: notification,
+			timer: time.AfterFunc(window, func() {
+				d.ready <- key
+			}),
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("%w: dropped %d notifications", ErrQueueFull, dropped)
+	}
+	return nil
+}
+
+// Shutdown stops accepting notifications, fetches all pending notifications without waiting for Window
+// and waits until they are dispatched or ctx is done
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.start()
+	d.mutex.Lock()
+	if !d.closed {
+		d.closed = true
+		for key, pending := range d.pending {
+			// notifications whose timer already fired are in the queue already
+			if pending.timer.Stop() {
+				d.ready <- key
+			}
+		}
+	}
+	d.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		d.mutex.Lock()
+		if d.stop != nil {
+			close(d.stop)
+			d.stop = nil
+		}
+		d.mutex.Unlock()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// start starts the workers of Enqueue
+func (d *Dispatcher) start() {
+	d.startOnce.Do(func() {
+		workers := d.Workers
+		if workers <= 0 {
+			workers = 4
+		}
+		queueSize := d.QueueSize
+		if queueSize <= 0 {
+			queueSize = 1000
+		}
+		d.pending = make(map[string]*pendingNotification)
+		// the queue holds at most all pending notifications, sending never blocks
+		d.ready = make(chan string, queueSize)
+		d.stop = make(chan struct{})
+		for i := 0; i < workers; i++ {
+			go d.work(d.stop)
+		}
+	})
+}
+
+// work dispatches queued notifications until stop is closed
+func (d *Dispatcher) work(stop chan struct{}) {
+	for {
+		select {
+		case key := <-d.ready:
+			d.mutex.Lock()
+			pending := d.pending[key]
+			delete(d.pending, key)
+			d.mutex.Unlock()
+
+			_ = d.dispatchNotification(pending.ctx, pending.notification)
+			d.inflight.Done()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Dispatch fetches the changed data of all notifications and passes it to the registered handlers
+// Duplicate notifications within notifications are fetched once
+// Returns all errors which appeared while fetching the data, errors are also passed to OnError
+func (d *Dispatcher) Dispatch(ctx context.Context, notifications []Subscription) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, notification := range notifications {
+		key := notificationKey(notification)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := d.dispatchNotification(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dispatchNotification dispatches a single notification and passes errors to OnError
+func (d *Dispatcher) dispatchNotification(ctx context.Context, notification Subscription) error {
+	err := d.dispatch(ctx, notification)
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("%s of %s on %s: %w", notification.CollectionType, notification.OwnerID, notification.Date, err)
+	if d.OnError != nil {
+		d.OnError(ctx, notification, err)
+	}
+	return err
+}
+
+// notificationKey returns the key of a notification which identifies the data to fetch
+func notificationKey(notification Subscription) string {
+	return notification.OwnerID + "/" + notification.CollectionType + "/" + notification.Date
+}
+
+// dispatch fetches the data of a single notification and calls the matching handler
+func (d *Dispatcher) dispatch(ctx context.Context, notification Subscription) error {
+	switch notification.CollectionType {
+	case CollectionUserRevokedAccess:
+		if d.OnUserRevokedAccess != nil {
+			d.OnUserRevokedAccess(ctx, notification)
+		}
+		return nil
+	case CollectionDeleteUser:
+		if d.OnDeleteUser != nil {
+			d.OnDeleteUser(ctx, notification)
+		}
+		return nil
+	case CollectionActivities, CollectionBody, CollectionFoods, CollectionSleep:
+	default:
+		return fmt.Errorf("unknown collection type %q", notification.CollectionType)
+	}
+
+	if !d.hasHandler(notification.CollectionType) {
+		return nil
+	}
+
+	session, err := d.Sessions(notification.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	switch notification.CollectionType {
+	case CollectionActivities:
+		summary, err := session.ActivitiesDaySummaryContext(ctx, notification.Date)
+		if err != nil {
+			return err
+		}
+		d.OnActivities(ctx, notification, summary)
+	case CollectionBody:
+		weight, err := session.BodyWeightLogByDayContext(ctx, notification.Date)
+		if err != nil {
+			return err
+		}
+		fat, err := session.BodyFatLogByDayContext(ctx, notification.Date)
+		if err != nil {
+			return err
+		}
+		d.OnBody(ctx, notification, weight, fat)
+	case CollectionFoods:
+		food, err := session.FoodLogByDayContext(ctx, notification.Date)
+		if err != nil {
+			return err
+		}
+		water, err := session.WaterLogByDayContext(ctx, notification.Date)
+		if err != nil {
+			return err
+		}
+		d.OnFoods(ctx, notification, food, water)
+	case CollectionSleep:
+		sleep, err := session.SleepByDayContext(ctx, notification.Date)
+		if err != nil {
+			return err
+		}
+		d.OnSleep(ctx, notification, sleep)
+	}
+	return nil
+}
+
+// hasHandler returns true if a handler for the collection type is registered
+func (d *Dispatcher) hasHandler(collectionType string) bool {
+	switch collectionType {
+	case CollectionActivities:
+		return d.OnActivities != nil
+	case CollectionBody:
+		return d.OnBody != nil
+	case CollectionFoods:
+		return d.OnFoods != nil
+	case CollectionSleep:
+		return d.OnSleep != nil
+	}
+	return false
+}
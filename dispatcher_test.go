@@ -0,0 +1,172 @@
+package fitbit_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+)
+
+// activities returns a notification of changed activities of the test user
+func activities(date string) fitbit.Subscription {
+	return fitbit.Subscription{CollectionType: fitbit.CollectionActivities, Date: date, OwnerID: "ABC123"}
+}
+
+// newTestDispatcher returns a dispatcher using the session for all owners
+func newTestDispatcher(t *testing.T, session *fitbit.Session) *fitbit.Dispatcher {
+	return &fitbit.Dispatcher{
+		Sessions: func(ownerID string) (*fitbit.Session, error) { return session, nil },
+		OnError: func(ctx context.Context, notification fitbit.Subscription, err error) {
+			t.Errorf("dispatch: %v", err)
+		},
+	}
+}
+
+func TestDispatcherCoalescesPendingNotifications(t *testing.T) {
+	_, session := newTestSession(t, nil)
+	var calls atomic.Int32
+	dispatcher := newTestDispatcher(t, session)
+	dispatcher.Window = 50 * time.Millisecond
+	dispatcher.OnActivities = func(ctx context.Context, notification fitbit.Subscription, summary fitbit.ActivitiesSummaryDay) {
+		calls.Add(1)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := dispatcher.Enqueue(context.Background(), []fitbit.Subscription{activities("2024-01-01")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := dispatcher.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("%d fetches of duplicate notifications, expected 1", calls.Load())
+	}
+}
+
+func TestDispatcherFetchesNotificationsAfterFetch(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	fetched := make(chan int, 2)
+	dispatcher := newTestDispatcher(t, session)
+	dispatcher.Window = 10 * time.Millisecond
+	dispatcher.OnActivities = func(ctx context.Context, notification fitbit.Subscription, summary fitbit.ActivitiesSummaryDay) {
+		fetched <- summary.Summary.Steps
+	}
+
+	if err := dispatcher.Enqueue(context.Background(), []fitbit.Subscription{activities("2024-01-01")}); err != nil {
+		t.Fatal(err)
+	}
+	if steps := <-fetched; steps != 8500 {
+		t.Errorf("%d steps fetched, expected 8500", steps)
+	}
+
+	// data changed after the first fetch must be fetched again
+	server.Update("ABC123", func(u *fitbittest.User) { u.Days["2024-01-01"].Steps = 9000 })
+	if err := dispatcher.Enqueue(context.Background(), []fitbit.Subscription{activities("2024-01-01")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(fetched)
+	if steps := <-fetched; steps != 9000 {
+		t.Errorf("%d steps fetched after the update, expected 9000", steps)
+	}
+}
+
+func TestDispatcherQueueSize(t *testing.T) {
+	_, session := newTestSession(t, nil)
+	var calls atomic.Int32
+	dispatcher := newTestDispatcher(t, session)
+	dispatcher.Window = time.Hour
+	dispatcher.QueueSize = 2
+	dispatcher.OnActivities = func(ctx context.Context, notification fitbit.Subscription, summary fitbit.ActivitiesSummaryDay) {
+		calls.Add(1)
+	}
+
+	err := dispatcher.Enqueue(context.Background(), []fitbit.Subscription{
+		activities("2024-01-01"),
+		activities("2024-01-02"),
+		activities("2024-01-03"),
+	})
+	if !errors.Is(err, fitbit.ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	// pending notifications are fetched on shutdown without waiting for the window
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := dispatcher.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("%d notifications fetched, expected 2", calls.Load())
+	}
+	err = dispatcher.Enqueue(context.Background(), []fitbit.Subscription{activities("2024-01-04")})
+	if !errors.Is(err, fitbit.ErrDispatcherClosed) {
+		t.Errorf("expected ErrDispatcherClosed, got %v", err)
+	}
+}
+
+func TestDispatcherWorkers(t *testing.T) {
+	_, session := newTestSession(t, nil)
+	var mutex sync.Mutex
+	active, maxActive, calls := 0, 0, 0
+	dispatcher := newTestDispatcher(t, session)
+	dispatcher.Window = time.Millisecond
+	dispatcher.Workers = 2
+	dispatcher.OnActivities = func(ctx context.Context, notification fitbit.Subscription, summary fitbit.ActivitiesSummaryDay) {
+		mutex.Lock()
+		active++
+		calls++
+		maxActive = max(maxActive, active)
+		mutex.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mutex.Lock()
+		active--
+		mutex.Unlock()
+	}
+
+	var notifications []fitbit.Subscription
+	for day := 1; day <= 6; day++ {
+		notifications = append(notifications, activities(fitbit.NewDate(2024, time.January, day).String()))
+	}
+	if err := dispatcher.Enqueue(context.Background(), notifications); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls != len(notifications) {
+		t.Errorf("%d notifications fetched, expected %d", calls, len(notifications))
+	}
+	if maxActive > 2 {
+		t.Errorf("%d notifications fetched concurrently with 2 workers", maxActive)
+	}
+}
+
+func TestDispatchFetchesDuplicatesOnce(t *testing.T) {
+	_, session := newTestSession(t, nil)
+	calls := 0
+	dispatcher := newTestDispatcher(t, session)
+	dispatcher.OnActivities = func(ctx context.Context, notification fitbit.Subscription, summary fitbit.ActivitiesSummaryDay) {
+		calls++
+	}
+
+	notifications := []fitbit.Subscription{activities("2024-01-01"), activities("2024-01-01")}
+	if err := dispatcher.Dispatch(context.Background(), notifications); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.Dispatch(context.Background(), notifications[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("%d fetches, expected 2", calls)
+	}
+}
@@ -1,28 +1,15 @@
 package fitbit
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
+	"iter"
 	"net/url"
-	"strconv"
 )
 
 // ECG data
 type ECGLogList struct {
-	EcgReadings []struct {
-		StartTime               string `json:"startTime"`
-		AverageHeartRate        int    `json:"averageHeartRate"`
-		ResultClassification    string `json:"resultClassification"`
-		WaveformSamples         []int  `json:"waveformSamples"`
-		SamplingFrequencyHz     int    `json:"samplingFrequencyHz"`
-		ScalingFactor           int    `json:"scalingFactor"`
-		NumberOfWaveformSamples int    `json:"numberOfWaveformSamples"`
-		LeadNumber              int    `json:"leadNumber"`
-		FeatureVersion          string `json:"featureVersion"`
-		DeviceName              string `json:"deviceName"`
-		FirmwareVersion         string `json:"firmwareVersion"`
-	} `json:"ecgReadings"`
-	Pagination struct {
+	EcgReadings []ECGReading `json:"ecgReadings"`
+	Pagination  struct {
 		AfterDate string `json:"afterDate"`
 		Limit     int    `json:"limit"`
 		Next      string `json:"next"`
@@ -32,37 +19,55 @@ type ECGLogList struct {
 	} `json:"pagination"`
 }
 
+// ECGReading contains a single ECG reading of the ECG log list
+type ECGReading struct {
+	StartTime               string `json:"startTime"`
+	AverageHeartRate        int    `json:"averageHeartRate"`
+	ResultClassification    string `json:"resultClassification"`
+	WaveformSamples         []int  `json:"waveformSamples"`
+	SamplingFrequencyHz     int    `json:"samplingFrequencyHz"`
+	ScalingFactor           int    `json:"scalingFactor"`
+	NumberOfWaveformSamples int    `json:"numberOfWaveformSamples"`
+	LeadNumber              int    `json:"leadNumber"`
+	FeatureVersion          string `json:"featureVersion"`
+	DeviceName              string `json:"deviceName"`
+	FirmwareVersion         string `json:"firmwareVersion"`
+}
+
 // ECGLog returns the ECG log list
 func (m *Session) ECGLog(params LogListParameters) (ECGLogList, error) {
-	parameterList := url.Values{}
+	return m.ECGLogContext(context.Background(), params)
+}
 
-	//nolint:gocritic
-	if params.BeforeDate != "" {
-		parameterList.Add("beforeDate", params.BeforeDate)
-		parameterList.Add("sort", "desc")
-	} else if params.AfterDate != "" {
-		parameterList.Add("afterDate", params.BeforeDate)
-		parameterList.Add("sort", "asc")
-	} else {
-		return ECGLogList{}, errors.New("beforeDate or afterDate must be given")
+// ECGLogContext is like ECGLog but uses ctx for the request
+func (m *Session) ECGLogContext(ctx context.Context, params LogListParameters) (ECGLogList, error) {
+	parameterList, err := params.query(10)
+	if err != nil {
+		return ECGLogList{}, err
 	}
 
-	if params.Limit > 0 {
-		if params.Limit > 10 {
-			return ECGLogList{}, errors.New("limit must be 10 or less")
-		}
-		parameterList.Add("limit", strconv.Itoa(params.Limit))
-	}
+	return m.ecgLogPage(ctx, parameterList)
+}
 
-	parameterList.Add("offset", strconv.Itoa(params.Offset))
+// ECGLogIter returns an iterator over all ECG readings starting at the given parameters
+// Following pages are requested on demand until all readings are returned or the iteration is stopped
+func (m *Session) ECGLogIter(ctx context.Context, params LogListParameters) iter.Seq2[ECGReading, error] {
+	parameterList, err := params.query(10)
+	return paginate(ctx, parameterList, err, func(ctx context.Context, query url.Values) ([]ECGReading, string, error) {
+		page, err := m.ecgLogPage(ctx, query)
+		return page.EcgReadings, page.Pagination.Next, err
+	})
+}
 
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/ecg/list.json?" + parameterList.Encode())
+// ecgLogPage requests a single page of the ECG log list
+func (m *Session) ecgLogPage(ctx context.Context, parameterList url.Values) (ECGLogList, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/ecg/list.json?"+parameterList.Encode())
 	if err != nil {
 		return ECGLogList{}, err
 	}
 
 	ecgResponse := ECGLogList{}
-	if err := json.Unmarshal(contents, &ecgResponse); err != nil {
+	if err := m.decode("ECGLog", contents, &ecgResponse); err != nil {
 		return ECGLogList{}, err
 	}
 
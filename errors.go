@@ -1,6 +1,114 @@
 package fitbit
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
 
-var errExpiredToken = errors.New("expired token")
+var errExpiredToken = fmt.Errorf("expired token: %w", ErrInvalidToken)
 var errTokenChangeNotDefined = errors.New("tokenchange function is not defined")
+var errTokenStoreNotDefined = errors.New("token store is not defined")
+var errNoToken = fmt.Errorf("no token set: %w", ErrInvalidToken)
+var errZeroDate = errors.New("date is not set")
+
+// ErrTokenNotFound is returned by a TokenStore if no token is stored
+var ErrTokenNotFound = errors.New("token not found")
+
+// ErrInvalidState is returned by CompleteLogin if the state of the callback is unknown, expired or already used
+var ErrInvalidState = errors.New("invalid login state")
+
+// ErrQueueFull is returned by Dispatcher.Enqueue if notifications were dropped because too many are pending
+var ErrQueueFull = errors.New("dispatcher queue is full")
+
+// ErrDispatcherClosed is returned by Dispatcher.Enqueue after Shutdown was called
+var ErrDispatcherClosed = errors.New("dispatcher is shut down")
+
+// Sentinel errors which can be matched against an *APIError using errors.Is
+var (
+	ErrRateLimited       = errors.New("rate limit exceeded")
+	ErrInvalidToken      = errors.New("invalid or expired token")
+	ErrInsufficientScope = errors.New("insufficient scope")
+	ErrNotFound          = errors.New("resource not found")
+)
+
+// APIErrorEntry contains a single error entry as returned by the Fitbit API
+type APIErrorEntry struct {
+	ErrorType string `json:"errorType"`
+	FieldName string `json:"fieldName,omitempty"`
+	Message   string `json:"message"`
+}
+
+// APIError is returned if the Fitbit API responds with a status code of 400 or above
+// https://dev.fitbit.com/build/reference/web-api/troubleshooting-guide/error-messages/
+type APIError struct {
+	StatusCode int             // StatusCode is the HTTP status code of the response
+	Errors     []APIErrorEntry // Errors contains all entries of the errors array within the response
+	Ratelimit  Ratelimit       // Ratelimit is the rate limit information of the failed response
+	Path       string          // Path is the path of the failed request
+}
+
+// newAPIError builds a new APIError based on the response body
+func newAPIError(statusCode int, path string, body []byte, ratelimit Ratelimit) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Ratelimit:  ratelimit,
+		Path:       path,
+	}
+
+	// body can be empty or non-JSON (e.g. on a 502 of a proxy), in this case only the status is used
+	var response struct {
+		Errors []APIErrorEntry `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil {
+		apiErr.Errors = response.Errors
+	}
+
+	return apiErr
+}
+
+// Error returns a readable representation of the error
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("fitbit: %d %s on %s", e.StatusCode, http.StatusText(e.StatusCode), e.Path)
+	for _, entry := range e.Errors {
+		msg += fmt.Sprintf("; %s", entry.ErrorType)
+		if entry.FieldName != "" {
+			msg += fmt.Sprintf(" (%s)", entry.FieldName)
+		}
+		if entry.Message != "" {
+			msg += ": " + entry.Message
+		}
+	}
+	return msg
+}
+
+// Is allows matching the error against ErrRateLimited, ErrInvalidToken,
+// ErrInsufficientScope and ErrNotFound using errors.Is
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrInvalidToken:
+		return e.StatusCode == http.StatusUnauthorized || e.hasErrorType("invalid_token", "expired_token")
+	case ErrInsufficientScope:
+		return e.hasErrorType("insufficient_scope", "insufficient_permissions") ||
+			(e.StatusCode == http.StatusForbidden && len(e.Errors) == 0)
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.hasErrorType("not_found")
+	}
+	return false
+}
+
+// hasErrorType returns true if one of the error entries is of the given types
+func (e *APIError) hasErrorType(types ...string) bool {
+	for _, entry := range e.Errors {
+		for _, t := range types {
+			if strings.EqualFold(entry.ErrorType, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -7,12 +7,15 @@ import (
 	"time"
 
 	"github.com/Thomas2500/go-fitbit"
-	"github.com/google/uuid"
 )
 
 func handleFitbitLogin(w http.ResponseWriter, r *http.Request) {
-	csrf := uuid.New().String()
-	redirectURL := fca.LoginURL(csrf)
+	// start a new login using PKCE, the random state is validated on callback
+	redirectURL, _, err := fca.NewLogin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
 }
 func httpFitbitGetProfile(w http.ResponseWriter, r *http.Request) {
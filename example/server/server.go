@@ -2,8 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
@@ -12,8 +12,6 @@ import (
 	"time"
 
 	"github.com/Thomas2500/go-fitbit"
-	"github.com/google/uuid"
-	"golang.org/x/oauth2"
 )
 
 // Temporary constant - replace with your own clientID, secret and subscriber code
@@ -44,30 +42,23 @@ func main() {
 			fitbit.ScopeTemperature,
 			fitbit.ScopeWeight,
 		},
+		// Save token changes to file
+		TokenStore: fitbit.NewFileTokenStore("token.json"),
 	})
 
-	// Define fitbit hook function to save token changes to file
-	fca.TokenChange = fitbitSaveToken
-
 	// Print OAuth2 access url to grant permissions to use API requests on behalf of the user
-	csrf := uuid.New().String()
-	fmt.Println(fca.LoginURL(csrf))
-
-	// We already have a token which can be loaded
-	jsonFile, err := ioutil.ReadFile("token.json") // just pass the file name
+	loginURL, _, err := fca.NewLogin()
 	if err != nil {
-		fmt.Print(err)
+		fmt.Println("Error creating login url", err)
 		return
 	}
+	fmt.Println(loginURL)
 
-	// load file contents into token struct to initialize the session using SetTocken
-	token := oauth2.Token{}
-	err = json.Unmarshal(jsonFile, &token)
-	if err != nil {
-		fmt.Println("Error parsing token", err)
+	// We already have a token which can be loaded from the token store
+	if err := fca.LoadToken(); err != nil {
+		fmt.Println("Error loading token", err)
 		return
 	}
-	fca.SetToken(&token)
 
 	// Execute some functions async to test some API functionality - may fail if not authorized using token
 	go func() {
@@ -90,7 +81,7 @@ func main() {
 
 	// Login and callback page
 	http.HandleFunc("/callback", handleFitbitCallback)
-	http.HandleFunc("/subscriber", handleFitbitSubscriber)
+	http.Handle("/subscriber", handleFitbitSubscriber)
 
 	// API pages with data
 	http.HandleFunc("/login", handleFitbitLogin)
@@ -132,24 +123,9 @@ func main() {
 	select {}
 }
 
-// fitbitSaveToken as hook funktion automatically called when the Token is changed
-func fitbitSaveToken(token *oauth2.Token) {
-	jsonFile, err := os.Create("token.json")
-	if err != nil {
-		log.Println("error creating token file", err)
-	}
-	defer jsonFile.Close()
-	err = json.NewEncoder(jsonFile).Encode(token)
-	if err != nil {
-		log.Println("error encoding token", err)
-	}
-	log.Println("FITBIT: token saved to file")
-}
-
 // handleFitbitCallback handles the oAuth2 callback visited by the user after granting permissions
 func handleFitbitCallback(w http.ResponseWriter, r *http.Request) {
 	// check if the request is a callback from fitbit and a code is given
-	// Advice: CSRF (state) token can be checked here if the request is legitimate
 	if r.FormValue("code") == "" {
 		if _, err := w.Write([]byte("No code given!")); err != nil {
 			fmt.Println("can't write to client on fitbit callback", err)
@@ -157,8 +133,13 @@ func handleFitbitCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Exchange the code for an access token
-	token, err := fca.Exchange(r.FormValue("code"))
+	// Validate the state of the login and exchange the code for an access token using the PKCE verifier
+	// the token is saved to file by the token store for recurring use
+	token, err := fca.CompleteLoginContext(r.Context(), r.FormValue("state"), r.FormValue("code"))
+	if errors.Is(err, fitbit.ErrInvalidState) {
+		http.Error(w, "Invalid login state!", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Println("FITBIT: error persisting initial token", err)
 		return
@@ -166,10 +147,7 @@ func handleFitbitCallback(w http.ResponseWriter, r *http.Request) {
 
 	// Prettify token for logging
 	js, err := json.Marshal(token)
-	log.Printf("FITBIT: token: %s - E: %s", string(js), err.Error())
-
-	// save token to file for recurring use
-	fitbitSaveToken(token)
+	log.Printf("FITBIT: token: %s - E: %v", string(js), err)
 	log.Println("FITBIT: token saved")
 
 	// redirect to main page
@@ -1,24 +1,17 @@
 package main
 
 import (
-	"io/ioutil"
+	"context"
 	"log"
-	"net/http"
+
+	"github.com/Thomas2500/go-fitbit"
 )
 
 // https://dev.fitbit.com/build/reference/web-api/subscriptions/index.html
-func handleFitbitSubscriber(w http.ResponseWriter, r *http.Request) {
-	if keys, ok := r.URL.Query()["verify"]; ok && len(keys[0]) > 0 {
-		// drop verify messages not containing out subscriber token
-		if keys[0] != subscriberCode {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-	}
-	w.WriteHeader(http.StatusNoContent)
-
+var handleFitbitSubscriber = fitbit.NewSubscriberHandler(clientSecret, subscriberCode, func(ctx context.Context, notifications []fitbit.Subscription) error {
 	log.Println("subscriber incoming request")
-	log.Println(r.URL.Query())
-	by, err := ioutil.ReadAll(r.Body)
-	log.Println(err, string(by))
-}
+	for _, notification := range notifications {
+		log.Printf("%s changed %s on %s", notification.OwnerID, notification.CollectionType, notification.Date)
+	}
+	return nil
+})
@@ -0,0 +1,440 @@
+package fitbit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// FITOptions changes the conversion of TCX data to FIT
+type FITOptions struct {
+	// Log is the entry of the activity within ActivitiesLogList, the offset of its start time is used for the
+	// local time of the activity. If the TCX data contains a single activity, the calories and average heart rate
+	// of the log are used for the session if the laps don't contain them
+	Log *ActivityLogEntry
+}
+
+// FIT protocol and profile version written to the file header
+const (
+	fitProtocolVersion = 0x20
+	fitProfileVersion  = 2132
+)
+
+// fitEpoch is the start of FIT timestamps, 1989-12-31T00:00:00Z
+const fitEpoch = 631065600
+
+// Base types of FIT fields
+const (
+	fitEnum    = 0x00
+	fitUint8   = 0x02
+	fitUint16  = 0x84
+	fitSint32  = 0x85
+	fitUint32  = 0x86
+	fitUint32z = 0x8C
+)
+
+// Invalid values of FIT base types, used for unknown field values
+const (
+	fitInvalidUint8  = 0xFF
+	fitInvalidUint16 = 0xFFFF
+	fitInvalidSint32 = 0x7FFFFFFF
+	fitInvalidUint32 = 0xFFFFFFFF
+)
+
+// fitField defines a field of a FIT message
+type fitField struct {
+	number   byte
+	size     byte
+	baseType byte
+}
+
+// fitMessage defines a FIT message with the local message type used within the file
+type fitMessage struct {
+	local  byte
+	global uint16
+	fields []fitField
+}
+
+// Messages written to FIT activity files, the order of fields matches the order of values passed to fitWriter.write
+var (
+	fitFileIDMessage = fitMessage{local: 0, global: 0, fields: []fitField{
+		{0, 1, fitEnum},    // type
+		{1, 2, fitUint16},  // manufacturer
+		{2, 2, fitUint16},  // product
+		{3, 4, fitUint32z}, // serial_number
+		{4, 4, fitUint32},  // time_created
+	}}
+	fitRecordMessage = fitMessage{local: 1, global: 20, fields: []fitField{
+		{253, 4, fitUint32}, // timestamp
+		{0, 4, fitSint32},   // position_lat
+		{1, 4, fitSint32},   // position_long
+		{2, 2, fitUint16},   // altitude
+		{3, 1, fitUint8},    // heart_rate
+		{4, 1, fitUint8},    // cadence
+		{5, 4, fitUint32},   // distance
+		{6, 2, fitUint16},   // speed
+	}}
+	fitLapMessage = fitMessage{local: 2, global: 19, fields: []fitField{
+		{254, 2, fitUint16}, // message_index
+		{253, 4, fitUint32}, // timestamp
+		{0, 1, fitEnum},     // event
+		{1, 1, fitEnum},     // event_type
+		{2, 4, fitUint32},   // start_time
+		{3, 4, fitSint32},   // start_position_lat
+		{4, 4, fitSint32},   // start_position_long
+		{7, 4, fitUint32},   // total_elapsed_time
+		{8, 4, fitUint32},   // total_timer_time
+		{9, 4, fitUint32},   // total_distance
+		{11, 2, fitUint16},  // total_calories
+		{13, 2, fitUint16},  // avg_speed
+		{14, 2, fitUint16},  // max_speed
+		{15, 1, fitUint8},   // avg_heart_rate
+		{16, 1, fitUint8},   // max_heart_rate
+		{17, 1, fitUint8},   // avg_cadence
+		{24, 1, fitEnum},    // lap_trigger
+		{25, 1, fitEnum},    // sport
+	}}
+	fitSessionMessage = fitMessage{local: 3, global: 18, fields: []fitField{
+		{254, 2, fitUint16}, // message_index
+		{253, 4, fitUint32}, // timestamp
+		{0, 1, fitEnum},     // event
+		{1, 1, fitEnum},     // event_type
+		{2, 4, fitUint32},   // start_time
+		{3, 4, fitSint32},   // start_position_lat
+		{4, 4, fitSint32},   // start_position_long
+		{5, 1, fitEnum},     // sport
+		{7, 4, fitUint32},   // total_elapsed_time
+		{8, 4, fitUint32},   // total_timer_time
+		{9, 4, fitUint32},   // total_distance
+		{11, 2, fitUint16},  // total_calories
+		{14, 2, fitUint16},  // avg_speed
+		{15, 2, fitUint16},  // max_speed
+		{16, 1, fitUint8},   // avg_heart_rate
+		{17, 1, fitUint8},   // max_heart_rate
+		{25, 2, fitUint16},  // first_lap_index
+		{26, 2, fitUint16},  // num_laps
+		{28, 1, fitEnum},    // trigger
+	}}
+	fitActivityMessage = fitMessage{local: 4, global: 34, fields: []fitField{
+		{253, 4, fitUint32}, // timestamp
+		{0, 4, fitUint32},   // total_timer_time
+		{1, 2, fitUint16},   // num_sessions
+		{2, 1, fitEnum},     // type
+		{3, 1, fitEnum},     // event
+		{4, 1, fitEnum},     // event_type
+		{5, 4, fitUint32},   // local_timestamp
+	}}
+)
+
+// Values of FIT enums
+const (
+	fitFileActivity       = 4
+	fitManufacturerDev    = 255
+	fitEventSession       = 8
+	fitEventLap           = 9
+	fitEventActivity      = 26
+	fitEventTypeStop      = 1
+	fitSessionTriggerEnd  = 0
+	fitActivityTypeManual = 0
+)
+
+// fitLapTriggers maps the TCX trigger method of a lap to the FIT lap trigger, other methods are written as manual
+var fitLapTriggers = map[string]uint64{
+	"Manual":   0,
+	"Time":     1,
+	"Distance": 2,
+	"Location": 4,
+}
+
+// fitSports maps the TCX sport to the FIT sport, other sports are written as generic
+var fitSports = map[string]uint64{
+	"Running": 1,
+	"Biking":  2,
+}
+
+// fitCRCTable is the nibble table of the FIT CRC-16
+var fitCRCTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// fitCRC updates the FIT CRC-16 with the given data
+func fitCRC(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		tmp := fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[b&0xF]
+		tmp = fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[(b>>4)&0xF]
+	}
+	return crc
+}
+
+// fitWriter writes the records of a FIT file, every message is defined before its first use
+type fitWriter struct {
+	buf     bytes.Buffer
+	defined [16]bool
+}
+
+// write writes a data message with the given values, values must match the fields of the message
+func (w *fitWriter) write(message fitMessage, values ...uint64) {
+	if !w.defined[message.local] {
+		w.buf.WriteByte(0x40 | message.local)
+		w.buf.WriteByte(0) // reserved
+		w.buf.WriteByte(0) // little endian
+		w.buf.Write(binary.LittleEndian.AppendUint16(nil, message.global))
+		w.buf.WriteByte(byte(len(message.fields)))
+		for _, field := range message.fields {
+			w.buf.Write([]byte{field.number, field.size, field.baseType})
+		}
+		w.defined[message.local] = true
+	}
+
+	w.buf.WriteByte(message.local)
+	for i, field := range message.fields {
+		for b := byte(0); b < field.size; b++ {
+			w.buf.WriteByte(byte(values[i] >> (8 * b)))
+		}
+	}
+}
+
+// bytes returns the FIT file including header and CRC
+func (w *fitWriter) bytes() []byte {
+	header := make([]byte, 12, 14)
+	header[0] = 14
+	header[1] = fitProtocolVersion
+	binary.LittleEndian.PutUint16(header[2:], fitProfileVersion)
+	binary.LittleEndian.PutUint32(header[4:], uint32(w.buf.Len()))
+	copy(header[8:], ".FIT")
+	header = binary.LittleEndian.AppendUint16(header, fitCRC(0, header))
+
+	file := append(header, w.buf.Bytes()...)
+	return binary.LittleEndian.AppendUint16(file, fitCRC(0, file))
+}
+
+// fitTime returns the FIT timestamp of t
+func fitTime(t time.Time) uint64 {
+	if t.IsZero() || t.Unix() < fitEpoch {
+		return fitInvalidUint32
+	}
+	return uint64(t.Unix() - fitEpoch)
+}
+
+// fitPosition returns the latitude and longitude of the position in semicircles
+func fitPosition(position *TCXPosition) (uint64, uint64) {
+	if position == nil {
+		return fitInvalidSint32, fitInvalidSint32
+	}
+	semicircles := func(degrees float64) uint64 {
+		value := math.Round(degrees * (1 << 31) / 180)
+		if value >= 1<<31 {
+			// 180 degrees east is the same as 180 degrees west
+			value -= 1 << 32
+		}
+		return uint64(uint32(int32(value)))
+	}
+	return semicircles(position.LatitudeDegrees), semicircles(position.LongitudeDegrees)
+}
+
+// fitScaled returns (value + offset) * scale or invalid if value is not set or exceeds the range of the field
+func fitScaled(value *float64, scale float64, offset float64, invalid uint64) uint64 {
+	if value == nil {
+		return invalid
+	}
+	scaled := math.Round((*value + offset) * scale)
+	if scaled < 0 || scaled >= float64(invalid) {
+		return invalid
+	}
+	return uint64(scaled)
+}
+
+// fitInt returns the value or invalid if value is not set or exceeds the range of the field
+func fitInt(value *int, invalid uint64) uint64 {
+	if value == nil || *value < 0 || uint64(*value) >= invalid {
+		return invalid
+	}
+	return uint64(*value)
+}
+
+// fitHeartRate returns the heart rate or invalid if not set
+func fitHeartRate(heartRate *TCXHeartRate) uint64 {
+	if heartRate == nil {
+		return fitInvalidUint8
+	}
+	return fitInt(&heartRate.Value, fitInvalidUint8)
+}
+
+// TCXToFIT encodes parsed TCX data as FIT activity file
+// Every activity is written as session and every lap as lap of the session, track points are written as records.
+// Track points without a position are written without position
+func TCXToFIT(tcx GarminTrainingCenterDatabasev2, options FITOptions) ([]byte, error) {
+	activities := tcxActivities(tcx)
+	if len(activities) == 0 {
+		return nil, errors.New("TCX data contains no activities")
+	}
+
+	w := &fitWriter{}
+	var serial uint64
+	if creator := activities[0].Creator; creator != nil && creator.UnitID != nil {
+		serial = uint64(*creator.UnitID)
+	}
+	w.write(fitFileIDMessage, fitFileActivity, fitManufacturerDev, 0, serial, fitTime(activities[0].ID.Time))
+
+	var end time.Time
+	var timerTime float64
+	lapIndex := 0
+	for i, activity := range activities {
+		var log *ActivityLogEntry
+		if len(activities) == 1 {
+			log = options.Log
+		}
+		session := writeFITSession(w, activity, i, lapIndex, log)
+		lapIndex += len(activity.Lap)
+		timerTime += session.timerTime
+		if session.end.After(end) {
+			end = session.end
+		}
+	}
+
+	localTime := fitTime(end)
+	if options.Log != nil && localTime != fitInvalidUint32 {
+		_, offset := options.Log.StartTime.Zone()
+		localTime = uint64(int64(localTime) + int64(offset))
+	}
+	w.write(fitActivityMessage, fitTime(end), fitScaled(&timerTime, 1000, 0, fitInvalidUint32), uint64(len(activities)),
+		fitActivityTypeManual, fitEventActivity, fitEventTypeStop, localTime)
+
+	return w.bytes(), nil
+}
+
+// fitSessionSummary contains the values of a written session required for the activity message
+type fitSessionSummary struct {
+	end       time.Time
+	timerTime float64
+}
+
+// writeFITSession writes the records and laps of the activity followed by the session message
+// Totals of the session are summed up from the laps, averages are weighted by the duration of the laps
+func writeFITSession(w *fitWriter, activity TCXActivity, index int, firstLap int, log *ActivityLogEntry) fitSessionSummary {
+	sport := fitSports[activity.Sport]
+
+	start := activity.ID.Time
+	end := start
+	var startPosition *TCXPosition
+	var timerTime, distance, maxSpeed, heartRateTime, heartRateSum float64
+	var calories, maxHeartRate int
+
+	for i, lap := range activity.Lap {
+		var lapPosition *TCXPosition
+		lapStart := lap.StartTime.Time
+		lapEnd := lapStart.Add(time.Duration(lap.TotalTimeSeconds * float64(time.Second)))
+		for _, t := range lap.Track {
+			for _, point := range t.Trackpoint {
+				writeFITRecord(w, point)
+				if lapPosition == nil {
+					lapPosition = point.Position
+				}
+				if point.Time.After(lapEnd) {
+					lapEnd = point.Time.Time
+				}
+			}
+		}
+		if startPosition == nil {
+			startPosition = lapPosition
+		}
+		if start.IsZero() || (!lapStart.IsZero() && lapStart.Before(start)) {
+			start = lapStart
+		}
+		if lapEnd.After(end) {
+			end = lapEnd
+		}
+
+		avgSpeed := uint64(fitInvalidUint16)
+		if lap.Extensions != nil && lap.Extensions.LX != nil && lap.Extensions.LX.AvgSpeed != nil {
+			avgSpeed = fitScaled(lap.Extensions.LX.AvgSpeed, 1000, 0, fitInvalidUint16)
+		} else if lap.TotalTimeSeconds > 0 {
+			speed := lap.DistanceMeters / lap.TotalTimeSeconds
+			avgSpeed = fitScaled(&speed, 1000, 0, fitInvalidUint16)
+		}
+		cadence := lap.Cadence
+		if cadence == nil && lap.Extensions != nil && lap.Extensions.LX != nil {
+			cadence = lap.Extensions.LX.AvgRunCadence
+		}
+		trigger := fitLapTriggers[lap.TriggerMethod]
+		lapStartLat, lapStartLong := fitPosition(lapPosition)
+		elapsed := lapEnd.Sub(lapStart).Seconds()
+		w.write(fitLapMessage, uint64(firstLap+i), fitTime(lapEnd), fitEventLap, fitEventTypeStop, fitTime(lapStart),
+			lapStartLat, lapStartLong, fitScaled(&elapsed, 1000, 0, fitInvalidUint32),
+			fitScaled(&lap.TotalTimeSeconds, 1000, 0, fitInvalidUint32), fitScaled(&lap.DistanceMeters, 100, 0, fitInvalidUint32),
+			fitInt(&lap.Calories, fitInvalidUint16), avgSpeed, fitScaled(lap.MaximumSpeed, 1000, 0, fitInvalidUint16),
+			fitHeartRate(lap.AverageHeartRateBpm), fitHeartRate(lap.MaximumHeartRateBpm), fitInt(cadence, fitInvalidUint8),
+			trigger, sport)
+
+		timerTime += lap.TotalTimeSeconds
+		distance += lap.DistanceMeters
+		calories += lap.Calories
+		if lap.MaximumSpeed != nil {
+			maxSpeed = max(maxSpeed, *lap.MaximumSpeed)
+		}
+		if lap.AverageHeartRateBpm != nil {
+			heartRateTime += lap.TotalTimeSeconds
+			heartRateSum += float64(lap.AverageHeartRateBpm.Value) * lap.TotalTimeSeconds
+		}
+		if lap.MaximumHeartRateBpm != nil {
+			maxHeartRate = max(maxHeartRate, lap.MaximumHeartRateBpm.Value)
+		}
+	}
+
+	avgHeartRate := uint64(fitInvalidUint8)
+	if heartRateTime > 0 {
+		heartRate := heartRateSum / heartRateTime
+		avgHeartRate = fitScaled(&heartRate, 1, 0, fitInvalidUint8)
+	} else if log != nil && log.AverageHeartRate > 0 {
+		avgHeartRate = fitInt(&log.AverageHeartRate, fitInvalidUint8)
+	}
+	if calories == 0 && log != nil {
+		calories = log.Calories
+	}
+	avgSpeed := uint64(fitInvalidUint16)
+	if timerTime > 0 {
+		speed := distance / timerTime
+		avgSpeed = fitScaled(&speed, 1000, 0, fitInvalidUint16)
+	}
+	maxSpeedValue := uint64(fitInvalidUint16)
+	if maxSpeed > 0 {
+		maxSpeedValue = fitScaled(&maxSpeed, 1000, 0, fitInvalidUint16)
+	}
+	maxHeartRateValue := uint64(fitInvalidUint8)
+	if maxHeartRate > 0 {
+		maxHeartRateValue = fitInt(&maxHeartRate, fitInvalidUint8)
+	}
+
+	startLat, startLong := fitPosition(startPosition)
+	elapsed := end.Sub(start).Seconds()
+	w.write(fitSessionMessage, uint64(index), fitTime(end), fitEventSession, fitEventTypeStop, fitTime(start),
+		startLat, startLong, sport, fitScaled(&elapsed, 1000, 0, fitInvalidUint32),
+		fitScaled(&timerTime, 1000, 0, fitInvalidUint32), fitScaled(&distance, 100, 0, fitInvalidUint32),
+		fitInt(&calories, fitInvalidUint16), avgSpeed, maxSpeedValue, avgHeartRate, maxHeartRateValue,
+		uint64(firstLap), uint64(len(activity.Lap)), fitSessionTriggerEnd)
+
+	return fitSessionSummary{end: end, timerTime: timerTime}
+}
+
+// writeFITRecord writes a record message of the track point
+func writeFITRecord(w *fitWriter, point TCXTrackpoint) {
+	cadence := point.Cadence
+	var speed *float64
+	if point.Extensions != nil && point.Extensions.TPX != nil {
+		speed = point.Extensions.TPX.Speed
+		if cadence == nil {
+			cadence = point.Extensions.TPX.RunCadence
+		}
+	}
+	lat, long := fitPosition(point.Position)
+	w.write(fitRecordMessage, fitTime(point.Time.Time), lat, long, fitScaled(point.AltitudeMeters, 5, 500, fitInvalidUint16),
+		fitHeartRate(point.HeartRateBpm), fitInt(cadence, fitInvalidUint8),
+		fitScaled(point.DistanceMeters, 100, 0, fitInvalidUint32), fitScaled(speed, 1000, 0, fitInvalidUint16))
+}
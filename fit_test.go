@@ -0,0 +1,209 @@
+package fitbit_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+// fitRecord is a decoded FIT data message with its raw field values by field number
+type fitRecord struct {
+	global uint16
+	fields map[byte]uint64
+}
+
+// fitCRC16 calculates the CRC-16 used by FIT files bit by bit to verify the table based encoder
+func fitCRC16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// decodeFIT decodes a FIT file using little endian definitions without developer fields
+func decodeFIT(t *testing.T, data []byte) []fitRecord {
+	t.Helper()
+	if len(data) < 16 || data[0] != 14 || string(data[8:12]) != ".FIT" {
+		t.Fatalf("invalid FIT header % x", data[:min(len(data), 14)])
+	}
+	if crc := binary.LittleEndian.Uint16(data[12:]); crc != fitCRC16(data[:12]) {
+		t.Fatalf("header CRC %04x, expected %04x", crc, fitCRC16(data[:12]))
+	}
+	size := int(binary.LittleEndian.Uint32(data[4:]))
+	if len(data) != 14+size+2 {
+		t.Fatalf("file has %d bytes, header announces %d bytes of data", len(data), size)
+	}
+	if crc := binary.LittleEndian.Uint16(data[14+size:]); crc != fitCRC16(data[:14+size]) {
+		t.Fatalf("file CRC %04x, expected %04x", crc, fitCRC16(data[:14+size]))
+	}
+
+	type definition struct {
+		global uint16
+		fields [][2]byte
+	}
+	definitions := map[byte]definition{}
+	var records []fitRecord
+	r := bytes.NewReader(data[14 : 14+size])
+	for r.Len() > 0 {
+		header, _ := r.ReadByte()
+		local := header & 0x0F
+		if header&0x40 != 0 {
+			var fixed [5]byte
+			if _, err := r.Read(fixed[:]); err != nil || fixed[1] != 0 {
+				t.Fatalf("invalid definition of local message %d", local)
+			}
+			d := definition{global: binary.LittleEndian.Uint16(fixed[2:])}
+			for i := 0; i < int(fixed[4]); i++ {
+				var field [3]byte
+				if _, err := r.Read(field[:]); err != nil {
+					t.Fatal(err)
+				}
+				d.fields = append(d.fields, [2]byte{field[0], field[1]})
+			}
+			definitions[local] = d
+			continue
+		}
+
+		d, ok := definitions[local]
+		if !ok {
+			t.Fatalf("data message of undefined local message %d", local)
+		}
+		record := fitRecord{global: d.global, fields: map[byte]uint64{}}
+		for _, field := range d.fields {
+			var value uint64
+			for b := 0; b < int(field[1]); b++ {
+				v, err := r.ReadByte()
+				if err != nil {
+					t.Fatal(err)
+				}
+				value |= uint64(v) << (8 * b)
+			}
+			record.fields[field[0]] = value
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// fitDegrees converts semicircles to degrees
+func fitDegrees(semicircles uint64) float64 {
+	return float64(int32(uint32(semicircles))) * 180 / (1 << 31)
+}
+
+func TestTCXToFIT(t *testing.T) {
+	start := time.Date(2024, 1, 1, 8, 30, 0, 0, time.FixedZone("CET", 3600))
+	data, err := fitbit.TCXToFIT(readTCXFixture(t), fitbit.FITOptions{
+		Log: &fitbit.ActivityLogEntry{StartTime: start, Calories: 99},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := decodeFIT(t, data)
+	var globals []uint16
+	for _, record := range records {
+		globals = append(globals, record.global)
+	}
+	// file_id, 3 records, lap, session, activity
+	expected := []uint16{0, 20, 20, 20, 19, 18, 34}
+	if len(globals) != len(expected) {
+		t.Fatalf("messages %v, expected %v", globals, expected)
+	}
+	for i := range expected {
+		if globals[i] != expected[i] {
+			t.Fatalf("messages %v, expected %v", globals, expected)
+		}
+	}
+
+	// FIT timestamps count the seconds since 1989-12-31T00:00:00Z
+	startTime := uint64(start.Unix() - time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC).Unix())
+	fileID, first, second, lap, session, activity := records[0], records[1], records[2], records[4], records[5], records[6]
+	if fileID.fields[0] != 4 || fileID.fields[3] != 3412345678 || fileID.fields[4] != startTime {
+		t.Errorf("unexpected file_id %v", fileID.fields)
+	}
+
+	if first.fields[253] != startTime || first.fields[3] != 131 || first.fields[4] != 84 || first.fields[6] != 3200 {
+		t.Errorf("unexpected first record %v", first.fields)
+	}
+	if math.Abs(fitDegrees(first.fields[0])-48.2081743) > 1e-6 || math.Abs(fitDegrees(first.fields[1])-16.3738189) > 1e-6 {
+		t.Errorf("position %f %f", fitDegrees(first.fields[0]), fitDegrees(first.fields[1]))
+	}
+	if altitude := float64(first.fields[2])/5 - 500; math.Abs(altitude-171.2) > 0.2 {
+		t.Errorf("altitude %f", altitude)
+	}
+	// the position of points without position is written as invalid instead of being invented
+	if second.fields[0] != 0x7FFFFFFF || second.fields[1] != 0x7FFFFFFF || second.fields[5] != 20130 || second.fields[4] != 0xFF {
+		t.Errorf("unexpected second record %v", second.fields)
+	}
+
+	if lap.fields[0] != 9 || lap.fields[2] != startTime || lap.fields[253] != startTime+120 || lap.fields[8] != 120000 ||
+		lap.fields[9] != 40250 || lap.fields[11] != 31 || lap.fields[13] != 3350 || lap.fields[14] != 3600 ||
+		lap.fields[15] != 142 || lap.fields[16] != 151 || lap.fields[17] != 85 || lap.fields[25] != 1 {
+		t.Errorf("unexpected lap %v", lap.fields)
+	}
+	// calories of the laps are preferred over the log
+	if session.fields[0] != 8 || session.fields[5] != 1 || session.fields[8] != 120000 || session.fields[9] != 40250 ||
+		session.fields[11] != 31 || session.fields[16] != 142 || session.fields[17] != 151 || session.fields[26] != 1 {
+		t.Errorf("unexpected session %v", session.fields)
+	}
+	if activity.fields[1] != 1 || activity.fields[3] != 26 || activity.fields[253] != startTime+120 ||
+		activity.fields[5] != startTime+120+3600 {
+		t.Errorf("unexpected activity %v", activity.fields)
+	}
+}
+
+func TestTCXToFITMultipleActivities(t *testing.T) {
+	tcx := readTCXFixture(t)
+	second := tcx.Activities.Activity[0]
+	second.Sport = "Other"
+	second.ID.Time = second.ID.Add(time.Hour)
+	second.Lap = append([]fitbit.TCXLap(nil), second.Lap...)
+	second.Lap[0].StartTime = second.ID
+	second.Lap[0].Track = nil
+	tcx.Activities.Activity = append(tcx.Activities.Activity, second)
+
+	data, err := fitbit.TCXToFIT(tcx, fitbit.FITOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var laps, sessions []fitRecord
+	var activity fitRecord
+	for _, record := range decodeFIT(t, data) {
+		switch record.global {
+		case 19:
+			laps = append(laps, record)
+		case 18:
+			sessions = append(sessions, record)
+		case 34:
+			activity = record
+		}
+	}
+	if len(laps) != 2 || laps[1].fields[254] != 1 || laps[1].fields[25] != 0 {
+		t.Errorf("unexpected laps %v", laps)
+	}
+	if len(sessions) != 2 || sessions[1].fields[254] != 1 || sessions[1].fields[25] != 1 || sessions[1].fields[5] != 0 {
+		t.Errorf("unexpected sessions %v", sessions)
+	}
+	// without log the local time equals UTC
+	if activity.fields[1] != 2 || activity.fields[0] != 240000 || activity.fields[5] != activity.fields[253] {
+		t.Errorf("unexpected activity %v", activity.fields)
+	}
+}
+
+func TestTCXToFITWithoutActivities(t *testing.T) {
+	if _, err := fitbit.TCXToFIT(fitbit.GarminTrainingCenterDatabasev2{}, fitbit.FITOptions{}); err == nil {
+		t.Error("expected an error for TCX data without activities")
+	}
+}
@@ -2,7 +2,6 @@ package fitbit
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -11,15 +10,17 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/oauth2"
 )
 
-// Define used Fitbit endpoints
+// Define default Fitbit endpoints, can be overwritten using Config
 const (
-	fitbitAuthURL  = "https://www.fitbit.com/oauth2/authorize" //nolint:gosec
-	fitbitTokenURL = "https://api.fitbit.com/oauth2/token"     //nolint:gosec
+	DefaultAPIURL   = "https://api.fitbit.com"
+	DefaultAuthURL  = "https://www.fitbit.com/oauth2/authorize" //nolint:gosec
+	DefaultTokenURL = "https://api.fitbit.com/oauth2/token"     //nolint:gosec
 )
 
 // Scope describes an oauth2 scope for Fitbit
@@ -46,6 +47,7 @@ const (
 // Session is the main object with user data
 type Session struct {
 	// HookTokenChange is a function that is called when the refresh_token changes
+	// It is used as TokenStore if no TokenStore is configured
 	TokenChange func(token *oauth2.Token)
 
 	ratelimit Ratelimit
@@ -62,10 +64,36 @@ type Session struct {
 	// httpClient is the authenticated http client used for this oAuth session
 	httpClient *http.Client
 
+	// transport is the transport including all middlewares used by httpClient and token requests
+	transport http.RoundTripper
+
 	// locale is the locale used for this session
 	locale string
 
+	// apiURL is the base url used for all API requests without trailing slash
+	apiURL string
+
+	// retryPolicy defines how failed requests are retried
+	retryPolicy RetryPolicy
+
+	// rateLimiter paces requests based on the rate limit, nil if not used
+	rateLimiter *RateLimiter
+
 	mutex sync.RWMutex
+
+	// storeMutex serializes token rotations to persist them in order
+	storeMutex sync.Mutex
+
+	// pendingLogins contains started logins by state
+	pendingLogins map[string]pendingLogin
+
+	// location is the timezone of the user, nil if not known yet
+	location *time.Location
+
+	// inFlight is the number of running requests and lastUsed the time of the last request in unix nanoseconds,
+	// a Manager does not evict sessions which are in use
+	inFlight atomic.Int32
+	lastUsed atomic.Int64
 }
 
 // Config describes the configuration of a fitbit API configuration
@@ -75,6 +103,20 @@ type Config struct {
 	RedirectURL  string  // RedirectURL is the redirect url of the application (required)
 	Scopes       []Scope // Scopes is a list of scopes to request
 	Locale       string  // en_AU, fr_FR, de_DE, ja_JP, en_NZ, es_ES, en_GB, en_US (default: de_DE)
+	APIURL       string  // APIURL is the base url of the API without version, e.g. http://127.0.0.1:8080 (default: DefaultAPIURL)
+	AuthURL      string  // AuthURL is the OAuth 2.0 authorization url (default: DefaultAuthURL)
+	TokenURL     string  // TokenURL is the OAuth 2.0 token url (default: DefaultTokenURL)
+
+	Transport   http.RoundTripper // Transport is the base transport used for all requests, e.g. for proxies or custom TLS (default: http.DefaultTransport)
+	Middlewares []Middleware      // Middlewares are wrapped around Transport in the given order, the first one sees a request first
+	Timeout     time.Duration     // Timeout is the timeout of a single request (default: no timeout)
+
+	ChunkConcurrency int // ChunkConcurrency is the number of concurrent requests of date ranges split into multiple requests (default: 1)
+
+	TokenStore TokenStore // TokenStore persists rotated tokens (default: TokenChange of the session)
+
+	StrictDecoding bool                                   // StrictDecoding fails responses containing fields unknown to the response struct with *UnknownFieldsError
+	UnknownFields  func(endpoint string, fields []string) // UnknownFields is called with the endpoint and the paths of all unknown fields of a response, it may be called concurrently
 }
 
 // Ratelimit includes the rate limit information provided on every request
@@ -86,6 +128,17 @@ type Ratelimit struct {
 
 // New creates a new fitbit oauth session
 func New(config Config) *Session {
+	// use default endpoints if not overwritten by config
+	if config.APIURL == "" {
+		config.APIURL = DefaultAPIURL
+	}
+	if config.AuthURL == "" {
+		config.AuthURL = DefaultAuthURL
+	}
+	if config.TokenURL == "" {
+		config.TokenURL = DefaultTokenURL
+	}
+
 	// Create new oauth configuation
 	oAuthConfig := &oauth2.Config{
 		ClientID:     config.ClientID,
@@ -93,11 +146,16 @@ func New(config Config) *Session {
 		RedirectURL:  config.RedirectURL,
 		Scopes:       config.Scopes,
 		Endpoint: oauth2.Endpoint{
-			AuthURL:  fitbitAuthURL,
-			TokenURL: fitbitTokenURL,
+			AuthURL:  config.AuthURL,
+			TokenURL: config.TokenURL,
 		},
 	}
 
+	return newSession(config, oAuthConfig, buildTransport(config))
+}
+
+// newSession creates a session using the given OAuth config and transport, which can be shared by multiple sessions
+func newSession(config Config, oAuthConfig *oauth2.Config, transport http.RoundTripper) *Session {
 	// determine locale, if not used set to de_DE (this was the previous default)
 	// list of locales: https://dev.fitbit.com/build/reference/web-api/developer-guide/application-design/#Localization
 	locale := config.Locale
@@ -112,6 +170,8 @@ func New(config Config) *Session {
 		config:      config,
 		oAuthConfig: oAuthConfig,
 		locale:      locale,
+		apiURL:      strings.TrimRight(config.APIURL, "/"),
+		transport:   transport,
 	}
 }
 
@@ -120,59 +180,61 @@ func (m *Session) LoginURL(csrf string) string {
 	return m.oAuthConfig.AuthCodeURL(csrf, oauth2.AccessTypeOffline)
 }
 
-// cacherTransport is a transport which intercepts RoundTrip to check if the token changed on HTTP requests
-type cacherTransport struct {
-	Base    *oauth2.Transport
-	Session *Session
+// storingTokenSource is a token source which persists rotated tokens as soon as they are obtained
+// Tokens are persisted before the request is sent, a failing request can't lose a rotated refresh token
+type storingTokenSource struct {
+	Base        oauth2.TokenSource
+	Session     *Session
+	Refreshable bool // Refreshable is set if the token of the base source contains a refresh token
 }
 
-// RoundTrip overrides the http.Client RoundTrip method to check if the token changed
-func (c *cacherTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
-	// get the current used token and determine if it is already expired
-	if _, err := c.Base.Source.Token(); err != nil {
-		return nil, errExpiredToken
-	}
-	// authorize based on oauth2.Transport.RoundTrip
-	resp, err = c.Base.RoundTrip(req)
+// Token returns the token of the base source and persists it using the session if it changed
+func (s *storingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.Base.Token()
 	if err != nil {
+		if s.isExpired(err) {
+			// the token is invalid and can't be refreshed, probably a new authentication is required
+			return nil, fmt.Errorf("%w: %w", errExpiredToken, err)
+		}
+		// network and server errors of the token endpoint may succeed on a later attempt
 		return nil, err
 	}
-	// get the current used token to compare it with the previous one
-	newTok, err := c.Base.Source.Token()
-	if err != nil {
-		// a error appeared which means the token is invalid
-		// return the response, the user has to decide how to proceed here
-		// probably a new authentication is required which is a manual task for the user
-		return resp, nil
+
+	// if the token changed update the token of the session and persist it using the token store
+	if err := s.Session.storeToken(token); err != nil {
+		// the rotated refresh token is lost if it can't be persisted, so the caller must know about it
+		return nil, fmt.Errorf("saving rotated token: %w", err)
 	}
+	return token, nil
+}
 
-	// check if the token changed from old to new one
-	// if it changed update token copy and trigger TokenChange user function to allow persisting the token
-	if c.Session.token == nil ||
-		c.Session.token.AccessToken != newTok.AccessToken ||
-		c.Session.token.RefreshToken != newTok.RefreshToken {
-		// Save new token if it differs from the old one
-		c.Session.mutex.Lock()
-		c.Session.token = newTok
-		c.Session.mutex.Unlock()
-
-		// Call token change hook if defined by user
-		if c.Session.TokenChange != nil {
-			go c.Session.TokenChange(newTok)
+// isExpired returns true if err means that the token was rejected by the token endpoint or can't be refreshed at all
+func (s *storingTokenSource) isExpired(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		if retrieveErr.ErrorCode == "invalid_grant" {
+			return true
 		}
+		return retrieveErr.Response != nil &&
+			(retrieveErr.Response.StatusCode == http.StatusBadRequest || retrieveErr.Response.StatusCode == http.StatusUnauthorized)
 	}
-	return resp, nil
+	// the base source fails without sending a request if the token has expired and there is no refresh token
+	return !s.Refreshable
 }
 
-// Like oauth2.Config.Client(), but using cacherTransport to persist tokens.
+// Like oauth2.Config.Client(), but using storingTokenSource to persist tokens.
+// The caller must hold m.mutex
 func (m *Session) newHTTPClient() *http.Client {
 	return &http.Client{
-		Transport: &cacherTransport{
-			Session: m,
-			Base: &oauth2.Transport{
-				Source: m.oAuthConfig.TokenSource(context.Background(), m.token),
+		Transport: &oauth2.Transport{
+			Source: &storingTokenSource{
+				Base:        m.oAuthConfig.TokenSource(m.oAuthContext(context.Background()), m.token),
+				Session:     m,
+				Refreshable: m.token != nil && m.token.RefreshToken != "",
 			},
+			Base: m.transport,
 		},
+		Timeout: m.config.Timeout,
 	}
 }
 
@@ -184,130 +246,112 @@ func (m *Session) SetToken(token *oauth2.Token) {
 	m.mutex.Unlock()
 }
 
-// SaveToken triggers the TokenChange function to manually save the token
+// SaveToken saves the current token using the token store or the TokenChange function
 func (m *Session) SaveToken() error {
-	if m.TokenChange == nil {
+	store := m.tokenStore()
+	if store == nil {
 		return errTokenChangeNotDefined
 	}
-	m.TokenChange(m.token)
-	return nil
+	return store.Save(m.Token())
+}
+
+// Token returns the token currently used by the session
+func (m *Session) Token() *oauth2.Token {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.token
 }
 
 // GetRatelimit returns the current ratelimit information obtained by the last API request
 func (m *Session) GetRatelimit() Ratelimit {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	return m.ratelimit
 }
 
-type contentError struct {
-	Error struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-		Status  string `json:"status"`
-	} `json:"error"`
-}
-
-// makeRequest creates a new request to a given url using given
-// OAuth token of an user
-func (m *Session) makeRequest(url string) ([]byte, error) {
-	// if httpClient is nil build a new one
+// client returns the http client of the session and builds a new one if not available
+func (m *Session) client() *http.Client {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if m.httpClient == nil {
 		m.httpClient = m.newHTTPClient()
 	}
+	return m.httpClient
+}
 
-	// Build request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set custom header
-	req.Header.Set("User-Agent", "go-fitbit")
-	req.Header.Set("Accept-Language", m.locale)
-	req.Header.Set("Accept-Locale", m.locale)
-
-	// Fire request
-	response, err := m.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer response.Body.Close()
-
-	// Parse rate limit headers
-	m.parseRatelimit(&response.Header)
-
-	// Read all data from request
-	contents, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	// Check for error in content
-	// This will catch errors such as request quota exceeded
-	var cError contentError
-	if err = json.Unmarshal(contents, &cError); err == nil && cError.Error.Code != 0 {
-		return contents, errors.New(fmt.Sprintf("Error: %d, %s, %s", cError.Error.Code, cError.Error.Message, cError.Error.Status))
-	}
-
-	return contents, nil
+// makeRequest creates a new request to a given url using given
+// OAuth token of an user
+func (m *Session) makeRequest(ctx context.Context, url string) ([]byte, error) {
+	return m.doRequest(ctx, "GET", url, nil, nil)
 }
 
 // makePOSTRequest creates a new request to a given url using given
 // OAuth token of an user
-func (m *Session) makePOSTRequest(targetURL string, param map[string]string) ([]byte, error) {
-	// if httpClient is nil build a new one
-	if m.httpClient == nil {
-		m.httpClient = m.newHTTPClient()
-	}
-
+func (m *Session) makePOSTRequest(ctx context.Context, targetURL string, param map[string]string) ([]byte, error) {
 	// Build post params
 	form := url.Values{}
 	for name, value := range param {
 		form.Add(name, value)
 	}
 
-	// Build request
-	req, err := http.NewRequest("POST", targetURL, strings.NewReader(form.Encode()))
-	if err != nil {
-		return nil, err
-	}
-
-	// Set custom header
-	req.Header.Set("User-Agent", "go-fitbit")
-	req.Header.Set("Accept-Language", m.locale)
-	req.Header.Set("Accept-Locale", m.locale)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	// Fire request
-	response, err := m.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer response.Body.Close()
-
-	// Parse rate limit headers
-	m.parseRatelimit(&response.Header)
-
-	// Read all data from request
-	contents, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	return contents, nil
+	return m.doRequest(ctx, "POST", targetURL, form, nil)
 }
 
 // makeDELETERequest creates a new request to a given url using given
 // OAuth token of an user
 //
 //nolint:unparam
-func (m *Session) makeDELETERequest(url string) ([]byte, error) {
-	// if httpClient is nil build a new one
-	if m.httpClient == nil {
-		m.httpClient = m.newHTTPClient()
+func (m *Session) makeDELETERequest(ctx context.Context, url string) ([]byte, error) {
+	return m.doRequest(ctx, "DELETE", url, nil, nil)
+}
+
+// doRequest sends a request with the given method to the given url using the
+// OAuth token of an user. If form is not nil it is sent url encoded as body,
+// additional headers are added to the request.
+// Responses with a status code of 400 or above are returned as *APIError.
+// Failed requests are retried based on the retry policy of the session
+func (m *Session) doRequest(ctx context.Context, method string, targetURL string, form url.Values, header http.Header) ([]byte, error) {
+	m.inFlight.Add(1)
+	defer func() {
+		m.lastUsed.Store(time.Now().UnixNano())
+		m.inFlight.Add(-1)
+	}()
+
+	m.mutex.RLock()
+	policy := m.retryPolicy
+	m.mutex.RUnlock()
+	for attempt := 1; ; attempt++ {
+		limiter, err := m.reserveRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		contents, err := m.doSingleRequest(ctx, method, targetURL, form, header)
+		if limiter != nil {
+			limiter.release()
+		}
+		delay, retry := policy.retryDelay(method, attempt, err)
+		if !retry {
+			return contents, err
+		}
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return contents, err
+		}
+	}
+}
+
+// doSingleRequest sends a single request without retries
+func (m *Session) doSingleRequest(ctx context.Context, method string, targetURL string, form url.Values, header http.Header) ([]byte, error) {
+	// a session without token can't authorize requests, e.g. after Logout
+	if m.Token() == nil {
+		return nil, errNoToken
 	}
 
 	// Build request
-	req, err := http.NewRequest("DELETE", url, nil)
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -316,16 +360,24 @@ func (m *Session) makeDELETERequest(url string) ([]byte, error) {
 	req.Header.Set("User-Agent", "go-fitbit")
 	req.Header.Set("Accept-Language", m.locale)
 	req.Header.Set("Accept-Locale", m.locale)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
 
 	// Fire request
-	response, err := m.httpClient.Do(req)
+	response, err := m.client().Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer response.Body.Close()
 
 	// Parse rate limit headers
-	m.parseRatelimit(&response.Header)
+	ratelimit := m.parseRatelimit(&response.Header)
 
 	// Read all data from request
 	contents, err := io.ReadAll(response.Body)
@@ -333,11 +385,28 @@ func (m *Session) makeDELETERequest(url string) ([]byte, error) {
 		return nil, err
 	}
 
+	// Provide the unmodified response if requested using WithRawResponse
+	recordRawResponse(ctx, RawResponse{
+		Method:     method,
+		URL:        req.URL.String(),
+		StatusCode: response.StatusCode,
+		Header:     response.Header.Clone(),
+		Body:       contents,
+		Ratelimit:  ratelimit,
+	})
+
+	// Check for error responses
+	// This will catch errors such as request quota exceeded
+	if response.StatusCode >= http.StatusBadRequest {
+		return contents, newAPIError(response.StatusCode, req.URL.Path, contents, ratelimit)
+	}
+
 	return contents, nil
 }
 
-// parseRatelimit parses the rate limit headers of fitbit API
-func (m *Session) parseRatelimit(header *http.Header) {
+// parseRatelimit parses the rate limit headers of fitbit API and returns the updated rate limit information
+func (m *Session) parseRatelimit(header *http.Header) Ratelimit {
+	m.mutex.Lock()
 	// Get rate limit data of request
 	// fist header returns the remaining API requests until reset time is reached
 	rateLimitData := header.Get("fitbit-rate-limit-remaining")
@@ -358,15 +427,41 @@ func (m *Session) parseRatelimit(header *http.Header) {
 		remSec, _ := strconv.Atoi(rateLimitData)
 		m.ratelimit.RateLimitReset = time.Now().Add(time.Second * time.Duration(remSec))
 	}
+	ratelimit := m.ratelimit
+	limiter := m.rateLimiter
+	m.mutex.Unlock()
+
+	// refresh the rate limiter with the new information of this response
+	if limiter != nil && rateLimitData != "" {
+		limiter.Update(ratelimit)
+	}
+	return ratelimit
 }
 
 // Exchange uses an authorization code to retrieve an access token and refresh token.
-// sets them in the current session using SetToken and rebuilds the httpClient
+// sets them in the current session using SetToken, rebuilds the httpClient and saves the token using the token store
 func (m *Session) Exchange(code string) (*oauth2.Token, error) {
-	token, err := m.oAuthConfig.Exchange(context.Background(), code)
+	return m.ExchangeContext(context.Background(), code)
+}
+
+// ExchangeContext is like Exchange but uses ctx for the token request
+func (m *Session) ExchangeContext(ctx context.Context, code string) (*oauth2.Token, error) {
+	return m.exchange(ctx, code)
+}
+
+// exchange retrieves a token using the authorization code, sets and persists it
+func (m *Session) exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	token, err := m.oAuthConfig.Exchange(m.oAuthContext(ctx), code, opts...)
 	if err != nil {
 		return nil, err
 	}
 	m.SetToken(token)
+
+	// persist the initial token
+	if store := m.tokenStore(); store != nil {
+		if err := store.Save(token); err != nil {
+			return token, fmt.Errorf("saving token: %w", err)
+		}
+	}
 	return token, nil
 }
@@ -0,0 +1,394 @@
+package fitbittest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ErrUnmatchedRequest is returned by a Recorder in replay mode if no recorded interaction matches a request
+var ErrUnmatchedRequest = errors.New("fitbittest: no recorded interaction matches the request")
+
+// redacted replaces sanitized values within cassettes
+const redacted = "REDACTED"
+
+// Mode defines if a Recorder replays or records interactions
+type Mode int
+
+const (
+	// ModeReplay replays recorded interactions and fails on requests without a recorded interaction
+	ModeReplay Mode = iota
+	// ModeRecord replays recorded interactions and records requests without a recorded interaction
+	ModeRecord
+	// ModeRewrite sends all requests and replaces the cassette with the new interactions
+	ModeRewrite
+)
+
+// SanitizedKeys are the JSON keys and form fields whose values are replaced within cassettes
+var SanitizedKeys = []string{
+	"access_token", "refresh_token", "id_token", "code", "code_verifier", "client_secret", "token", "user_id",
+	"email", "fullName", "displayName", "firstName", "lastName", "encodedId", "ownerId", "avatar", "avatar150", "avatar640",
+	"dateOfBirth",
+}
+
+// SanitizedChildKeys are JSON keys whose values are replaced only within objects of the given parent key
+// The friends endpoints return users as resource objects with their id and the name within attributes
+// Objects within arrays belong to the key of the array
+var SanitizedChildKeys = map[string][]string{
+	"attributes": {"name"},
+	"data":       {"id"},
+	"included":   {"id"},
+}
+
+// keptHeaders are the response headers stored within cassettes
+var keptHeaders = []string{
+	"Content-Type", "Location", "Retry-After",
+	"Fitbit-Rate-Limit-Limit", "Fitbit-Rate-Limit-Remaining", "Fitbit-Rate-Limit-Reset",
+}
+
+// emailPattern matches email addresses within response bodies
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// userPathPattern matches the user segment of API paths, the current user "-" is kept
+var userPathPattern = regexp.MustCompile(`^(/1(?:\.\d+)?/user/)([^/-][^/]*|-[^/]+)(/|$)`)
+
+// Recorder is a http.RoundTripper which records requests into a cassette file and replays them
+// It can be used as Transport of fitbit.Config to test code using a Session without access to the API
+type Recorder struct {
+	tb    testing.TB
+	path  string
+	mode  Mode
+	next  http.RoundTripper
+	mutex sync.Mutex
+	data  cassette
+	used  []bool
+}
+
+// cassette is the content of a cassette file
+type cassette struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+// interaction is a single recorded request and response
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+// recordedRequest is a sanitized request, the host is not recorded to allow replaying against any API URL
+// User ids within the path are redacted, requests are matched against the redacted path
+type recordedRequest struct {
+	Method string     `json:"method"`
+	Path   string     `json:"path"`
+	Query  url.Values `json:"query,omitempty"`
+	Form   url.Values `json:"form,omitempty"`
+}
+
+// recordedResponse is a sanitized response, JSON bodies are stored as JSON for readable cassettes
+type recordedResponse struct {
+	StatusCode int               `json:"status"`
+	Header     map[string]string `json:"header,omitempty"`
+	JSON       json.RawMessage   `json:"json,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// NewRecorder returns a new recorder using the cassette file at path
+// In replay mode the file must exist, in record mode it is created if it does not exist
+// next is used to send requests in record and rewrite mode (default: http.DefaultTransport)
+// If tb is not nil, requests without recorded interaction in replay mode fail the test, also if the
+// returned error is handled by the code under test, and so do interactions which were not replayed until the test ends
+func NewRecorder(tb testing.TB, path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{tb: tb, path: path, mode: mode, next: next}
+
+	if mode != ModeRewrite {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &r.data); err != nil {
+				return nil, fmt.Errorf("reading cassette %s: %w", path, err)
+			}
+		case errors.Is(err, os.ErrNotExist) && mode == ModeRecord:
+		default:
+			return nil, err
+		}
+	}
+	r.used = make([]bool, len(r.data.Interactions))
+
+	if tb != nil && mode == ModeReplay {
+		tb.Cleanup(func() {
+			for _, unused := range r.Unused() {
+				tb.Errorf("fitbittest: recorded interaction was not replayed: %s", unused)
+			}
+		})
+	}
+
+	return r, nil
+}
+
+// RoundTrip replays a recorded interaction or records a new one depending on the mode
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	if r.mode != ModeRewrite {
+		if i := r.match(recorded); i >= 0 {
+			r.used[i] = true
+			response := r.data.Interactions[i].Response
+			r.mutex.Unlock()
+			return response.replay(req), nil
+		}
+	}
+	mode := r.mode
+	r.mutex.Unlock()
+
+	if mode == ModeReplay {
+		err := fmt.Errorf("%w: %s %s?%s", ErrUnmatchedRequest, recorded.Method, recorded.Path, recorded.Query.Encode())
+		if r.tb != nil {
+			r.tb.Errorf("%v", err)
+		}
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	response, err := recordResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	r.data.Interactions = append(r.data.Interactions, interaction{Request: recorded, Response: response})
+	r.used = append(r.used, true)
+	r.mutex.Unlock()
+
+	return resp, nil
+}
+
+// Save writes the cassette file, nothing is written in replay mode
+func (r *Recorder) Save() error {
+	if r.mode == ModeReplay {
+		return nil
+	}
+
+	r.mutex.Lock()
+	data, err := json.MarshalIndent(r.data, "", "  ")
+	r.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
+
+// Unused returns a description of all recorded interactions which were not replayed
+// Tests can use it to detect requests which are no longer sent
+func (r *Recorder) Unused() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var unused []string
+	for i, used := range r.used {
+		if !used {
+			request := r.data.Interactions[i].Request
+			unused = append(unused, request.Method+" "+request.Path+"?"+request.Query.Encode())
+		}
+	}
+	return unused
+}
+
+// match returns the index of the first unused interaction matching the request or -1, the caller must hold the mutex
+func (r *Recorder) match(request recordedRequest) int {
+	for i, recorded := range r.data.Interactions {
+		if r.used[i] {
+			continue
+		}
+		if recorded.Request.Method == request.Method && recorded.Request.Path == request.Path &&
+			recorded.Request.Query.Encode() == request.Query.Encode() {
+			return i
+		}
+	}
+	return -1
+}
+
+// recordRequest returns the sanitized request, the body of the request is restored to be sent afterwards
+func recordRequest(req *http.Request) (recordedRequest, error) {
+	recorded := recordedRequest{
+		Method: req.Method,
+		Path:   sanitizePath(req.URL.Path),
+		Query:  sanitizeValues(req.URL.Query()),
+	}
+	if len(recorded.Query) == 0 {
+		recorded.Query = nil
+	}
+
+	if req.Body != nil && strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return recordedRequest{}, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		form, err := url.ParseQuery(string(body))
+		if err == nil && len(form) > 0 {
+			recorded.Form = sanitizeValues(form)
+		}
+	}
+
+	return recorded, nil
+}
+
+// recordResponse returns the sanitized response, the body of the response is restored to be read by the caller
+func recordResponse(resp *http.Response) (recordedResponse, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return recordedResponse{}, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := recordedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     make(map[string]string),
+	}
+	for _, key := range keptHeaders {
+		if value := resp.Header.Get(key); value != "" {
+			recorded.Header[key] = value
+		}
+	}
+	if location, err := url.Parse(recorded.Header["Location"]); err == nil && location.RawQuery != "" {
+		location.RawQuery = sanitizeValues(location.Query()).Encode()
+		recorded.Header["Location"] = location.String()
+	}
+
+	var value interface{}
+	if json.Unmarshal(body, &value) == nil {
+		sanitized, err := json.Marshal(sanitizeJSON(value, ""))
+		if err != nil {
+			return recordedResponse{}, err
+		}
+		recorded.JSON = sanitized
+	} else {
+		recorded.Body = emailPattern.ReplaceAllString(string(body), redacted)
+	}
+
+	return recorded, nil
+}
+
+// replay returns the recorded response as response of the given request
+func (r recordedResponse) replay(req *http.Request) *http.Response {
+	body := []byte(r.Body)
+	if len(r.JSON) > 0 {
+		body = r.JSON
+	}
+
+	header := make(http.Header)
+	for key, value := range r.Header {
+		header.Set(key, value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// sanitizePath replaces the user id within the path of API requests
+func sanitizePath(path string) string {
+	return userPathPattern.ReplaceAllString(path, "${1}"+redacted+"${3}")
+}
+
+// sanitizeValues replaces the values of sanitized keys within query parameters or forms
+func sanitizeValues(values url.Values) url.Values {
+	for key := range values {
+		if isSanitizedKey(key) {
+			values[key] = []string{redacted}
+		}
+	}
+	return values
+}
+
+// sanitizeJSON replaces the values of sanitized keys and all email addresses within a decoded JSON value
+// parent is the key of the object or array containing value
+func sanitizeJSON(value interface{}, parent string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if isSanitizedKey(key) || isSanitizedChildKey(parent, key) {
+				if _, ok := child.(string); ok {
+					v[key] = redacted
+					continue
+				}
+			}
+			v[key] = sanitizeJSON(child, key)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = sanitizeJSON(child, parent)
+		}
+	case string:
+		return emailPattern.ReplaceAllString(v, redacted)
+	}
+	return value
+}
+
+// isSanitizedKey returns true if the values of key are sanitized
+func isSanitizedKey(key string) bool {
+	for _, sanitized := range SanitizedKeys {
+		if strings.EqualFold(key, sanitized) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSanitizedChildKey returns true if the values of key are sanitized within objects of the parent key
+func isSanitizedChildKey(parent string, key string) bool {
+	for _, sanitized := range SanitizedChildKeys[parent] {
+		if strings.EqualFold(key, sanitized) {
+			return true
+		}
+	}
+	return false
+}
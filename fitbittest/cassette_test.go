@@ -0,0 +1,377 @@
+package fitbittest_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+)
+
+// newRecorder returns a recorder of the cassette at path reporting unmatched and unused interactions to tb
+func newRecorder(tb testing.TB, path string, mode fitbittest.Mode) *fitbittest.Recorder {
+	tb.Helper()
+	recorder, err := fitbittest.NewRecorder(tb, path, mode, nil)
+	if err != nil {
+		tb.Fatalf("new recorder: %v", err)
+	}
+	return recorder
+}
+
+// reportingTB collects the failures a recorder reports instead of failing the test
+type reportingTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (tb *reportingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *reportingTB) Cleanup(f func()) {
+	tb.cleanups = append(tb.cleanups, f)
+}
+
+// finish runs the registered cleanup functions like at the end of a test
+func (tb *reportingTB) finish() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+	tb.cleanups = nil
+}
+
+// recordingSession returns a session of the server sending all requests through the recorder
+func recordingSession(t *testing.T, server *fitbittest.Server, recorder *fitbittest.Recorder) *fitbit.Session {
+	t.Helper()
+	config := server.Config()
+	config.Transport = recorder
+	session := fitbit.New(config)
+	if _, err := session.Exchange(server.Authorize("ABC123")); err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	return session
+}
+
+// record records a profile and a heart rate request into the cassette at path
+func record(t *testing.T, server *fitbittest.Server, path string, mode fitbittest.Mode) *fitbittest.Recorder {
+	t.Helper()
+	recorder := newRecorder(t, path, mode)
+	session := recordingSession(t, server, recorder)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.HeartLogByDay("2024-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	return recorder
+}
+
+func TestRecorderReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "session.json")
+	server := newServer(t)
+	record(t, server, path, fitbittest.ModeRecord)
+	server.Close()
+
+	// the server is gone, all responses are replayed from the cassette
+	tb := &reportingTB{TB: t}
+	recorder := newRecorder(tb, path, fitbittest.ModeReplay)
+	session := recordingSession(t, server, recorder)
+	heart, err := session.HeartLogByDay("2024-01-01")
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if heart.ActivitiesHeart[0].Value.RestingHeartRate != 58 {
+		t.Errorf("unexpected resting heart rate %d", heart.ActivitiesHeart[0].Value.RestingHeartRate)
+	}
+	if _, err := session.Profile(0); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if unused := recorder.Unused(); len(unused) != 0 {
+		t.Errorf("unused interactions %v", unused)
+	}
+
+	// a request without recorded interaction fails loudly
+	_, err = session.HeartLogByDay("2024-01-02")
+	if !errors.Is(err, fitbittest.ErrUnmatchedRequest) || !strings.Contains(err.Error(), "/1/user/-/activities/heart/date/2024-01-02/1d.json") {
+		t.Errorf("expected ErrUnmatchedRequest naming the request, got %v", err)
+	}
+	// interactions are replayed once
+	if _, err := session.Profile(0); !errors.Is(err, fitbittest.ErrUnmatchedRequest) {
+		t.Errorf("expected ErrUnmatchedRequest for a second profile request, got %v", err)
+	}
+
+	// both misses fail the test, even though the errors were returned
+	if len(tb.errors) != 2 || !strings.Contains(tb.errors[0], "/1/user/-/activities/heart/date/2024-01-02/1d.json") ||
+		!strings.Contains(tb.errors[1], "/1/user/-/profile.json") {
+		t.Errorf("expected the unmatched requests to be reported, got %q", tb.errors)
+	}
+	tb.finish()
+	if len(tb.errors) != 2 {
+		t.Errorf("expected no unused interactions to be reported, got %q", tb.errors)
+	}
+}
+
+func TestRecorderUnused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server := newServer(t)
+	record(t, server, path, fitbittest.ModeRecord)
+
+	tb := &reportingTB{TB: t}
+	recorder := newRecorder(tb, path, fitbittest.ModeReplay)
+	session := recordingSession(t, server, recorder)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+	unused := recorder.Unused()
+	if len(unused) != 1 || unused[0] != "GET /1/user/-/activities/heart/date/2024-01-01/1d.json?" {
+		t.Errorf("unexpected unused interactions %v", unused)
+	}
+
+	// unused interactions fail the test at its end
+	if len(tb.errors) != 0 {
+		t.Errorf("unexpected failures before the end of the test %q", tb.errors)
+	}
+	tb.finish()
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "GET /1/user/-/activities/heart/date/2024-01-01/1d.json?") {
+		t.Errorf("expected the unused interaction to be reported, got %q", tb.errors)
+	}
+}
+
+func TestRecorderWithoutTB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server := newServer(t)
+	record(t, server, path, fitbittest.ModeRecord)
+
+	// without testing.TB the miss is only returned and unused interactions are not reported
+	recorder, err := fitbittest.NewRecorder(nil, path, fitbittest.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/1/user/-/badges.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.RoundTrip(req); !errors.Is(err, fitbittest.ErrUnmatchedRequest) {
+		t.Errorf("expected ErrUnmatchedRequest, got %v", err)
+	}
+}
+
+func TestRecorderMissingCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := fitbittest.NewRecorder(t, path, fitbittest.ModeReplay, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing cassette to fail in replay mode, got %v", err)
+	}
+	if _, err := fitbittest.NewRecorder(t, path, fitbittest.ModeRecord, nil); err != nil {
+		t.Errorf("record mode: %v", err)
+	}
+}
+
+func TestRecorderRecordKeepsInteractions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server := newServer(t)
+	record(t, server, path, fitbittest.ModeRecord)
+
+	// recorded interactions are replayed, only new requests are sent and appended
+	requests := len(server.Requests())
+	recorder := newRecorder(t, path, fitbittest.ModeRecord)
+	session := recordingSession(t, server, recorder)
+	// the exchange was replayed with a redacted token, new requests need a valid token
+	session.SetToken(server.Token("ABC123"))
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.SleepByDay("2024-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	for _, request := range server.Requests()[requests:] {
+		if request.Path == "/1/user/-/profile.json" {
+			t.Error("recorded profile request was sent")
+		}
+	}
+
+	tb := &reportingTB{TB: t}
+	recorder = newRecorder(tb, path, fitbittest.ModeReplay)
+	session = recordingSession(t, server, recorder)
+	if _, err := session.SleepByDay("2024-01-01"); err != nil {
+		t.Errorf("new interaction was not recorded: %v", err)
+	}
+	if unused := recorder.Unused(); len(unused) != 2 {
+		t.Errorf("expected the profile and heart rate interactions to be kept, unused %v", unused)
+	}
+	tb.finish()
+	if len(tb.errors) != 2 {
+		t.Errorf("expected the kept interactions to be reported as unused, got %q", tb.errors)
+	}
+}
+
+func TestRecorderRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server := newServer(t)
+	record(t, server, path, fitbittest.ModeRecord)
+
+	server.Update("ABC123", func(u *fitbittest.User) {
+		u.Days["2024-01-01"].RestingHeartRate = 61
+	})
+	recorder := newRecorder(t, path, fitbittest.ModeRewrite)
+	session := recordingSession(t, server, recorder)
+	if _, err := session.HeartLogByDay("2024-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tb := &reportingTB{TB: t}
+	recorder = newRecorder(tb, path, fitbittest.ModeReplay)
+	session = recordingSession(t, server, recorder)
+	heart, err := session.HeartLogByDay("2024-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if heart.ActivitiesHeart[0].Value.RestingHeartRate != 61 {
+		t.Errorf("cassette was not rewritten, resting heart rate %d", heart.ActivitiesHeart[0].Value.RestingHeartRate)
+	}
+	// the profile interaction of the previous recording was replaced
+	if _, err := session.Profile(0); !errors.Is(err, fitbittest.ErrUnmatchedRequest) {
+		t.Errorf("expected the old profile interaction to be removed, got %v", err)
+	}
+	if len(tb.errors) != 1 {
+		t.Errorf("expected the profile request to be reported, got %q", tb.errors)
+	}
+}
+
+func TestRecorderSanitizes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server := newServer(t)
+	server.Update("ABC123", func(u *fitbittest.User) {
+		u.DisplayName = "jo.doe@example.com"
+		u.FullName = "Josephine Doe"
+	})
+
+	recorder := newRecorder(t, path, fitbittest.ModeRecord)
+	session := recordingSession(t, server, recorder)
+	secrets := []string{"ABC123", "jo.doe@example.com", "Josephine Doe", server.ClientSecret}
+	token := session.Token()
+	secrets = append(secrets, token.AccessToken, token.RefreshToken)
+
+	// the user id is part of the path if a user is requested by id
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/1/user/ABC123/profile.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	resp, err := (&http.Client{Transport: recorder}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("profile by user id returned %d", resp.StatusCode)
+	}
+
+	// a refresh and a revoke send the tokens within the request form
+	session.SetToken(expired(token))
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+	token = session.Token()
+	secrets = append(secrets, token.AccessToken, token.RefreshToken)
+	if err := session.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), `"/1/user/REDACTED/profile.json"`) {
+		t.Errorf("user id of the path was not redacted:\n%s", data)
+	}
+
+	// the redacted cassette is replayed, also for requests of a user by id
+	// only this request is replayed, the other interactions are left unused
+	tb := &reportingTB{TB: t}
+	recorder = newRecorder(tb, path, fitbittest.ModeReplay)
+	req.Header.Set("Authorization", "Bearer replayed")
+	resp, err = (&http.Client{Transport: recorder}).Do(req)
+	if err != nil {
+		t.Fatalf("replay of the request by user id: %v", err)
+	}
+	resp.Body.Close()
+	if len(tb.errors) != 0 {
+		t.Errorf("unexpected failures %q", tb.errors)
+	}
+}
+
+func TestRecorderSanitizesFriends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server := newServer(t)
+	server.Update("ABC123", func(u *fitbittest.User) {
+		u.DateOfBirth = "1987-06-05"
+	})
+
+	recorder := newRecorder(t, path, fitbittest.ModeRecord)
+	session := recordingSession(t, server, recorder)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.GetFriends(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.GetFriendsLeaderboard(); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"ABC123", "FRND01", "Max M.", `"Jo"`, "1987-06-05"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %s:\n%s", secret, data)
+		}
+	}
+
+	recorder = newRecorder(t, path, fitbittest.ModeReplay)
+	session = recordingSession(t, server, recorder)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	friends, err := session.GetFriends()
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(friends.Data) != 1 || friends.Data[0].ID != "REDACTED" || friends.Data[0].Attributes.Name != "REDACTED" {
+		t.Errorf("unexpected replayed friends %+v", friends.Data)
+	}
+	leaderboard, err := session.GetFriendsLeaderboard()
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(leaderboard.Data) != 2 || leaderboard.Data[0].Attributes.StepSummary != 42000 || leaderboard.Data[0].Relationships.User.Data.ID != "REDACTED" {
+		t.Errorf("unexpected replayed leaderboard %+v", leaderboard.Data)
+	}
+}
@@ -0,0 +1,153 @@
+package fitbittest
+
+import (
+	"time"
+)
+
+// User contains the seeded data of a single user
+// Dates are keys in the format yyyy-MM-dd, times are interpreted in the timezone of the user
+type User struct {
+	ID          string          // ID is the encoded user id used within tokens and subscriptions
+	DisplayName string          // DisplayName is returned within the profile
+	FullName    string          // FullName is returned within the profile
+	DateOfBirth string          // DateOfBirth is returned within the profile in the format yyyy-MM-dd
+	Timezone    string          // Timezone is the IANA timezone of the user (default: UTC)
+	Days        map[string]*Day // Days contains the daily data by date
+	Sleep       []Sleep         // Sleep contains all sleep logs
+	Weight      []Weight        // Weight contains all body weight and body fat logs
+	Activities  []Activity      // Activities contains all activity logs
+	ECG         []ECG           // ECG contains all ECG readings
+	Friends     []Friend        // Friends contains the friends of the user and their rank within the leaderboard
+}
+
+// Friend is a friend of a user
+type Friend struct {
+	ID    string // ID is the encoded user id of the friend
+	Name  string // Name is the display name of the friend
+	Steps int    // Steps is the step count of the friend within the leaderboard
+}
+
+// Day contains the data of a single day
+type Day struct {
+	Steps                float64  // Steps is the number of steps of the day
+	StepsIntraday        []Sample // StepsIntraday contains the steps per minute
+	Distance             float64  // Distance in kilometers
+	Floors               float64  // Floors climbed
+	Elevation            float64  // Elevation in meters
+	CaloriesOut          float64  // CaloriesOut is the total number of burned calories
+	ActivityCalories     float64  // ActivityCalories is the number of calories burned by activities
+	MinutesSedentary     int      // MinutesSedentary is the number of sedentary minutes
+	MinutesLightlyActive int      // MinutesLightlyActive is the number of lightly active minutes
+	MinutesFairlyActive  int      // MinutesFairlyActive is the number of fairly active minutes
+	MinutesVeryActive    int      // MinutesVeryActive is the number of very active minutes
+	RestingHeartRate     int      // RestingHeartRate in beats per minute, 0 if unknown
+	HeartRate            []Sample // HeartRate contains the intraday heart rate in beats per minute
+	HRV                  *HRV     // HRV contains the heart rate variability of the main sleep
+	SpO2                 *SpO2    // SpO2 contains the oxygen saturation of the main sleep
+	TemperatureCore      []Sample // TemperatureCore contains the measured core temperatures in degree Celsius
+	TemperatureSkin      *float64 // TemperatureSkin is the nightly skin temperature relative to the baseline
+	CaloriesIn           float64  // CaloriesIn is the number of consumed calories
+	Water                []Water  // Water contains the water logs of the day
+}
+
+// Sample is a single intraday value
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// HRV contains the heart rate variability of a day
+type HRV struct {
+	DailyRmssd float64
+	DeepRmssd  float64
+	Intraday   []HRVSample
+}
+
+// HRVSample is a single intraday heart rate variability value
+type HRVSample struct {
+	Time     time.Time
+	Rmssd    float64
+	Coverage float64
+	HF       float64
+	LF       float64
+}
+
+// SpO2 contains the oxygen saturation of a day
+type SpO2 struct {
+	Avg      float64
+	Min      float64
+	Max      float64
+	Intraday []Sample
+}
+
+// Water is a single water log in milliliters
+type Water struct {
+	LogID  int64
+	Amount float64
+}
+
+// Sleep is a single sleep log, the date of the sleep is the date of End
+type Sleep struct {
+	LogID         int64
+	Start         time.Time
+	End           time.Time
+	MinutesAsleep int
+	MinutesAwake  int
+	Efficiency    int
+	IsMainSleep   bool
+}
+
+// Weight is a single body weight log, Fat is 0 if it was not measured
+type Weight struct {
+	LogID  int64
+	Time   time.Time
+	Weight float64
+	BMI    float64
+	Fat    float64
+}
+
+// Activity is a single activity log
+type Activity struct {
+	LogID            int64
+	ActivityTypeID   int
+	Name             string
+	Start            time.Time
+	Duration         time.Duration
+	Calories         int
+	Steps            int
+	Distance         float64 // Distance in kilometers
+	AverageHeartRate int
+	TCX              []byte // TCX is returned by the TCX endpoint, a TCX without track points is generated if empty
+}
+
+// ECG is a single ECG reading
+type ECG struct {
+	Start                time.Time
+	AverageHeartRate     int
+	ResultClassification string // ResultClassification is the result of the reading (default: Normal Sinus Rhythm)
+}
+
+// day returns the data of the given date, a new day is created if it does not exist
+func (u *User) day(date string) *Day {
+	if u.Days == nil {
+		u.Days = make(map[string]*Day)
+	}
+	day, ok := u.Days[date]
+	if !ok {
+		day = &Day{}
+		u.Days[date] = day
+	}
+	return day
+}
+
+// location returns the timezone of the user
+func (u *User) location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,1175 @@
+package fitbittest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02T15:04:05.000"
+	clockLayout    = "15:04:05"
+)
+
+// errInvalidDate is returned if a date of the path can not be parsed
+var errInvalidDate = errors.New("invalid date")
+
+// registerRoutes registers all supported API endpoints
+func (s *Server) registerRoutes() {
+	const user = `^/1(?:\.[12])?/user/([^/]+)`
+	const date = `(today|\d{4}-\d{2}-\d{2})`
+	const period = `(today|\d{4}-\d{2}-\d{2}|1d|7d|30d|1w|1m|3m|6m|1y)`
+
+	add := func(method string, pattern string, handler func(w http.ResponseWriter, r *http.Request, u *User, params []string)) {
+		s.routes = append(s.routes, route{method: method, pattern: regexp.MustCompile(user + pattern + `$`), handler: handler})
+	}
+
+	add(http.MethodGet, `/profile\.json`, s.handleProfile)
+
+	add(http.MethodGet, `/activities/heart/date/`+date+`/1d/(1sec|1min)/time/(\d{2}:\d{2})/(\d{2}:\d{2})\.json`, s.handleHeartIntraday)
+	add(http.MethodGet, `/activities/heart/date/`+date+`/`+period+`/(1sec|1min)\.json`, s.handleHeartRangeIntraday)
+	add(http.MethodGet, `/activities/heart/date/`+date+`/`+period+`\.json`, s.handleHeart)
+
+	add(http.MethodGet, `/activities/date/`+date+`\.json`, s.handleActivitySummary)
+	add(http.MethodGet, `/activities/list\.json`, s.handleActivityList)
+	add(http.MethodGet, `/activities/(\d+)\.tcx`, s.handleActivityTCX)
+	add(http.MethodPost, `/activities\.json`, s.handleLogActivity)
+	add(http.MethodDelete, `/activities/(\d+)\.json`, s.handleRemoveActivity)
+	add(http.MethodGet, `/activities/((?:tracker/)?\w+)/date/`+date+`/1d/(1min|5min|15min)\.json`, s.handleActivityIntraday)
+	add(http.MethodGet, `/activities/((?:tracker/)?\w+)/date/`+date+`/`+period+`\.json`, s.handleActivityTimeSeries)
+
+	add(http.MethodGet, `/sleep/date/`+date+`\.json`, s.handleSleepDay)
+	add(http.MethodGet, `/sleep/date/`+date+`/`+date+`\.json`, s.handleSleepRange)
+	add(http.MethodGet, `/sleep/list\.json`, s.handleSleepList)
+
+	add(http.MethodGet, `/ecg/list\.json`, s.handleECGList)
+
+	add(http.MethodGet, `/body/log/(weight|fat)/date/`+date+`\.json`, s.handleBodyLog)
+	add(http.MethodGet, `/body/log/(weight|fat)/date/`+date+`/`+period+`\.json`, s.handleBodyLog)
+	add(http.MethodPost, `/body/log/(weight|fat)\.json`, s.handleAddBodyLog)
+	add(http.MethodDelete, `/body/log/(weight|fat)/(\d+)\.json`, s.handleRemoveBodyLog)
+
+	add(http.MethodGet, `/foods/log/date/`+date+`\.json`, s.handleFoodLog)
+	add(http.MethodGet, `/foods/log/water/date/`+date+`\.json`, s.handleWaterLog)
+	add(http.MethodGet, `/foods/log/(caloriesIn|water)/date/`+date+`/`+period+`\.json`, s.handleFoodTimeSeries)
+	add(http.MethodPost, `/foods/log/water\.json`, s.handleAddWater)
+
+	add(http.MethodGet, `/hrv/date/`+date+`(?:/`+date+`)?\.json`, s.handleHRV)
+	add(http.MethodGet, `/hrv/date/`+date+`(?:/`+date+`)?/all\.json`, s.handleHRVIntraday)
+
+	add(http.MethodGet, `/spo2/date/`+date+`\.json`, s.handleSpO2Day)
+	add(http.MethodGet, `/spo2/date/`+date+`/`+date+`\.json`, s.handleSpO2Range)
+	add(http.MethodGet, `/spo2/date/`+date+`/all\.json`, s.handleSpO2Intraday)
+
+	add(http.MethodGet, `/temp/(core|skin)/date/`+date+`(?:/`+date+`)?\.json`, s.handleTemperature)
+
+	add(http.MethodGet, `/friends\.json`, s.handleFriends)
+	add(http.MethodGet, `/leaderboard/friends\.json`, s.handleLeaderboard)
+
+	add(http.MethodGet, `/(?:(\w+)/)?apiSubscriptions\.json`, s.handleSubscriptionList)
+	add(http.MethodPost, `/(?:(\w+)/)?apiSubscriptions/([^/]+)\.json`, s.handleAddSubscription)
+	add(http.MethodDelete, `/(?:(\w+)/)?apiSubscriptions/([^/]+)\.json`, s.handleRemoveSubscription)
+}
+
+// handleProfile returns the profile of the user
+func (s *Server) handleProfile(w http.ResponseWriter, _ *http.Request, u *User, _ []string) {
+	_, offset := time.Now().In(u.location()).Zone()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user": map[string]interface{}{
+			"encodedId":           u.ID,
+			"displayName":         u.DisplayName,
+			"fullName":            u.FullName,
+			"dateOfBirth":         u.DateOfBirth,
+			"timezone":            u.location().String(),
+			"offsetFromUTCMillis": offset * 1000,
+			"locale":              "en_US",
+			"distanceUnit":        "METRIC",
+			"weightUnit":          "METRIC",
+			"avatar":              "",
+			"features":            map[string]interface{}{},
+			"topBadges":           []interface{}{},
+		},
+	})
+}
+
+// handleFriends returns the friends of the user
+func (s *Server) handleFriends(w http.ResponseWriter, _ *http.Request, u *User, _ []string) {
+	data := []interface{}{}
+	for _, friend := range u.Friends {
+		data = append(data, friendPerson(friend.ID, friend.Name, true))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+// handleLeaderboard returns the leaderboard of the user and the friends ranked by their steps
+// the steps of the user are the steps of the last 7 days
+func (s *Server) handleLeaderboard(w http.ResponseWriter, _ *http.Request, u *User, _ []string) {
+	entries := []Friend{{ID: u.ID, Name: u.DisplayName}}
+	today := time.Now().In(u.location())
+	for i := 0; i < 7; i++ {
+		entries[0].Steps += int(lookup(u, today.AddDate(0, 0, -i).Format(dateLayout)).Steps)
+	}
+	entries = append(entries, u.Friends...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Steps > entries[j].Steps })
+
+	data := []interface{}{}
+	included := []interface{}{}
+	for i, entry := range entries {
+		data = append(data, map[string]interface{}{
+			"type": "ranked-user",
+			"id":   entry.ID,
+			"attributes": map[string]interface{}{
+				"step-rank":    i + 1,
+				"step-summary": entry.Steps,
+			},
+			"relationships": map[string]interface{}{
+				"user": map[string]interface{}{
+					"data": map[string]interface{}{"type": "person", "id": entry.ID},
+				},
+			},
+		})
+		included = append(included, friendPerson(entry.ID, entry.Name, entry.ID != u.ID))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data, "included": included})
+}
+
+// friendPerson returns a person of the friends endpoints
+func friendPerson(id string, name string, friend bool) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "person",
+		"id":   id,
+		"attributes": map[string]interface{}{
+			"name":   name,
+			"avatar": "https://static0.fitbit.com/images/profile/defaultProfile_100.png",
+			"child":  false,
+			"friend": friend,
+		},
+	}
+}
+
+// handleHeart returns the daily heart rate summaries of a date or date range
+func (s *Server) handleHeart(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], params[1])
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"activities-heart": heartDays(u, dates),
+	})
+}
+
+// handleHeartRangeIntraday returns the heart rate summaries of a date range with the intraday heart rate
+func (s *Server) handleHeartRangeIntraday(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], params[1])
+	if !ok {
+		return
+	}
+	var samples []Sample
+	for _, date := range dates {
+		samples = append(samples, lookup(u, date).HeartRate...)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"activities-heart":          heartDays(u, dates),
+		"activities-heart-intraday": intradayDataset(samples, params[2], "", ""),
+	})
+}
+
+// handleHeartIntraday returns the heart rate summary of a day with the intraday heart rate of the given time range
+func (s *Server) handleHeartIntraday(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], "1d")
+	if !ok {
+		return
+	}
+	day := lookup(u, dates[0])
+	dataset := intradayDataset(day.HeartRate, params[1], params[2]+":00", params[3]+":59")
+
+	// the summary of the time range contains the zones on the top level and the average heart rate as string value
+	average := 0.0
+	samples := dataset["dataset"].([]interface{})
+	for _, sample := range samples {
+		average += sample.(map[string]interface{})["value"].(float64) / float64(len(samples))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"activities-heart": []interface{}{map[string]interface{}{
+			"customHeartRateZones": []interface{}{},
+			"dateTime":             dates[0],
+			"heartRateZones":       []interface{}{},
+			"value":                formatValue(average),
+		}},
+		"activities-heart-intraday": dataset,
+	})
+}
+
+// heartDays returns the heart rate summaries of the given dates
+func heartDays(u *User, dates []string) []interface{} {
+	days := []interface{}{}
+	for _, date := range dates {
+		value := map[string]interface{}{
+			"customHeartRateZones": []interface{}{},
+			"heartRateZones":       []interface{}{},
+		}
+		if resting := lookup(u, date).RestingHeartRate; resting != 0 {
+			value["restingHeartRate"] = resting
+		}
+		days = append(days, map[string]interface{}{"dateTime": date, "value": value})
+	}
+	return days
+}
+
+// intradayDataset returns the samples within the optional time range as intraday dataset
+func intradayDataset(samples []Sample, resolution string, from string, to string) map[string]interface{} {
+	dataset := []interface{}{}
+	for _, sample := range samples {
+		clock := sample.Time.Format(clockLayout)
+		if (from != "" && clock < from) || (to != "" && clock > to) {
+			continue
+		}
+		dataset = append(dataset, map[string]interface{}{"time": clock, "value": sample.Value})
+	}
+	datasetType := "minute"
+	if resolution == "1sec" {
+		datasetType = "second"
+	}
+	return map[string]interface{}{
+		"dataset":         dataset,
+		"datasetInterval": 1,
+		"datasetType":     datasetType,
+	}
+}
+
+// handleActivitySummary returns the activity summary and the activity logs of a day
+func (s *Server) handleActivitySummary(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], "1d")
+	if !ok {
+		return
+	}
+	day := lookup(u, dates[0])
+
+	activities := []interface{}{}
+	for _, activity := range u.Activities {
+		if activity.Start.In(u.location()).Format(dateLayout) == dates[0] {
+			activities = append(activities, map[string]interface{}{
+				"activityId":   activity.ActivityTypeID,
+				"calories":     activity.Calories,
+				"distance":     activity.Distance,
+				"duration":     activity.Duration.Milliseconds(),
+				"logId":        activity.LogID,
+				"name":         activity.Name,
+				"startDate":    dates[0],
+				"startTime":    activity.Start.In(u.location()).Format("15:04"),
+				"steps":        activity.Steps,
+				"hasStartTime": true,
+			})
+		}
+	}
+
+	summary := map[string]interface{}{
+		"steps":                day.Steps,
+		"caloriesOut":          day.CaloriesOut,
+		"activityCalories":     day.ActivityCalories,
+		"floors":               day.Floors,
+		"elevation":            day.Elevation,
+		"sedentaryMinutes":     day.MinutesSedentary,
+		"lightlyActiveMinutes": day.MinutesLightlyActive,
+		"fairlyActiveMinutes":  day.MinutesFairlyActive,
+		"veryActiveMinutes":    day.MinutesVeryActive,
+		"distances":            []interface{}{map[string]interface{}{"activity": "total", "distance": day.Distance}},
+	}
+	if day.RestingHeartRate != 0 {
+		summary["restingHeartRate"] = day.RestingHeartRate
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"activities": activities,
+		"goals":      map[string]interface{}{},
+		"summary":    summary,
+	})
+}
+
+// activityValue returns the daily value of an activity time series resource
+func activityValue(day Day, resource string) (float64, bool) {
+	switch strings.TrimPrefix(resource, "tracker/") {
+	case "steps":
+		return day.Steps, true
+	case "calories":
+		return day.CaloriesOut, true
+	case "distance":
+		return day.Distance, true
+	case "floors":
+		return day.Floors, true
+	case "elevation":
+		return day.Elevation, true
+	case "minutesSedentary":
+		return float64(day.MinutesSedentary), true
+	case "minutesLightlyActive":
+		return float64(day.MinutesLightlyActive), true
+	case "minutesFairlyActive":
+		return float64(day.MinutesFairlyActive), true
+	case "minutesVeryActive":
+		return float64(day.MinutesVeryActive), true
+	case "activityCalories":
+		return day.ActivityCalories, true
+	}
+	return 0, false
+}
+
+// activitySeries returns the daily values of the resource as time series
+func activitySeries(u *User, resource string, dates []string) ([]interface{}, bool) {
+	series := []interface{}{}
+	for _, date := range dates {
+		value, ok := activityValue(lookup(u, date), resource)
+		if !ok {
+			return nil, false
+		}
+		series = append(series, map[string]interface{}{"dateTime": date, "value": formatValue(value)})
+	}
+	return series, true
+}
+
+// handleActivityTimeSeries returns the daily values of an activity resource
+func (s *Server) handleActivityTimeSeries(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[1], params[2])
+	if !ok {
+		return
+	}
+	series, ok := activitySeries(u, params[0], dates)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "validation", "invalid resource "+params[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"activities-" + strings.ReplaceAll(params[0], "/", "-"): series,
+	})
+}
+
+// handleActivityIntraday returns the daily and intraday values of an activity resource, intraday values are only seeded for steps
+func (s *Server) handleActivityIntraday(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[1], "1d")
+	if !ok {
+		return
+	}
+	series, ok := activitySeries(u, params[0], dates)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "validation", "invalid resource "+params[0])
+		return
+	}
+	var samples []Sample
+	if params[0] == "steps" {
+		samples = lookup(u, dates[0]).StepsIntraday
+	}
+	key := "activities-" + strings.ReplaceAll(params[0], "/", "-")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		key:               series,
+		key + "-intraday": intradayDataset(samples, "1min", "", ""),
+	})
+}
+
+// activityEntry returns an activity in the format of the activity log list
+func (s *Server) activityEntry(u *User, activity Activity) map[string]interface{} {
+	entry := map[string]interface{}{
+		"activityName":      activity.Name,
+		"activityTypeId":    activity.ActivityTypeID,
+		"calories":          activity.Calories,
+		"distance":          activity.Distance,
+		"distanceUnit":      "Kilometer",
+		"duration":          activity.Duration.Milliseconds(),
+		"activeDuration":    activity.Duration.Milliseconds(),
+		"logId":             activity.LogID,
+		"logType":           "auto_detected",
+		"startTime":         activity.Start.In(u.location()).Format("2006-01-02T15:04:05.000-07:00"),
+		"originalStartTime": activity.Start.In(u.location()).Format("2006-01-02T15:04:05.000-07:00"),
+		"originalDuration":  activity.Duration.Milliseconds(),
+		"lastModified":      activity.Start.Add(activity.Duration).UTC().Format("2006-01-02T15:04:05.000Z"),
+		"steps":             activity.Steps,
+		"tcxLink":           fmt.Sprintf("%s/1/user/-/activities/%d.tcx", s.URL, activity.LogID),
+	}
+	if activity.AverageHeartRate != 0 {
+		entry["averageHeartRate"] = activity.AverageHeartRate
+	}
+	return entry
+}
+
+// handleActivityList returns a page of the activity log list
+func (s *Server) handleActivityList(w http.ResponseWriter, r *http.Request, u *User, _ []string) {
+	activities := append([]Activity(nil), u.Activities...)
+	page, ok := s.listPage(w, r, u, len(activities), 100, func(i int) time.Time { return activities[i].Start }, func(i, j int) {
+		activities[i], activities[j] = activities[j], activities[i]
+	})
+	if !ok {
+		return
+	}
+
+	entries := []interface{}{}
+	for _, i := range page.indices {
+		entries = append(entries, s.activityEntry(u, activities[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"activities": entries,
+		"pagination": page.pagination,
+	})
+}
+
+// handleActivityTCX returns the TCX of an activity
+func (s *Server) handleActivityTCX(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	logID, _ := strconv.ParseInt(params[0], 10, 64)
+	for _, activity := range u.Activities {
+		if activity.LogID != logID {
+			continue
+		}
+
+		data := activity.TCX
+		if len(data) == 0 {
+			var err error
+			data, err = fitbit.WriteTCX(activityTCX(activity))
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "system", err.Error())
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/vnd.garmin.tcx+xml;charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+		return
+	}
+	writeError(w, http.StatusNotFound, "not_found", "activity log "+params[0]+" does not exist")
+}
+
+// activityTCX returns a TCX with a single lap without track points of the activity
+func activityTCX(activity Activity) fitbit.GarminTrainingCenterDatabasev2 {
+	return fitbit.GarminTrainingCenterDatabasev2{
+		Activities: &fitbit.TCXActivities{
+			Activity: []fitbit.TCXActivity{{
+				Sport: "Other",
+				ID:    fitbit.TCXTime{Time: activity.Start},
+				Lap: []fitbit.TCXLap{{
+					StartTime:        fitbit.TCXTime{Time: activity.Start},
+					TotalTimeSeconds: activity.Duration.Seconds(),
+					DistanceMeters:   activity.Distance * 1000,
+					Calories:         activity.Calories,
+					Intensity:        "Active",
+					TriggerMethod:    "Manual",
+				}},
+				Creator: &fitbit.TCXSource{Name: "Fitbit"},
+			}},
+		},
+	}
+}
+
+// handleLogActivity adds a new activity log
+func (s *Server) handleLogActivity(w http.ResponseWriter, r *http.Request, u *User, _ []string) {
+	start, err := time.ParseInLocation("2006-01-02 15:04", r.PostFormValue("date")+" "+r.PostFormValue("startTime"), u.location())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation", "invalid date or startTime")
+		return
+	}
+	duration, err := strconv.ParseInt(r.PostFormValue("durationMillis"), 10, 64)
+	if err != nil || duration <= 0 {
+		writeError(w, http.StatusBadRequest, "validation", "invalid durationMillis")
+		return
+	}
+
+	activity := Activity{
+		LogID:    s.logID(),
+		Name:     r.PostFormValue("activityName"),
+		Start:    start,
+		Duration: time.Duration(duration) * time.Millisecond,
+	}
+	activity.ActivityTypeID, _ = strconv.Atoi(r.PostFormValue("activityId"))
+	activity.Calories, _ = strconv.Atoi(r.PostFormValue("manualCalories"))
+	activity.Distance, _ = strconv.ParseFloat(r.PostFormValue("distance"), 64)
+	u.Activities = append(u.Activities, activity)
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"activityLog": map[string]interface{}{
+			"activityId":  activity.ActivityTypeID,
+			"calories":    activity.Calories,
+			"distance":    activity.Distance,
+			"duration":    duration,
+			"logId":       activity.LogID,
+			"name":        activity.Name,
+			"startTime":   r.PostFormValue("startTime"),
+			"description": "",
+		},
+	})
+}
+
+// handleRemoveActivity removes an activity log
+func (s *Server) handleRemoveActivity(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	logID, _ := strconv.ParseInt(params[0], 10, 64)
+	for i, activity := range u.Activities {
+		if activity.LogID == logID {
+			u.Activities = append(u.Activities[:i], u.Activities[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not_found", "activity log "+params[0]+" does not exist")
+}
+
+// sleepEntry returns a sleep log in the format of the sleep endpoints
+func sleepEntry(u *User, sleep Sleep) map[string]interface{} {
+	return map[string]interface{}{
+		"dateOfSleep":         sleep.End.In(u.location()).Format(dateLayout),
+		"duration":            sleep.End.Sub(sleep.Start).Milliseconds(),
+		"efficiency":          sleep.Efficiency,
+		"endTime":             sleep.End.In(u.location()).Format(dateTimeLayout),
+		"infoCode":            0,
+		"isMainSleep":         sleep.IsMainSleep,
+		"levels":              map[string]interface{}{"data": []interface{}{}, "shortData": []interface{}{}, "summary": map[string]interface{}{}},
+		"logId":               sleep.LogID,
+		"logType":             "auto_detected",
+		"minutesAfterWakeup":  0,
+		"minutesAsleep":       sleep.MinutesAsleep,
+		"minutesAwake":        sleep.MinutesAwake,
+		"minutesToFallAsleep": 0,
+		"startTime":           sleep.Start.In(u.location()).Format(dateTimeLayout),
+		"timeInBed":           int(sleep.End.Sub(sleep.Start).Minutes()),
+		"type":                "stages",
+	}
+}
+
+// sleepOfDates returns all sleep logs of the given dates
+func sleepOfDates(u *User, dates []string) []interface{} {
+	sleeps := []interface{}{}
+	for _, sleep := range u.Sleep {
+		date := sleep.End.In(u.location()).Format(dateLayout)
+		for _, d := range dates {
+			if d == date {
+				sleeps = append(sleeps, sleepEntry(u, sleep))
+			}
+		}
+	}
+	return sleeps
+}
+
+// handleSleepDay returns the sleep logs and the summary of a day
+func (s *Server) handleSleepDay(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], "1d")
+	if !ok {
+		return
+	}
+	sleeps := sleepOfDates(u, dates)
+	asleep, inBed := 0, 0
+	for _, sleep := range sleeps {
+		asleep += sleep.(map[string]interface{})["minutesAsleep"].(int)
+		inBed += sleep.(map[string]interface{})["timeInBed"].(int)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sleep": sleeps,
+		"summary": map[string]interface{}{
+			"totalMinutesAsleep": asleep,
+			"totalSleepRecords":  len(sleeps),
+			"totalTimeInBed":     inBed,
+		},
+	})
+}
+
+// handleSleepRange returns the sleep logs of a date range
+func (s *Server) handleSleepRange(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], params[1])
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"sleep": sleepOfDates(u, dates)})
+}
+
+// handleSleepList returns a page of the sleep log list
+func (s *Server) handleSleepList(w http.ResponseWriter, r *http.Request, u *User, _ []string) {
+	sleeps := append([]Sleep(nil), u.Sleep...)
+	page, ok := s.listPage(w, r, u, len(sleeps), 100, func(i int) time.Time { return sleeps[i].Start }, func(i, j int) {
+		sleeps[i], sleeps[j] = sleeps[j], sleeps[i]
+	})
+	if !ok {
+		return
+	}
+
+	entries := []interface{}{}
+	for _, i := range page.indices {
+		entries = append(entries, sleepEntry(u, sleeps[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sleep":      entries,
+		"pagination": page.pagination,
+	})
+}
+
+// handleECGList returns a page of the ECG log list
+func (s *Server) handleECGList(w http.ResponseWriter, r *http.Request, u *User, _ []string) {
+	readings := append([]ECG(nil), u.ECG...)
+	page, ok := s.listPage(w, r, u, len(readings), 10, func(i int) time.Time { return readings[i].Start }, func(i, j int) {
+		readings[i], readings[j] = readings[j], readings[i]
+	})
+	if !ok {
+		return
+	}
+
+	entries := []interface{}{}
+	for _, i := range page.indices {
+		entries = append(entries, ecgEntry(u, readings[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ecgReadings": entries,
+		"pagination":  page.pagination,
+	})
+}
+
+// ecgEntry returns a reading in the format of the ECG log list
+func ecgEntry(u *User, reading ECG) map[string]interface{} {
+	classification := reading.ResultClassification
+	if classification == "" {
+		classification = "Normal Sinus Rhythm"
+	}
+	return map[string]interface{}{
+		"startTime":               reading.Start.In(u.location()).Format(dateTimeLayout),
+		"averageHeartRate":        reading.AverageHeartRate,
+		"resultClassification":    classification,
+		"waveformSamples":         []int{},
+		"samplingFrequencyHz":     250,
+		"scalingFactor":           10922,
+		"numberOfWaveformSamples": 0,
+		"leadNumber":              1,
+		"featureVersion":          "1.2.3-2.11",
+		"deviceName":              "Sense 2",
+		"firmwareVersion":         "1.2.3",
+	}
+}
+
+// handleBodyLog returns the weight or fat logs of a date or date range
+func (s *Server) handleBodyLog(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	end := "1d"
+	if len(params) > 2 && params[2] != "" {
+		end = params[2]
+	}
+	dates, ok := dateRange(w, u, params[1], end)
+	if !ok {
+		return
+	}
+
+	entries := []interface{}{}
+	for _, weight := range u.Weight {
+		t := weight.Time.In(u.location())
+		if !containsDate(dates, t.Format(dateLayout)) || (params[0] == "fat" && weight.Fat == 0) {
+			continue
+		}
+		entries = append(entries, bodyEntry(params[0], weight, t))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{params[0]: entries})
+}
+
+// bodyEntry returns a weight or fat log in the format of the body endpoints
+func bodyEntry(kind string, weight Weight, t time.Time) map[string]interface{} {
+	entry := map[string]interface{}{
+		"date":   t.Format(dateLayout),
+		"logId":  weight.LogID,
+		"source": "API",
+		"time":   t.Format(clockLayout),
+	}
+	if kind == "weight" {
+		entry["weight"] = weight.Weight
+		entry["bmi"] = weight.BMI
+	}
+	if weight.Fat != 0 {
+		entry["fat"] = weight.Fat
+	}
+	return entry
+}
+
+// handleAddBodyLog adds a new weight or fat log
+func (s *Server) handleAddBodyLog(w http.ResponseWriter, r *http.Request, u *User, params []string) {
+	clock := r.PostFormValue("time")
+	if clock == "" {
+		clock = "23:59:59"
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", r.PostFormValue("date")+" "+clock, u.location())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation", "invalid date or time")
+		return
+	}
+	value, err := strconv.ParseFloat(r.PostFormValue(params[0]), 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation", "invalid "+params[0])
+		return
+	}
+
+	weight := Weight{LogID: s.logID(), Time: t}
+	if params[0] == "weight" {
+		weight.Weight = value
+	} else {
+		weight.Fat = value
+	}
+	u.Weight = append(u.Weight, weight)
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{params[0] + "Log": bodyEntry(params[0], weight, t)})
+}
+
+// handleRemoveBodyLog removes a weight or fat log
+func (s *Server) handleRemoveBodyLog(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	logID, _ := strconv.ParseInt(params[1], 10, 64)
+	for i, weight := range u.Weight {
+		if weight.LogID == logID {
+			u.Weight = append(u.Weight[:i], u.Weight[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not_found", "body log "+params[1]+" does not exist")
+}
+
+// handleFoodLog returns the food log summary of a day
+func (s *Server) handleFoodLog(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], "1d")
+	if !ok {
+		return
+	}
+	day := lookup(u, dates[0])
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"foods": []interface{}{},
+		"goals": map[string]interface{}{},
+		"summary": map[string]interface{}{
+			"calories": day.CaloriesIn,
+			"water":    waterTotal(day),
+		},
+	})
+}
+
+// handleWaterLog returns the water logs of a day
+func (s *Server) handleWaterLog(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], "1d")
+	if !ok {
+		return
+	}
+	day := lookup(u, dates[0])
+	logs := []interface{}{}
+	for _, water := range day.Water {
+		logs = append(logs, map[string]interface{}{"amount": water.Amount, "logId": water.LogID})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"summary": map[string]interface{}{"water": waterTotal(day)},
+		"water":   logs,
+	})
+}
+
+// handleFoodTimeSeries returns the calories in or water time series
+func (s *Server) handleFoodTimeSeries(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[1], params[2])
+	if !ok {
+		return
+	}
+	series := []interface{}{}
+	for _, date := range dates {
+		day := lookup(u, date)
+		value := day.CaloriesIn
+		if params[0] == "water" {
+			value = waterTotal(day)
+		}
+		series = append(series, map[string]interface{}{"dateTime": date, "value": formatValue(value)})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"foods-log-" + params[0]: series})
+}
+
+// handleAddWater adds a new water log
+func (s *Server) handleAddWater(w http.ResponseWriter, r *http.Request, u *User, _ []string) {
+	dates, ok := dateRange(w, u, r.PostFormValue("date"), "1d")
+	if !ok {
+		return
+	}
+	amount, err := strconv.ParseFloat(r.PostFormValue("amount"), 64)
+	if err != nil || amount <= 0 {
+		writeError(w, http.StatusBadRequest, "validation", "invalid amount")
+		return
+	}
+	water := Water{LogID: s.logID(), Amount: amount}
+	day := u.day(dates[0])
+	day.Water = append(day.Water, water)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"waterLog": map[string]interface{}{"amount": water.Amount, "logId": water.LogID},
+	})
+}
+
+// waterTotal returns the sum of all water logs of a day
+func waterTotal(day Day) float64 {
+	total := 0.0
+	for _, water := range day.Water {
+		total += water.Amount
+	}
+	return total
+}
+
+// handleHRV returns the daily heart rate variability of a date or date range
+func (s *Server) handleHRV(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], params[1])
+	if !ok {
+		return
+	}
+	days := []interface{}{}
+	for _, date := range dates {
+		if hrv := lookup(u, date).HRV; hrv != nil {
+			days = append(days, map[string]interface{}{
+				"dateTime": date,
+				"value":    map[string]interface{}{"dailyRmssd": hrv.DailyRmssd, "deepRmssd": hrv.DeepRmssd},
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"hrv": days})
+}
+
+// handleHRVIntraday returns the intraday heart rate variability of a date or date range
+func (s *Server) handleHRVIntraday(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], params[1])
+	if !ok {
+		return
+	}
+	days := []interface{}{}
+	for _, date := range dates {
+		hrv := lookup(u, date).HRV
+		if hrv == nil {
+			continue
+		}
+		minutes := []interface{}{}
+		for _, sample := range hrv.Intraday {
+			minutes = append(minutes, map[string]interface{}{
+				"minute": sample.Time.In(u.location()).Format(dateTimeLayout),
+				"value": map[string]interface{}{
+					"rmssd":    sample.Rmssd,
+					"coverage": sample.Coverage,
+					"hf":       sample.HF,
+					"lf":       sample.LF,
+				},
+			})
+		}
+		days = append(days, map[string]interface{}{"dateTime": date, "minutes": minutes})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"hrv": days})
+}
+
+// spO2Day returns the daily SpO2 of a date, days without data only contain the date
+func spO2Day(u *User, date string) map[string]interface{} {
+	spo2 := lookup(u, date).SpO2
+	if spo2 == nil {
+		return map[string]interface{}{"dateTime": date}
+	}
+	return map[string]interface{}{
+		"dateTime": date,
+		"value":    map[string]interface{}{"avg": spo2.Avg, "min": spo2.Min, "max": spo2.Max},
+	}
+}
+
+// handleSpO2Day returns the daily SpO2 of a day
+func (s *Server) handleSpO2Day(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], "1d")
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, spO2Day(u, dates[0]))
+}
+
+// handleSpO2Range returns the daily SpO2 of a date range
+func (s *Server) handleSpO2Range(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], params[1])
+	if !ok {
+		return
+	}
+	days := []interface{}{}
+	for _, date := range dates {
+		days = append(days, spO2Day(u, date))
+	}
+	writeJSON(w, http.StatusOK, days)
+}
+
+// handleSpO2Intraday returns the intraday SpO2 of a day
+func (s *Server) handleSpO2Intraday(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[0], "1d")
+	if !ok {
+		return
+	}
+	minutes := []interface{}{}
+	if spo2 := lookup(u, dates[0]).SpO2; spo2 != nil {
+		for _, sample := range spo2.Intraday {
+			minutes = append(minutes, map[string]interface{}{
+				"minute": sample.Time.In(u.location()).Format("2006-01-02T15:04:05"),
+				"value":  sample.Value,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"dateTime": dates[0], "minutes": minutes})
+}
+
+// handleTemperature returns the core or skin temperature of a date or date range
+func (s *Server) handleTemperature(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	dates, ok := dateRange(w, u, params[1], params[2])
+	if !ok {
+		return
+	}
+	entries := []interface{}{}
+	for _, date := range dates {
+		day := lookup(u, date)
+		if params[0] == "core" {
+			for _, sample := range day.TemperatureCore {
+				entries = append(entries, map[string]interface{}{
+					"dateTime": sample.Time.In(u.location()).Format("2006-01-02T15:04:05"),
+					"value":    sample.Value,
+				})
+			}
+		} else if day.TemperatureSkin != nil {
+			entries = append(entries, map[string]interface{}{
+				"dateTime": date,
+				"value":    map[string]interface{}{"nightlyRelative": *day.TemperatureSkin},
+				"logType":  "dedicated_temp_sensor",
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"temp" + strings.ToUpper(params[0][:1]) + params[0][1:]: entries})
+}
+
+// handleSubscriptionList returns the subscriptions of the user, filtered by collection if given
+func (s *Server) handleSubscriptionList(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	subscriptions := []fitbit.Subscription{}
+	for _, subscription := range s.subscriptions[u.ID] {
+		if params[0] == "" || subscription.CollectionType == params[0] {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	writeJSON(w, http.StatusOK, fitbit.SubscriptionList{APISubscriptions: subscriptions})
+}
+
+// handleAddSubscription adds a subscription, an existing subscription with the same id is returned as conflict
+func (s *Server) handleAddSubscription(w http.ResponseWriter, r *http.Request, u *User, params []string) {
+	collection := params[0]
+	if collection == "" {
+		collection = "user"
+	}
+	subscriberID := r.Header.Get("X-Fitbit-Subscriber-Id")
+	if subscriberID == "" {
+		subscriberID = "1"
+	}
+
+	for _, subscription := range s.subscriptions[u.ID] {
+		if subscription.SubscriptionID != params[1] {
+			continue
+		}
+		if subscription.CollectionType == collection && subscription.SubscriberID == subscriberID {
+			writeJSON(w, http.StatusOK, subscription)
+			return
+		}
+		writeJSON(w, http.StatusConflict, subscription)
+		return
+	}
+
+	subscription := fitbit.Subscription{
+		CollectionType: collection,
+		OwnerID:        u.ID,
+		OwnerType:      "user",
+		SubscriberID:   subscriberID,
+		SubscriptionID: params[1],
+	}
+	s.subscriptions[u.ID] = append(s.subscriptions[u.ID], subscription)
+	writeJSON(w, http.StatusCreated, subscription)
+}
+
+// handleRemoveSubscription removes a subscription
+func (s *Server) handleRemoveSubscription(w http.ResponseWriter, _ *http.Request, u *User, params []string) {
+	for i, subscription := range s.subscriptions[u.ID] {
+		if subscription.SubscriptionID == params[1] && (params[0] == "" || subscription.CollectionType == params[0]) {
+			s.subscriptions[u.ID] = append(s.subscriptions[u.ID][:i], s.subscriptions[u.ID][i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not_found", "subscription "+params[1]+" does not exist")
+}
+
+// listPage contains the selected entries and the pagination of a log list
+type listPage struct {
+	indices    []int
+	pagination map[string]interface{}
+}
+
+// listPage selects the entries of a log list page based on beforeDate or afterDate, sort, limit and offset
+// start returns the start time of an entry, swap swaps two entries to sort them
+func (s *Server) listPage(w http.ResponseWriter, r *http.Request, u *User, n int, maxLimit int, start func(i int) time.Time, swap func(i, j int)) (listPage, bool) {
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 || limit > maxLimit {
+		writeError(w, http.StatusBadRequest, "validation", fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		return listPage{}, false
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	before, after := query.Get("beforeDate"), query.Get("afterDate")
+	if (before == "") == (after == "") {
+		writeError(w, http.StatusBadRequest, "validation", "either beforeDate or afterDate must be given")
+		return listPage{}, false
+	}
+	boundary, err := parseListDate(before+after, u.location())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation", "invalid date "+before+after)
+		return listPage{}, false
+	}
+
+	sort.Sort(sortable{n: n, less: func(i, j int) bool { return start(i).Before(start(j)) }, swap: swap})
+	var matching []int
+	for i := 0; i < n; i++ {
+		if (before != "" && start(i).Before(boundary)) || (after != "" && start(i).After(boundary)) {
+			matching = append(matching, i)
+		}
+	}
+	if before != "" {
+		for i, j := 0, len(matching)-1; i < j; i, j = i+1, j-1 {
+			matching[i], matching[j] = matching[j], matching[i]
+		}
+	}
+
+	page := listPage{pagination: map[string]interface{}{
+		"limit":    limit,
+		"offset":   offset,
+		"next":     "",
+		"previous": "",
+		"sort":     query.Get("sort"),
+	}}
+	if before != "" {
+		page.pagination["beforeDate"] = before
+	} else {
+		page.pagination["afterDate"] = after
+	}
+	if offset < len(matching) {
+		end := offset + limit
+		if end > len(matching) {
+			end = len(matching)
+		}
+		page.indices = matching[offset:end]
+		if end < len(matching) {
+			next := *r.URL
+			nextQuery := next.Query()
+			nextQuery.Set("offset", strconv.Itoa(end))
+			next.RawQuery = nextQuery.Encode()
+			page.pagination["next"] = s.URL + next.RequestURI()
+		}
+	}
+	if offset > 0 {
+		previous := *r.URL
+		previousQuery := previous.Query()
+		previousQuery.Set("offset", strconv.Itoa(max(offset-limit, 0)))
+		previous.RawQuery = previousQuery.Encode()
+		page.pagination["previous"] = s.URL + previous.RequestURI()
+	}
+	return page, true
+}
+
+// sortable implements sort.Interface using functions
+type sortable struct {
+	n    int
+	less func(i, j int) bool
+	swap func(i, j int)
+}
+
+// Len returns the number of entries
+func (s sortable) Len() int { return s.n }
+
+// Less reports whether entry i is sorted before entry j
+func (s sortable) Less(i, j int) bool { return s.less(i, j) }
+
+// Swap swaps the entries i and j
+func (s sortable) Swap(i, j int) { s.swap(i, j) }
+
+// parseListDate parses the date of a log list request which can contain a time
+func parseListDate(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04:05", dateLayout} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errInvalidDate
+}
+
+// dateRange returns all dates of a range, end can be a date or a period like 7d ending at start
+// An error response is written if a date is invalid
+func dateRange(w http.ResponseWriter, u *User, start string, end string) ([]string, bool) {
+	first, err := parseDate(u, start)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation", "invalid date "+start)
+		return nil, false
+	}
+
+	var last time.Time
+	switch end {
+	case "", "1d":
+		last = first
+	case "7d", "1w":
+		first, last = first.AddDate(0, 0, -6), first
+	case "30d":
+		first, last = first.AddDate(0, 0, -29), first
+	case "1m":
+		first, last = first.AddDate(0, -1, 1), first
+	case "3m":
+		first, last = first.AddDate(0, -3, 1), first
+	case "6m":
+		first, last = first.AddDate(0, -6, 1), first
+	case "1y":
+		first, last = first.AddDate(-1, 0, 1), first
+	default:
+		last, err = parseDate(u, end)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "validation", "invalid date "+end)
+			return nil, false
+		}
+	}
+	if last.Before(first) {
+		writeError(w, http.StatusBadRequest, "validation", "the end date must be after the start date")
+		return nil, false
+	}
+
+	var dates []string
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format(dateLayout))
+	}
+	return dates, true
+}
+
+// parseDate parses a date of the path, today is resolved in the timezone of the user
+func parseDate(u *User, value string) (time.Time, error) {
+	if value == "today" {
+		value = time.Now().In(u.location()).Format(dateLayout)
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, errInvalidDate
+	}
+	return t, nil
+}
+
+// containsDate returns true if date is within dates
+func containsDate(dates []string, date string) bool {
+	for _, d := range dates {
+		if d == date {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the data of a date or an empty day
+func lookup(u *User, date string) Day {
+	if day, ok := u.Days[date]; ok && day != nil {
+		return *day
+	}
+	return Day{}
+}
+
+// formatValue formats a value of a time series which is returned as string by the Fitbit API
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// logID returns a new unique log id, the caller must hold the mutex
+func (s *Server) logID() int64 {
+	s.nextLogID++
+	return s.nextLogID
+}
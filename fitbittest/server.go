@@ -0,0 +1,526 @@
+// Package fitbittest provides an in-process fake of the Fitbit Web API for tests
+//
+// The fake implements the OAuth endpoints including refresh token rotation, the most
+// common data endpoints backed by a seedable in-memory data model, subscriptions,
+// rate limit headers and the injection of errors.
+package fitbittest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"golang.org/x/oauth2"
+)
+
+// Default credentials of the fake OAuth client
+const (
+	DefaultClientID     = "fitbittest-client"
+	DefaultClientSecret = "fitbittest-secret"
+)
+
+// Server is a fake Fitbit Web API server
+type Server struct {
+	URL           string        // URL is the base URL of the server
+	ClientID      string        // ClientID of the accepted OAuth client
+	ClientSecret  string        // ClientSecret of the accepted OAuth client
+	TokenLifetime time.Duration // TokenLifetime is the lifetime of issued access tokens (default: 8h)
+
+	server *httptest.Server
+	routes []route
+
+	mutex         sync.Mutex
+	users         map[string]*User
+	defaultUser   string
+	accessTokens  map[string]issuedToken
+	refreshTokens map[string]string
+	codes         map[string]authorizationCode
+	subscriptions map[string][]fitbit.Subscription
+	faults        []*Fault
+	requests      []Request
+	nextLogID     int64
+
+	rateLimit   int
+	rateWindow  time.Duration
+	rateWindows map[string]*rateWindow
+}
+
+// rateWindow is the current rate limit window of a user
+type rateWindow struct {
+	remaining int
+	reset     time.Time
+}
+
+// Request is a request received by the server
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	UserID string // UserID is the user of the access token, empty for OAuth requests
+}
+
+// Fault is an injected error returned instead of the regular response
+type Fault struct {
+	Method     string // Method matches the request method, empty matches all methods
+	Path       string // Path is a prefix of the request path, empty matches all paths
+	StatusCode int    // StatusCode of the response, 429 also reports an exhausted rate limit
+	ErrorType  string // ErrorType of the returned error entry (default: system)
+	Message    string // Message of the returned error entry
+	Count      int    // Count is the number of failing requests, 0 fails one request and a negative value all requests
+}
+
+// issuedToken is an access token issued by the server
+type issuedToken struct {
+	userID string
+	expiry time.Time
+}
+
+// authorizationCode is an authorization code issued by the authorize endpoint or Authorize
+type authorizationCode struct {
+	userID    string
+	challenge string
+}
+
+// route is a single API endpoint
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+	handler func(w http.ResponseWriter, r *http.Request, u *User, params []string)
+}
+
+// NewServer starts a new fake server, it has to be closed using Close
+func NewServer() *Server {
+	s := &Server{
+		ClientID:      DefaultClientID,
+		ClientSecret:  DefaultClientSecret,
+		TokenLifetime: 8 * time.Hour,
+		users:         make(map[string]*User),
+		accessTokens:  make(map[string]issuedToken),
+		refreshTokens: make(map[string]string),
+		codes:         make(map[string]authorizationCode),
+		subscriptions: make(map[string][]fitbit.Subscription),
+		nextLogID:     1000,
+		rateLimit:     150,
+		rateWindow:    time.Hour,
+		rateWindows:   make(map[string]*rateWindow),
+	}
+	s.registerRoutes()
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client returns a HTTP client connected to the server
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Config returns a session config using the endpoints and client credentials of the server
+func (s *Server) Config() fitbit.Config {
+	return fitbit.Config{
+		ClientID:     s.ClientID,
+		ClientSecret: s.ClientSecret,
+		RedirectURL:  "http://localhost/callback",
+		APIURL:       s.URL,
+		AuthURL:      s.URL + "/oauth2/authorize",
+		TokenURL:     s.URL + "/oauth2/token",
+	}
+}
+
+// AddUser adds or replaces a user, the first added user is authorized by the authorize endpoint
+func (s *Server) AddUser(u User) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.defaultUser == "" {
+		s.defaultUser = u.ID
+	}
+	s.users[u.ID] = &u
+}
+
+// Update calls fn with the user of the given id to modify the seeded data
+func (s *Server) Update(userID string, fn func(u *User)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if u, ok := s.users[userID]; ok {
+		fn(u)
+	}
+}
+
+// Token issues a new token for the given user which can be passed to Session.SetToken
+func (s *Server) Token(userID string) *oauth2.Token {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.issueToken(userID)
+}
+
+// Authorize returns an authorization code for the given user which can be passed to Session.Exchange
+func (s *Server) Authorize(userID string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	code := randomString()
+	s.codes[code] = authorizationCode{userID: userID}
+	return code
+}
+
+// ExpireTokens expires all access tokens of the given user, following requests fail until the token is refreshed
+func (s *Server) ExpireTokens(userID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for access, token := range s.accessTokens {
+		if token.userID == userID {
+			token.expiry = time.Now().Add(-time.Second)
+			s.accessTokens[access] = token
+		}
+	}
+}
+
+// Inject adds a fault returned by matching requests, faults are matched in the order they were added
+func (s *Server) Inject(f Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// SetRateLimit sets the number of API requests allowed per user within window and resets the current windows
+// Requests exceeding the limit are answered with 429 Too Many Requests
+func (s *Server) SetRateLimit(limit int, window time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rateLimit = limit
+	s.rateWindow = window
+	s.rateWindows = make(map[string]*rateWindow)
+}
+
+// Requests returns all requests received by the server
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Subscriptions returns all subscriptions of the given user
+func (s *Server) Subscriptions(userID string) []fitbit.Subscription {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]fitbit.Subscription(nil), s.subscriptions[userID]...)
+}
+
+// issueToken creates a new token pair, the caller must hold the mutex
+func (s *Server) issueToken(userID string) *oauth2.Token {
+	access := randomString()
+	refresh := randomString()
+	expiry := time.Now().Add(s.TokenLifetime)
+	s.accessTokens[access] = issuedToken{userID: userID, expiry: expiry}
+	s.refreshTokens[refresh] = userID
+
+	token := &oauth2.Token{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		RefreshToken: refresh,
+		Expiry:       expiry,
+	}
+	return token.WithExtra(map[string]interface{}{"user_id": userID})
+}
+
+// serveHTTP dispatches a request to the OAuth endpoints or the API
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query()})
+
+	switch r.URL.Path {
+	case "/oauth2/authorize":
+		s.handleAuthorize(w, r)
+		return
+	case "/oauth2/token":
+		if s.fault(w, r) {
+			return
+		}
+		s.handleToken(w, r)
+		return
+	case "/oauth2/revoke":
+		if s.fault(w, r) {
+			return
+		}
+		s.handleRevoke(w, r)
+		return
+	}
+
+	u, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	s.requests[len(s.requests)-1].UserID = u.ID
+
+	if !s.consumeRateLimit(w, u.ID) {
+		return
+	}
+	if s.fault(w, r) {
+		return
+	}
+
+	for _, rt := range s.routes {
+		if rt.method != r.Method {
+			continue
+		}
+		params := rt.pattern.FindStringSubmatch(r.URL.Path)
+		if params == nil {
+			continue
+		}
+		// the user within the path is either the user of the token or "-"
+		if params[1] != "-" && params[1] != u.ID {
+			writeError(w, http.StatusForbidden, "insufficient_permissions", "access to other users is not allowed")
+			return
+		}
+		rt.handler(w, r, u, params[2:])
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "not_found", "the resource "+r.URL.Path+" does not exist")
+}
+
+// handleAuthorize redirects to the redirect URL with a new authorization code of the default user
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != s.ClientID {
+		writeError(w, http.StatusUnauthorized, "invalid_client", "unknown client id")
+		return
+	}
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.String() == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "missing redirect_uri")
+		return
+	}
+	if s.defaultUser == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "no user was added to the server")
+		return
+	}
+
+	code := randomString()
+	s.codes[code] = authorizationCode{userID: s.defaultUser, challenge: query.Get("code_challenge")}
+
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// handleToken exchanges authorization codes and refresh tokens, refresh tokens are rotated on every use
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticateClient(r) {
+		writeError(w, http.StatusUnauthorized, "invalid_client", "invalid client credentials")
+		return
+	}
+
+	var userID string
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		code, ok := s.codes[r.PostFormValue("code")]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid_grant", "authorization code invalid")
+			return
+		}
+		delete(s.codes, r.PostFormValue("code"))
+		if code.challenge != "" {
+			sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+			if base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+				writeError(w, http.StatusBadRequest, "invalid_grant", "code verifier invalid")
+				return
+			}
+		}
+		userID = code.userID
+	case "refresh_token":
+		var ok bool
+		userID, ok = s.refreshTokens[r.PostFormValue("refresh_token")]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid_grant", "refresh token invalid")
+			return
+		}
+		delete(s.refreshTokens, r.PostFormValue("refresh_token"))
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type", "grant type not supported")
+		return
+	}
+
+	token := s.issueToken(userID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  token.AccessToken,
+		"expires_in":    int(s.TokenLifetime.Seconds()),
+		"refresh_token": token.RefreshToken,
+		"scope":         "activity heartrate location nutrition profile settings sleep social weight",
+		"token_type":    "Bearer",
+		"user_id":       userID,
+	})
+}
+
+// handleRevoke removes the given access or refresh token and all other tokens of the user
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticateClient(r) {
+		writeError(w, http.StatusUnauthorized, "invalid_client", "invalid client credentials")
+		return
+	}
+
+	token := r.PostFormValue("token")
+	userID, ok := s.refreshTokens[token]
+	if !ok {
+		userID = s.accessTokens[token].userID
+	}
+	if userID != "" {
+		for access, issued := range s.accessTokens {
+			if issued.userID == userID {
+				delete(s.accessTokens, access)
+			}
+		}
+		for refresh, owner := range s.refreshTokens {
+			if owner == userID {
+				delete(s.refreshTokens, refresh)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// authenticateClient validates the client credentials sent using basic auth or within the form
+func (s *Server) authenticateClient(r *http.Request) bool {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostFormValue("client_id")
+		clientSecret = r.PostFormValue("client_secret")
+	}
+	return clientID == s.ClientID && clientSecret == s.ClientSecret
+}
+
+// authenticate returns the user of the bearer token of the request
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	access, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_client", "authorization header required")
+		return nil, false
+	}
+	token, ok := s.accessTokens[access]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_token", "access token invalid")
+		return nil, false
+	}
+	if time.Now().After(token.expiry) {
+		writeError(w, http.StatusUnauthorized, "expired_token", "access token expired")
+		return nil, false
+	}
+	u, ok := s.users[token.userID]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_token", "user of access token does not exist")
+		return nil, false
+	}
+	return u, true
+}
+
+// consumeRateLimit counts the request against the rate limit of the user and sets the rate limit headers
+// Like the Fitbit API the rate limit is tracked per user
+func (s *Server) consumeRateLimit(w http.ResponseWriter, userID string) bool {
+	now := time.Now()
+	window, ok := s.rateWindows[userID]
+	if !ok || now.After(window.reset) {
+		window = &rateWindow{remaining: s.rateLimit, reset: now.Add(s.rateWindow)}
+		s.rateWindows[userID] = window
+	}
+
+	if window.remaining <= 0 {
+		s.writeRateLimit(w, window, now)
+		writeError(w, http.StatusTooManyRequests, "system", "too many requests")
+		return false
+	}
+	window.remaining--
+	s.writeRateLimit(w, window, now)
+	return true
+}
+
+// writeRateLimit sets the rate limit headers of the response
+func (s *Server) writeRateLimit(w http.ResponseWriter, window *rateWindow, now time.Time) {
+	reset := strconv.Itoa(int(window.reset.Sub(now).Seconds()))
+	w.Header().Set("fitbit-rate-limit-limit", strconv.Itoa(s.rateLimit))
+	w.Header().Set("fitbit-rate-limit-remaining", strconv.Itoa(window.remaining))
+	w.Header().Set("fitbit-rate-limit-reset", reset)
+	if window.remaining <= 0 {
+		w.Header().Set("Retry-After", reset)
+	}
+}
+
+// fault writes the first matching injected fault and returns true if one matched
+func (s *Server) fault(w http.ResponseWriter, r *http.Request) bool {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+
+		switch {
+		case f.Count < 0:
+		case f.Count <= 1:
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		default:
+			f.Count--
+		}
+
+		if f.StatusCode == http.StatusTooManyRequests {
+			w.Header().Set("fitbit-rate-limit-limit", strconv.Itoa(s.rateLimit))
+			w.Header().Set("fitbit-rate-limit-remaining", "0")
+			w.Header().Set("fitbit-rate-limit-reset", "1")
+			w.Header().Set("Retry-After", "1")
+		}
+		errorType := f.ErrorType
+		if errorType == "" {
+			errorType = "system"
+		}
+		writeError(w, f.StatusCode, errorType, f.Message)
+		return true
+	}
+	return false
+}
+
+// writeJSON writes the value as JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	//nolint:errchkjson
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes an error response in the format of the Fitbit API
+func writeError(w http.ResponseWriter, status int, errorType string, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"errors": []fitbit.APIErrorEntry{{
+			ErrorType: errorType,
+			Message:   message,
+		}},
+		"success": false,
+	})
+}
+
+// randomString returns a random hex string used for tokens and codes
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,426 @@
+package fitbittest_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+	"golang.org/x/oauth2"
+)
+
+// vienna is the timezone of the seeded user
+var vienna, _ = time.LoadLocation("Europe/Vienna")
+
+// newServer returns a server with a single user with data on 2024-01-01
+func newServer(t *testing.T) *fitbittest.Server {
+	t.Helper()
+	server := fitbittest.NewServer()
+	t.Cleanup(server.Close)
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, vienna)
+	}
+	skin := -0.4
+	server.AddUser(fitbittest.User{
+		ID:          "ABC123",
+		DisplayName: "Jo",
+		FullName:    "Jo Doe",
+		Timezone:    "Europe/Vienna",
+		Days: map[string]*fitbittest.Day{
+			"2024-01-01": {
+				Steps:            8500,
+				Distance:         6.2,
+				RestingHeartRate: 58,
+				HeartRate:        []fitbittest.Sample{{Time: at(7, 0), Value: 61}, {Time: at(7, 1), Value: 64}},
+				HRV:              &fitbittest.HRV{DailyRmssd: 34.5, DeepRmssd: 31.2},
+				SpO2:             &fitbittest.SpO2{Avg: 95.4, Min: 92, Max: 98},
+				TemperatureCore:  []fitbittest.Sample{{Time: at(8, 0), Value: 36.8}},
+				TemperatureSkin:  &skin,
+				CaloriesIn:       1800,
+			},
+		},
+		Sleep: []fitbittest.Sleep{
+			{LogID: 1, Start: at(0, 15), End: at(6, 45), MinutesAsleep: 360, MinutesAwake: 30, Efficiency: 92, IsMainSleep: true},
+		},
+		Weight: []fitbittest.Weight{
+			{LogID: 2, Time: at(7, 30), Weight: 72.5, BMI: 22.4, Fat: 18.5},
+		},
+		Activities: []fitbittest.Activity{
+			{LogID: 3, ActivityTypeID: 90009, Name: "Run", Start: at(17, 0), Duration: 30 * time.Minute, Calories: 320, Steps: 4200, Distance: 5},
+		},
+		ECG: []fitbittest.ECG{
+			{Start: at(9, 14), AverageHeartRate: 68},
+		},
+		Friends: []fitbittest.Friend{
+			{ID: "FRND01", Name: "Max M.", Steps: 42000},
+		},
+	})
+	return server
+}
+
+// newSession returns a session authorized for the seeded user of the server
+func newSession(t *testing.T, server *fitbittest.Server) *fitbit.Session {
+	t.Helper()
+	session := fitbit.New(server.Config())
+	if _, err := session.Exchange(server.Authorize("ABC123")); err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	return session
+}
+
+// expired returns an expired copy of the token to force a refresh with the next request
+func expired(token *oauth2.Token) *oauth2.Token {
+	expired := *token
+	expired.Expiry = time.Now().Add(-time.Minute)
+	return &expired
+}
+
+func TestOAuthLogin(t *testing.T) {
+	server := newServer(t)
+	session := fitbit.New(server.Config())
+
+	loginURL, state, err := session.NewLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(loginURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if !strings.HasPrefix(location.String(), "http://localhost/callback?") || location.Query().Get("state") != state {
+		t.Fatalf("unexpected redirect %s", location)
+	}
+
+	token, err := session.CompleteLogin(state, location.Query().Get("code"))
+	if err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if userID, _ := token.Extra("user_id").(string); userID != "ABC123" {
+		t.Errorf("token of user %q returned", userID)
+	}
+
+	// the code is bound to the PKCE challenge of the login and can be used only once
+	if _, err := session.Exchange(location.Query().Get("code")); err == nil {
+		t.Error("code was accepted twice")
+	}
+}
+
+func TestOAuthRejectsInvalidClient(t *testing.T) {
+	server := newServer(t)
+	config := server.Config()
+	config.ClientSecret = "wrong"
+	session := fitbit.New(config)
+	if _, err := session.Exchange(server.Authorize("ABC123")); err == nil {
+		t.Error("token was issued for invalid client credentials")
+	}
+}
+
+func TestOAuthRefreshRotatesTokens(t *testing.T) {
+	server := newServer(t)
+	session := newSession(t, server)
+	old := session.Token()
+
+	// the server rejects the old access token, the session refreshes its expired copy
+	server.ExpireTokens("ABC123")
+	if _, err := session.Profile(0); err == nil {
+		t.Fatal("expired access token was accepted")
+	}
+	session.SetToken(expired(old))
+	if _, err := session.Profile(0); err != nil {
+		t.Fatalf("request after refresh: %v", err)
+	}
+
+	rotated := session.Token()
+	if rotated.AccessToken == old.AccessToken || rotated.RefreshToken == old.RefreshToken {
+		t.Fatal("token was not rotated")
+	}
+
+	// a refresh token can be used only once
+	session.SetToken(expired(old))
+	if _, err := session.Profile(0); !errors.Is(err, fitbit.ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for a reused refresh token, got %v", err)
+	}
+}
+
+func TestOAuthRevoke(t *testing.T) {
+	server := newServer(t)
+	session := newSession(t, server)
+	token := session.Token()
+
+	if err := session.Logout(); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	session.SetToken(token)
+	if _, err := session.Profile(0); !errors.Is(err, fitbit.ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for a revoked token, got %v", err)
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	server := newServer(t)
+	session := newSession(t, server)
+
+	tests := []struct {
+		name string
+		path string
+		call func() error
+	}{
+		{"profile", "/1/user/-/profile.json", func() error {
+			profile, err := session.Profile(0)
+			if err == nil && (profile.User.EncodedID != "ABC123" || profile.User.DisplayName != "Jo" || profile.User.Timezone != "Europe/Vienna") {
+				err = errors.New("unexpected profile")
+			}
+			return err
+		}},
+		{"heart", "/1/user/-/activities/heart/date/2024-01-01/1d.json", func() error {
+			heart, err := session.HeartLogByDay("2024-01-01")
+			if err == nil && (len(heart.ActivitiesHeart) != 1 || heart.ActivitiesHeart[0].Value.RestingHeartRate != 58) {
+				err = errors.New("unexpected heart rate")
+			}
+			return err
+		}},
+		{"heart intraday", "/1/user/-/activities/heart/date/2024-01-01/1d/1min/time/07:00/07:59.json", func() error {
+			heart, err := session.HeartIntraday("2024-01-01", "1min", "07:00", "07:59")
+			if err == nil && len(heart.ActivitiesHeartIntraday.Dataset) != 2 {
+				err = errors.New("unexpected intraday heart rate")
+			}
+			return err
+		}},
+		{"activity summary", "/1/user/-/activities/date/2024-01-01.json", func() error {
+			summary, err := session.ActivitiesDaySummary("2024-01-01")
+			if err == nil && summary.Summary.Steps != 8500 {
+				err = errors.New("unexpected steps")
+			}
+			return err
+		}},
+		{"activity intraday", "/1/user/-/activities/steps/date/2024-01-01/1d/1min.json", func() error {
+			steps, err := session.ActivitiesLogInterdayByDay("2024-01-01", "steps")
+			if err == nil && (len(steps.ActivitiesSteps) != 1 || steps.ActivitiesSteps[0].Value != "8500") {
+				err = errors.New("unexpected steps")
+			}
+			return err
+		}},
+		{"activity log", "/1/user/-/activities/list.json", func() error {
+			list, err := session.ActivityLog(fitbit.LogListParameters{BeforeDate: "2024-01-02", Limit: 10})
+			if err == nil && (len(list.Activities) != 1 || list.Activities[0].LogID != 3 || list.Activities[0].Steps != 4200) {
+				err = errors.New("unexpected activity log")
+			}
+			return err
+		}},
+		{"activity tcx", "/1/user/-/activities/3.tcx", func() error {
+			data, err := session.ActivityTCX(3)
+			if err != nil {
+				return err
+			}
+			tcx, err := fitbit.ReadTCX(data)
+			if err == nil && (tcx.Activities == nil || len(tcx.Activities.Activity) != 1) {
+				err = errors.New("unexpected TCX")
+			}
+			return err
+		}},
+		{"ecg log", "/1/user/-/ecg/list.json", func() error {
+			list, err := session.ECGLog(fitbit.LogListParameters{AfterDate: "2023-12-31", Limit: 10})
+			if err == nil && (len(list.EcgReadings) != 1 || list.EcgReadings[0].StartTime != "2024-01-01T09:14:00.000" ||
+				list.EcgReadings[0].ResultClassification != "Normal Sinus Rhythm") {
+				err = errors.New("unexpected ECG log")
+			}
+			return err
+		}},
+		{"sleep", "/1.2/user/-/sleep/date/2024-01-01.json", func() error {
+			sleep, err := session.SleepByDay("2024-01-01")
+			if err == nil && (len(sleep.Sleep) != 1 || sleep.Sleep[0].Efficiency != 92 || !sleep.Sleep[0].IsMainSleep) {
+				err = errors.New("unexpected sleep")
+			}
+			return err
+		}},
+		{"body weight", "/1/user/-/body/log/weight/date/2024-01-01.json", func() error {
+			weight, err := session.BodyWeightLogByDay("2024-01-01")
+			if err == nil && (len(weight.Weight) != 1 || weight.Weight[0].Weight != 72.5) {
+				err = errors.New("unexpected weight")
+			}
+			return err
+		}},
+		{"food log", "/1/user/-/foods/log/date/2024-01-01.json", func() error {
+			_, err := session.FoodLogByDay("2024-01-01")
+			return err
+		}},
+		{"water", "/1/user/-/foods/log/water.json", func() error {
+			if _, err := session.AddWater("2024-01-01", 250, "ml"); err != nil {
+				return err
+			}
+			water, err := session.WaterLogByDay("2024-01-01")
+			if err == nil && water.Summary.Water != 250 {
+				err = errors.New("unexpected water")
+			}
+			return err
+		}},
+		{"hrv", "/1/user/-/hrv/date/2024-01-01.json", func() error {
+			hrv, err := session.HRVSummaryByDate("2024-01-01")
+			if err == nil && (len(hrv.Hrv) != 1 || hrv.Hrv[0].Value.DailyRmssd != 34.5) {
+				err = errors.New("unexpected hrv")
+			}
+			return err
+		}},
+		{"spo2", "/1/user/-/spo2/date/2024-01-01.json", func() error {
+			spo2, err := session.SpO2ByDay("2024-01-01")
+			if err == nil && spo2.Value.Avg != 95.4 {
+				err = errors.New("unexpected spo2")
+			}
+			return err
+		}},
+		{"temperature", "/1/user/-/temp/core/date/2024-01-01.json", func() error {
+			temperature, err := session.TemperatureCoreByDay("2024-01-01")
+			if err == nil && (len(temperature.TempCore) != 1 || temperature.TempCore[0].Value != 36.8) {
+				err = errors.New("unexpected temperature")
+			}
+			return err
+		}},
+		{"subscriptions", "/1/user/-/activities/apiSubscriptions.json", func() error {
+			if _, err := session.AddSubscriptionByID("activities", "sub-1", ""); err != nil {
+				return err
+			}
+			_, list, err := session.GetSubscriptions("activities")
+			if err == nil && (len(list.APISubscriptions) != 1 || list.APISubscriptions[0].OwnerID != "ABC123") {
+				err = errors.New("unexpected subscriptions")
+			}
+			if err == nil && len(server.Subscriptions("ABC123")) != 1 {
+				err = errors.New("subscription was not stored")
+			}
+			return err
+		}},
+		{"friends", "/1.1/user/-/friends.json", func() error {
+			friends, err := session.GetFriends()
+			if err == nil && (len(friends.Data) != 1 || friends.Data[0].ID != "FRND01" || friends.Data[0].Attributes.Name != "Max M.") {
+				err = errors.New("unexpected friends")
+			}
+			return err
+		}},
+		{"leaderboard", "/1.1/user/-/leaderboard/friends.json", func() error {
+			leaderboard, err := session.GetFriendsLeaderboard()
+			if err == nil && (len(leaderboard.Data) != 2 || leaderboard.Data[0].ID != "FRND01" || leaderboard.Data[0].Attributes.StepRank != 1 ||
+				len(leaderboard.Included) != 2 || leaderboard.Included[1].Attributes.Name != "Jo") {
+				err = errors.New("unexpected leaderboard")
+			}
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := len(server.Requests())
+			if err := test.call(); err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, request := range server.Requests()[requests:] {
+				found = found || (request.Path == test.path && request.UserID == "ABC123")
+			}
+			if !found {
+				t.Errorf("no request to %s of user ABC123 received", test.path)
+			}
+		})
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	server := newServer(t)
+	server.SetRateLimit(5, time.Hour)
+	session := newSession(t, server)
+
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+	ratelimit := session.GetRatelimit()
+	if ratelimit.RateLimitAvailable != 5 || ratelimit.RateLimitUsed != 1 {
+		t.Errorf("rate limit %+v, expected a limit of 5 with 1 used", ratelimit)
+	}
+	if reset := time.Until(ratelimit.RateLimitReset); reset < 59*time.Minute || reset > time.Hour+time.Second {
+		t.Errorf("rate limit resets in %s, expected one hour", reset)
+	}
+}
+
+func TestRateLimitExceeded(t *testing.T) {
+	server := newServer(t)
+	server.SetRateLimit(1, time.Hour)
+	session := newSession(t, server)
+
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+	_, err := session.Profile(0)
+	if !errors.Is(err, fitbit.ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	var apiErr *fitbit.APIError
+	if !errors.As(err, &apiErr) || apiErr.Ratelimit.RateLimitUsed != apiErr.Ratelimit.RateLimitAvailable || apiErr.Ratelimit.RateLimitReset.IsZero() {
+		t.Errorf("rate limit of the error is not set: %+v", apiErr)
+	}
+}
+
+func TestInjectedRateLimitIsRetried(t *testing.T) {
+	server := newServer(t)
+	session := newSession(t, server)
+	session.SetRetryPolicy(fitbit.RetryPolicy{MaxAttempts: 2, MaxWait: 5 * time.Second})
+
+	server.Inject(fitbittest.Fault{Path: "/1/user/-/profile.json", StatusCode: http.StatusTooManyRequests})
+	if _, err := session.Profile(0); err != nil {
+		t.Fatalf("request was not retried after the rate limit reset: %v", err)
+	}
+	profiles := 0
+	for _, request := range server.Requests() {
+		if request.Path == "/1/user/-/profile.json" {
+			profiles++
+		}
+	}
+	if profiles != 2 {
+		t.Errorf("%d requests sent, expected 2", profiles)
+	}
+}
+
+func TestInjectedFault(t *testing.T) {
+	server := newServer(t)
+	session := newSession(t, server)
+
+	server.Inject(fitbittest.Fault{Path: "/1.2/user/-/sleep", StatusCode: http.StatusNotFound, ErrorType: "not_found", Message: "no sleep", Count: 2})
+	for i := 0; i < 2; i++ {
+		_, err := session.SleepByDay("2024-01-01")
+		var apiErr *fitbit.APIError
+		if !errors.Is(err, fitbit.ErrNotFound) || !errors.As(err, &apiErr) || apiErr.Errors[0].Message != "no sleep" {
+			t.Fatalf("request %d: expected the injected error, got %v", i, err)
+		}
+	}
+	// other endpoints are not affected and the fault is used up after Count requests
+	if _, err := session.Profile(0); err != nil {
+		t.Errorf("profile: %v", err)
+	}
+	if _, err := session.SleepByDay("2024-01-01"); err != nil {
+		t.Errorf("sleep after the fault: %v", err)
+	}
+}
+
+func TestTokenForSetToken(t *testing.T) {
+	server := newServer(t)
+	session := fitbit.New(server.Config())
+	session.SetToken(server.Token("ABC123"))
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+
+	session.SetToken(&oauth2.Token{AccessToken: "unknown", Expiry: time.Now().Add(time.Hour)})
+	if _, err := session.Profile(0); !errors.Is(err, fitbit.ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for an unknown token, got %v", err)
+	}
+}
@@ -1,20 +1,25 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 )
 
 // FoodFavorites returns favorite food
 // TODO: not tested, seems to be not implemented within the app
 func (m *Session) FoodFavorites() (FoodCollectionList, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/foods/log/favorite.json")
+	return m.FoodFavoritesContext(context.Background())
+}
+
+// FoodFavoritesContext is like FoodFavorites but uses ctx for the request
+func (m *Session) FoodFavoritesContext(ctx context.Context) (FoodCollectionList, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/foods/log/favorite.json")
 	if err != nil {
 		return FoodCollectionList{}, err
 	}
 
 	favs := FoodCollectionList{}
-	if err := json.Unmarshal(contents, &favs); err != nil {
+	if err := m.decode("FoodFavorites", contents, &favs); err != nil {
 		return FoodCollectionList{}, err
 	}
 
@@ -92,13 +97,18 @@ type FoodCollectionList []struct {
 
 // FoodFrequent returns frequently eaten food
 func (m *Session) FoodFrequent() (FoodCollectionList, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/foods/log/frequent.json")
+	return m.FoodFrequentContext(context.Background())
+}
+
+// FoodFrequentContext is like FoodFrequent but uses ctx for the request
+func (m *Session) FoodFrequentContext(ctx context.Context) (FoodCollectionList, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/foods/log/frequent.json")
 	if err != nil {
 		return FoodCollectionList{}, err
 	}
 
 	frequent := FoodCollectionList{}
-	if err := json.Unmarshal(contents, &frequent); err != nil {
+	if err := m.decode("FoodFrequent", contents, &frequent); err != nil {
 		return FoodCollectionList{}, err
 	}
 
@@ -107,13 +117,18 @@ func (m *Session) FoodFrequent() (FoodCollectionList, error) {
 
 // FoodRecent returns recently eaten food
 func (m *Session) FoodRecent() (FoodCollectionList, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/foods/recent.json")
+	return m.FoodRecentContext(context.Background())
+}
+
+// FoodRecentContext is like FoodRecent but uses ctx for the request
+func (m *Session) FoodRecentContext(ctx context.Context) (FoodCollectionList, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/foods/recent.json")
 	if err != nil {
 		return FoodCollectionList{}, err
 	}
 
 	favs := FoodCollectionList{}
-	if err := json.Unmarshal(contents, &favs); err != nil {
+	if err := m.decode("FoodRecent", contents, &favs); err != nil {
 		return FoodCollectionList{}, err
 	}
 
@@ -122,7 +137,12 @@ func (m *Session) FoodRecent() (FoodCollectionList, error) {
 
 // AddFoodFavorite adds food by id to favorites
 func (m *Session) AddFoodFavorite(id uint64) error {
-	_, err := m.makePOSTRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/favorite/%d.json", id), map[string]string{})
+	return m.AddFoodFavoriteContext(context.Background(), id)
+}
+
+// AddFoodFavoriteContext is like AddFoodFavorite but uses ctx for the request
+func (m *Session) AddFoodFavoriteContext(ctx context.Context, id uint64) error {
+	_, err := m.makePOSTRequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/favorite/%d.json", m.apiURL, id), map[string]string{})
 	if err != nil {
 		return err
 	}
@@ -132,7 +152,12 @@ func (m *Session) AddFoodFavorite(id uint64) error {
 
 // RemoveFoodFavorite removes food by id from facorites
 func (m *Session) RemoveFoodFavorite(id uint64) error {
-	_, err := m.makeDELETERequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/favorite/%d.json", id))
+	return m.RemoveFoodFavoriteContext(context.Background(), id)
+}
+
+// RemoveFoodFavoriteContext is like RemoveFoodFavorite but uses ctx for the request
+func (m *Session) RemoveFoodFavoriteContext(ctx context.Context, id uint64) error {
+	_, err := m.makeDELETERequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/favorite/%d.json", m.apiURL, id))
 	if err != nil {
 		return err
 	}
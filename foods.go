@@ -1,11 +1,12 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
+	"time"
 )
 
 // FoodGoal contains the food goal of a user
@@ -22,13 +23,18 @@ type FoodGoal struct {
 
 // FoodGoal requests the food goal of the user
 func (m *Session) FoodGoal() (FoodGoal, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/foods/log/goal.json")
+	return m.FoodGoalContext(context.Background())
+}
+
+// FoodGoalContext is like FoodGoal but uses ctx for the request
+func (m *Session) FoodGoalContext(ctx context.Context) (FoodGoal, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/foods/log/goal.json")
 	if err != nil {
 		return FoodGoal{}, err
 	}
 
 	foods := FoodGoal{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("FoodGoal", contents, &foods); err != nil {
 		return FoodGoal{}, err
 	}
 
@@ -37,13 +43,18 @@ func (m *Session) FoodGoal() (FoodGoal, error) {
 
 // SetFoodGoal sets the users food goal
 func (m *Session) SetFoodGoal(goals map[string]string) (FoodGoal, error) {
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1/user/-/foods/log/goal.json", goals)
+	return m.SetFoodGoalContext(context.Background(), goals)
+}
+
+// SetFoodGoalContext is like SetFoodGoal but uses ctx for the request
+func (m *Session) SetFoodGoalContext(ctx context.Context, goals map[string]string) (FoodGoal, error) {
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1/user/-/foods/log/goal.json", goals)
 	if err != nil {
 		return FoodGoal{}, err
 	}
 
 	foodgoal := FoodGoal{}
-	if err := json.Unmarshal(contents, &foodgoal); err != nil {
+	if err := m.decode("SetFoodGoal", contents, &foodgoal); err != nil {
 		return FoodGoal{}, err
 	}
 
@@ -98,21 +109,44 @@ type FoodLog struct {
 }
 
 // FoodLogByDay returns the food log by a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) FoodLogByDay(day string) (FoodLog, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/date/%s.json", day))
+	return m.FoodLogByDayContext(context.Background(), day)
+}
+
+// FoodLogByDayContext is like FoodLogByDay but uses ctx for the request
+func (m *Session) FoodLogByDayContext(ctx context.Context, day string) (FoodLog, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return FoodLog{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return FoodLog{}, err
 	}
 
 	foods := FoodLog{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("FoodLogByDay", contents, &foods); err != nil {
 		return FoodLog{}, err
 	}
 
 	return foods, nil
 }
 
+// FoodLogOn is like FoodLogByDay but takes a Date
+func (m *Session) FoodLogOn(day Date) (FoodLog, error) {
+	return m.FoodLogOnContext(context.Background(), day)
+}
+
+// FoodLogOnContext is like FoodLogOn but uses ctx for the request
+func (m *Session) FoodLogOnContext(ctx context.Context, day Date) (FoodLog, error) {
+	if err := checkDates(day); err != nil {
+		return FoodLog{}, err
+	}
+	return m.FoodLogByDayContext(ctx, day.String())
+}
+
 // FoodWaterLogDateRange contains a summary of calories or water for a given date range
 type FoodWaterLogDateRange struct {
 	FoodsLogCaloriesIn []struct {
@@ -127,14 +161,43 @@ type FoodWaterLogDateRange struct {
 
 // FoodLogByDateRange returns the calories log of a given time range by date
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysFoodWater days are split into multiple requests
 func (m *Session) FoodLogByDateRange(startDay string, endDay string) (FoodWaterLogDateRange, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/caloriesIn/date/%s/%s.json", startDay, endDay))
+	return m.FoodLogByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// FoodLogByDateRangeContext is like FoodLogByDateRange but uses ctx for the request
+func (m *Session) FoodLogByDateRangeContext(ctx context.Context, startDay string, endDay string) (FoodWaterLogDateRange, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return FoodWaterLogDateRange{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysFoodWater, m.foodLogByDateRange)
+}
+
+// FoodLogBetween is like FoodLogByDateRange but takes Dates
+func (m *Session) FoodLogBetween(startDay Date, endDay Date) (FoodWaterLogDateRange, error) {
+	return m.FoodLogBetweenContext(context.Background(), startDay, endDay)
+}
+
+// FoodLogBetweenContext is like FoodLogBetween but uses ctx for the request
+func (m *Session) FoodLogBetweenContext(ctx context.Context, startDay Date, endDay Date) (FoodWaterLogDateRange, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return FoodWaterLogDateRange{}, err
+	}
+	return m.FoodLogByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// foodLogByDateRange requests a single date range
+func (m *Session) foodLogByDateRange(ctx context.Context, startDay string, endDay string) (FoodWaterLogDateRange, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/caloriesIn/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return FoodWaterLogDateRange{}, err
 	}
 
 	foods := FoodWaterLogDateRange{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("FoodLogByDateRange", contents, &foods); err != nil {
 		return FoodWaterLogDateRange{}, err
 	}
 
@@ -143,14 +206,43 @@ func (m *Session) FoodLogByDateRange(startDay string, endDay string) (FoodWaterL
 
 // WaterLogByDateRange returns the calories log of a given time range by date
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysFoodWater days are split into multiple requests
 func (m *Session) WaterLogByDateRange(startDay string, endDay string) (FoodWaterLogDateRange, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/water/date/%s/%s.json", startDay, endDay))
+	return m.WaterLogByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// WaterLogByDateRangeContext is like WaterLogByDateRange but uses ctx for the request
+func (m *Session) WaterLogByDateRangeContext(ctx context.Context, startDay string, endDay string) (FoodWaterLogDateRange, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return FoodWaterLogDateRange{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysFoodWater, m.waterLogByDateRange)
+}
+
+// WaterLogBetween is like WaterLogByDateRange but takes Dates
+func (m *Session) WaterLogBetween(startDay Date, endDay Date) (FoodWaterLogDateRange, error) {
+	return m.WaterLogBetweenContext(context.Background(), startDay, endDay)
+}
+
+// WaterLogBetweenContext is like WaterLogBetween but uses ctx for the request
+func (m *Session) WaterLogBetweenContext(ctx context.Context, startDay Date, endDay Date) (FoodWaterLogDateRange, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return FoodWaterLogDateRange{}, err
+	}
+	return m.WaterLogByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// waterLogByDateRange requests a single date range
+func (m *Session) waterLogByDateRange(ctx context.Context, startDay string, endDay string) (FoodWaterLogDateRange, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/water/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return FoodWaterLogDateRange{}, err
 	}
 
 	foods := FoodWaterLogDateRange{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("WaterLogByDateRange", contents, &foods); err != nil {
 		return FoodWaterLogDateRange{}, err
 	}
 
@@ -169,21 +261,44 @@ type WaterLog struct {
 }
 
 // WaterLogByDay returns the water log by a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) WaterLogByDay(day string) (WaterLog, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/water/date/%s.json", day))
+	return m.WaterLogByDayContext(context.Background(), day)
+}
+
+// WaterLogByDayContext is like WaterLogByDay but uses ctx for the request
+func (m *Session) WaterLogByDayContext(ctx context.Context, day string) (WaterLog, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return WaterLog{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/water/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return WaterLog{}, err
 	}
 
 	water := WaterLog{}
-	if err := json.Unmarshal(contents, &water); err != nil {
+	if err := m.decode("WaterLogByDay", contents, &water); err != nil {
 		return WaterLog{}, err
 	}
 
 	return water, nil
 }
 
+// WaterLogOn is like WaterLogByDay but takes a Date
+func (m *Session) WaterLogOn(day Date) (WaterLog, error) {
+	return m.WaterLogOnContext(context.Background(), day)
+}
+
+// WaterLogOnContext is like WaterLogOn but uses ctx for the request
+func (m *Session) WaterLogOnContext(ctx context.Context, day Date) (WaterLog, error) {
+	if err := checkDates(day); err != nil {
+		return WaterLog{}, err
+	}
+	return m.WaterLogByDayContext(ctx, day.String())
+}
+
 // WaterGoal describes the water goal of the user
 type WaterGoal struct {
 	Goal struct {
@@ -194,13 +309,18 @@ type WaterGoal struct {
 
 // WaterGoal get's the users water goal
 func (m *Session) WaterGoal() (WaterGoal, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/user/-/foods/log/water/goal.json")
+	return m.WaterGoalContext(context.Background())
+}
+
+// WaterGoalContext is like WaterGoal but uses ctx for the request
+func (m *Session) WaterGoalContext(ctx context.Context) (WaterGoal, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/user/-/foods/log/water/goal.json")
 	if err != nil {
 		return WaterGoal{}, err
 	}
 
 	water := WaterGoal{}
-	if err := json.Unmarshal(contents, &water); err != nil {
+	if err := m.decode("WaterGoal", contents, &water); err != nil {
 		return WaterGoal{}, err
 	}
 
@@ -209,13 +329,18 @@ func (m *Session) WaterGoal() (WaterGoal, error) {
 
 // SetWaterGoal sets a new water goal
 func (m *Session) SetWaterGoal(goal float64) (WaterGoal, error) {
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1/user/-/foods/log/water/goal.json", map[string]string{"target": strconv.FormatFloat(goal, 'E', -1, 64)})
+	return m.SetWaterGoalContext(context.Background(), goal)
+}
+
+// SetWaterGoalContext is like SetWaterGoal but uses ctx for the request
+func (m *Session) SetWaterGoalContext(ctx context.Context, goal float64) (WaterGoal, error) {
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1/user/-/foods/log/water/goal.json", map[string]string{"target": strconv.FormatFloat(goal, 'E', -1, 64)})
 	if err != nil {
 		return WaterGoal{}, err
 	}
 
 	water := WaterGoal{}
-	if err := json.Unmarshal(contents, &water); err != nil {
+	if err := m.decode("SetWaterGoal", contents, &water); err != nil {
 		return WaterGoal{}, err
 	}
 
@@ -227,9 +352,17 @@ func (m *Session) SetWaterGoal(goal float64) (WaterGoal, error) {
 // amount contains the amount of water consumed
 // unit can be ml, fl oz or cup
 func (m *Session) AddWater(date string, amount float64, unit string) (WaterLog, error) {
+	return m.AddWaterContext(context.Background(), date, amount, unit)
+}
+
+// AddWaterContext is like AddWater but uses ctx for the request
+func (m *Session) AddWaterContext(ctx context.Context, date string, amount float64, unit string) (WaterLog, error) {
 	if date == "" {
 		return WaterLog{}, errors.New("date must be defined")
 	}
+	if err := checkPostDate(date); err != nil {
+		return WaterLog{}, err
+	}
 	if amount <= 0 {
 		return WaterLog{}, errors.New("amount must me greater than 0")
 	}
@@ -237,7 +370,7 @@ func (m *Session) AddWater(date string, amount float64, unit string) (WaterLog,
 		return WaterLog{}, errors.New("unit must be ml, fl oz or cup")
 	}
 
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1/user/-/foods/log/water.json", map[string]string{
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1/user/-/foods/log/water.json", map[string]string{
 		"amount": strconv.FormatFloat(amount, 'f', 1, 64),
 		"date":   date,
 		"unit":   unit,
@@ -247,17 +380,35 @@ func (m *Session) AddWater(date string, amount float64, unit string) (WaterLog,
 	}
 
 	water := WaterLog{}
-	if err := json.Unmarshal(contents, &water); err != nil {
+	if err := m.decode("AddWater", contents, &water); err != nil {
 		return WaterLog{}, err
 	}
 
 	return water, nil
 }
 
+// AddWaterOn is like AddWater but takes a Date
+func (m *Session) AddWaterOn(date Date, amount float64, unit string) (WaterLog, error) {
+	return m.AddWaterOnContext(context.Background(), date, amount, unit)
+}
+
+// AddWaterOnContext is like AddWaterOn but uses ctx for the request
+func (m *Session) AddWaterOnContext(ctx context.Context, date Date, amount float64, unit string) (WaterLog, error) {
+	if err := checkDates(date); err != nil {
+		return WaterLog{}, err
+	}
+	return m.AddWaterContext(ctx, date.String(), amount, unit)
+}
+
 // UpdateWater updates an existing water log entry with new values
 // amount contains the amount of water consumed
 // unit can be ml, fl oz or cup
 func (m *Session) UpdateWater(id uint64, amount float64, unit string) (WaterLog, error) {
+	return m.UpdateWaterContext(context.Background(), id, amount, unit)
+}
+
+// UpdateWaterContext is like UpdateWater but uses ctx for the request
+func (m *Session) UpdateWaterContext(ctx context.Context, id uint64, amount float64, unit string) (WaterLog, error) {
 	if id == 0 {
 		return WaterLog{}, errors.New("id must be defined")
 	}
@@ -268,7 +419,7 @@ func (m *Session) UpdateWater(id uint64, amount float64, unit string) (WaterLog,
 		return WaterLog{}, errors.New("unit must be ml, fl oz or cup")
 	}
 
-	contents, err := m.makePOSTRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/water/%d.json", id), map[string]string{
+	contents, err := m.makePOSTRequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/water/%d.json", m.apiURL, id), map[string]string{
 		"amount": strconv.FormatFloat(amount, 'f', 1, 64),
 		"unit":   unit,
 	})
@@ -277,7 +428,7 @@ func (m *Session) UpdateWater(id uint64, amount float64, unit string) (WaterLog,
 	}
 
 	water := WaterLog{}
-	if err := json.Unmarshal(contents, &water); err != nil {
+	if err := m.decode("UpdateWater", contents, &water); err != nil {
 		return WaterLog{}, err
 	}
 
@@ -286,11 +437,16 @@ func (m *Session) UpdateWater(id uint64, amount float64, unit string) (WaterLog,
 
 // RemoveWater removes an existing water log entry
 func (m *Session) RemoveWater(id uint64) error {
+	return m.RemoveWaterContext(context.Background(), id)
+}
+
+// RemoveWaterContext is like RemoveWater but uses ctx for the request
+func (m *Session) RemoveWaterContext(ctx context.Context, id uint64) error {
 	if id == 0 {
 		return errors.New("id must be defined")
 	}
 
-	_, err := m.makeDELETERequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/water/%d.json", id))
+	_, err := m.makeDELETERequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/water/%d.json", m.apiURL, id))
 	if err != nil {
 		return err
 	}
@@ -308,13 +464,18 @@ type FoodLocales []struct {
 
 // FoodLocales returns a list of supported food locales
 func (m *Session) FoodLocales() (FoodLocales, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/foods/locales.json")
+	return m.FoodLocalesContext(context.Background())
+}
+
+// FoodLocalesContext is like FoodLocales but uses ctx for the request
+func (m *Session) FoodLocalesContext(ctx context.Context) (FoodLocales, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/foods/locales.json")
 	if err != nil {
 		return FoodLocales{}, err
 	}
 
 	foods := FoodLocales{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("FoodLocales", contents, &foods); err != nil {
 		return FoodLocales{}, err
 	}
 
@@ -343,13 +504,18 @@ type FoodSearchResult struct {
 
 // FoodSearch searches for food within the fitbit database for matching food
 func (m *Session) FoodSearch(value string) (FoodSearchResult, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/foods/search.json?query=" + url.QueryEscape(value))
+	return m.FoodSearchContext(context.Background(), value)
+}
+
+// FoodSearchContext is like FoodSearch but uses ctx for the request
+func (m *Session) FoodSearchContext(ctx context.Context, value string) (FoodSearchResult, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/foods/search.json?query="+url.QueryEscape(value))
 	if err != nil {
 		return FoodSearchResult{}, err
 	}
 
 	foods := FoodSearchResult{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("FoodSearch", contents, &foods); err != nil {
 		return FoodSearchResult{}, err
 	}
 
@@ -358,13 +524,18 @@ func (m *Session) FoodSearch(value string) (FoodSearchResult, error) {
 
 // FoodByID returns a food by its id
 func (m *Session) FoodByID(id uint64) (FoodEntry, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/foods/%d.json", id))
+	return m.FoodByIDContext(context.Background(), id)
+}
+
+// FoodByIDContext is like FoodByID but uses ctx for the request
+func (m *Session) FoodByIDContext(ctx context.Context, id uint64) (FoodEntry, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/foods/%d.json", m.apiURL, id))
 	if err != nil {
 		return FoodEntry{}, err
 	}
 
 	foods := FoodEntry{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("FoodByID", contents, &foods); err != nil {
 		return FoodEntry{}, err
 	}
 
@@ -403,13 +574,18 @@ type FoodEntry struct {
 
 // FoodUnits contains a list of supported food units
 func (m *Session) FoodUnits() (FoodUnits, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1/foods/units.json")
+	return m.FoodUnitsContext(context.Background())
+}
+
+// FoodUnitsContext is like FoodUnits but uses ctx for the request
+func (m *Session) FoodUnitsContext(ctx context.Context) (FoodUnits, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1/foods/units.json")
 	if err != nil {
 		return FoodUnits{}, err
 	}
 
 	foods := FoodUnits{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("FoodUnits", contents, &foods); err != nil {
 		return FoodUnits{}, err
 	}
 
@@ -425,6 +601,11 @@ type FoodUnits []struct {
 
 // AddFood logs a new consumed food entry
 func (m *Session) AddFood(data NewFoodLog) (AddFoodLogResponse, error) {
+	return m.AddFoodContext(context.Background(), data)
+}
+
+// AddFoodContext is like AddFood but uses ctx for the request
+func (m *Session) AddFoodContext(ctx context.Context, data NewFoodLog) (AddFoodLogResponse, error) {
 	dataToPost := make(map[string]string)
 	if data.MealTypeID < 1 || data.MealTypeID > 7 {
 		return AddFoodLogResponse{}, errors.New("mealTypeID must be given and between 1 and 7")
@@ -462,13 +643,13 @@ func (m *Session) AddFood(data NewFoodLog) (AddFoodLogResponse, error) {
 		return AddFoodLogResponse{}, errors.New("either foodId or foodName must be given")
 	}
 
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1/user/-/foods/log.json", dataToPost)
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1/user/-/foods/log.json", dataToPost)
 	if err != nil {
 		return AddFoodLogResponse{}, err
 	}
 
 	foods := AddFoodLogResponse{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("AddFood", contents, &foods); err != nil {
 		return AddFoodLogResponse{}, err
 	}
 
@@ -477,6 +658,11 @@ func (m *Session) AddFood(data NewFoodLog) (AddFoodLogResponse, error) {
 
 // UpdateFood changes a stored food log entry
 func (m *Session) UpdateFood(id uint64, data NewFoodLog) (AddFoodLogResponse, error) {
+	return m.UpdateFoodContext(context.Background(), id, data)
+}
+
+// UpdateFoodContext is like UpdateFood but uses ctx for the request
+func (m *Session) UpdateFoodContext(ctx context.Context, id uint64, data NewFoodLog) (AddFoodLogResponse, error) {
 	dataToPost := make(map[string]string)
 	if data.MealTypeID < 1 || data.MealTypeID > 7 {
 		return AddFoodLogResponse{}, errors.New("mealTypeID must be given and between 1 and 7")
@@ -493,13 +679,13 @@ func (m *Session) UpdateFood(id uint64, data NewFoodLog) (AddFoodLogResponse, er
 		dataToPost["calories"] = strconv.FormatUint(data.Calories, 10)
 	}
 
-	contents, err := m.makePOSTRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/%d.json", id), dataToPost)
+	contents, err := m.makePOSTRequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/%d.json", m.apiURL, id), dataToPost)
 	if err != nil {
 		return AddFoodLogResponse{}, err
 	}
 
 	foods := AddFoodLogResponse{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("UpdateFood", contents, &foods); err != nil {
 		return AddFoodLogResponse{}, err
 	}
 
@@ -508,11 +694,16 @@ func (m *Session) UpdateFood(id uint64, data NewFoodLog) (AddFoodLogResponse, er
 
 // RemoveFood removes an existing food log entry
 func (m *Session) RemoveFood(id uint64) error {
+	return m.RemoveFoodContext(context.Background(), id)
+}
+
+// RemoveFoodContext is like RemoveFood but uses ctx for the request
+func (m *Session) RemoveFoodContext(ctx context.Context, id uint64) error {
 	if id == 0 {
 		return errors.New("id must be defined")
 	}
 
-	_, err := m.makeDELETERequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/foods/log/%d.json", id))
+	_, err := m.makeDELETERequest(ctx, fmt.Sprintf("%s/1/user/-/foods/log/%d.json", m.apiURL, id))
 	if err != nil {
 		return err
 	}
@@ -578,3 +769,20 @@ type AddFoodLogResponse struct {
 		} `json:"nutritionalValues"`
 	} `json:"foodLog"`
 }
+
+// Series returns the calories in and the water consumption as normalized time series
+// loc is the timezone of the user, units the unit system of the session used to request the log
+func (f FoodWaterLogDateRange) Series(loc *time.Location, units UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, entry := range f.FoodsLogCaloriesIn {
+		if err := b.addString(MetricCaloriesIn, false, entry.DateTime, entry.Value, UnitKilocalories); err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range f.FoodsLogWater {
+		if err := b.addString(MetricWater, false, entry.DateTime, entry.Value, units.liquid()); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
@@ -1,7 +1,7 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strconv"
 )
@@ -25,13 +25,18 @@ type Friend struct {
 
 // GetFriends returns the current friends of the current user
 func (m *Session) GetFriends() (FriendsList, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1.1/user/-/friends.json")
+	return m.GetFriendsContext(context.Background())
+}
+
+// GetFriendsContext is like GetFriends but uses ctx for the request
+func (m *Session) GetFriendsContext(ctx context.Context) (FriendsList, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1.1/user/-/friends.json")
 	if err != nil {
 		return FriendsList{}, err
 	}
 
 	friends := FriendsList{}
-	if err := json.Unmarshal(contents, &friends); err != nil {
+	if err := m.decode("GetFriends", contents, &friends); err != nil {
 		return FriendsList{}, err
 	}
 
@@ -70,13 +75,18 @@ type FriendsLeaderboard struct {
 
 // GetFriendsLeaderboard returns the leaderbord including the user
 func (m *Session) GetFriendsLeaderboard() (FriendsLeaderboard, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1.1/user/-/leaderboard/friends.json")
+	return m.GetFriendsLeaderboardContext(context.Background())
+}
+
+// GetFriendsLeaderboardContext is like GetFriendsLeaderboard but uses ctx for the request
+func (m *Session) GetFriendsLeaderboardContext(ctx context.Context) (FriendsLeaderboard, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1.1/user/-/leaderboard/friends.json")
 	if err != nil {
 		return FriendsLeaderboard{}, err
 	}
 
 	friends := FriendsLeaderboard{}
-	if err := json.Unmarshal(contents, &friends); err != nil {
+	if err := m.decode("GetFriendsLeaderboard", contents, &friends); err != nil {
 		return FriendsLeaderboard{}, err
 	}
 
@@ -86,11 +96,16 @@ func (m *Session) GetFriendsLeaderboard() (FriendsLeaderboard, error) {
 // FriendInviteByEmail invites another user to be friends by email
 // FIXME: untested function, unknown response from server
 func (m *Session) FriendInviteByEmail(value string) ([]byte, error) {
+	return m.FriendInviteByEmailContext(context.Background(), value)
+}
+
+// FriendInviteByEmailContext is like FriendInviteByEmail but uses ctx for the request
+func (m *Session) FriendInviteByEmailContext(ctx context.Context, value string) ([]byte, error) {
 	data := map[string]string{
 		"invitedUserEmail": value,
 	}
 
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1.1/user/-/friends/invitations", data)
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1.1/user/-/friends/invitations", data)
 	if err != nil {
 		return nil, err
 	}
@@ -101,11 +116,16 @@ func (m *Session) FriendInviteByEmail(value string) ([]byte, error) {
 // FriendInviteByUserID invites another user to be friends by user id
 // FIXME: untested function, unknown response from server
 func (m *Session) FriendInviteByUserID(value string) ([]byte, error) {
+	return m.FriendInviteByUserIDContext(context.Background(), value)
+}
+
+// FriendInviteByUserIDContext is like FriendInviteByUserID but uses ctx for the request
+func (m *Session) FriendInviteByUserIDContext(ctx context.Context, value string) ([]byte, error) {
 	data := map[string]string{
 		"invitedUserId": value,
 	}
 
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1.1/user/-/friends/invitations", data)
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1.1/user/-/friends/invitations", data)
 	if err != nil {
 		return nil, err
 	}
@@ -146,13 +166,18 @@ type FriendsInvitations struct {
 
 // GetFriendInvitations returns a list of open friend invitations
 func (m *Session) GetFriendInvitations() (FriendsInvitations, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1.1/user/-/friends/invitations.json")
+	return m.GetFriendInvitationsContext(context.Background())
+}
+
+// GetFriendInvitationsContext is like GetFriendInvitations but uses ctx for the request
+func (m *Session) GetFriendInvitationsContext(ctx context.Context) (FriendsInvitations, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1.1/user/-/friends/invitations.json")
 	if err != nil {
 		return FriendsInvitations{}, err
 	}
 
 	friends := FriendsInvitations{}
-	if err := json.Unmarshal(contents, &friends); err != nil {
+	if err := m.decode("GetFriendInvitations", contents, &friends); err != nil {
 		return FriendsInvitations{}, err
 	}
 
@@ -162,11 +187,16 @@ func (m *Session) GetFriendInvitations() (FriendsInvitations, error) {
 // FriendsRespondToInvition reacts to a inviation
 // FIXME: untested function, unknown response from server
 func (m *Session) FriendsRespondToInvition(userID string, accept bool) ([]byte, error) {
+	return m.FriendsRespondToInvitionContext(context.Background(), userID, accept)
+}
+
+// FriendsRespondToInvitionContext is like FriendsRespondToInvition but uses ctx for the request
+func (m *Session) FriendsRespondToInvitionContext(ctx context.Context, userID string, accept bool) ([]byte, error) {
 	data := map[string]string{
 		"accept": strconv.FormatBool(accept),
 	}
 
-	contents, err := m.makePOSTRequest(fmt.Sprintf("https://api.fitbit.com/1.1/user/-/friends/invitations/%s", userID), data)
+	contents, err := m.makePOSTRequest(ctx, fmt.Sprintf("%s/1.1/user/-/friends/invitations/%s", m.apiURL, userID), data)
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,5 @@
 module github.com/Thomas2500/go-fitbit
 
-go 1.19
+go 1.23.0
 
 require golang.org/x/oauth2 v0.29.0
-
-require github.com/google/uuid v1.6.0
@@ -0,0 +1,215 @@
+package fitbit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"time"
+)
+
+// Namespaces used within GPX files
+const (
+	GPXNamespace                         = "http://www.topografix.com/GPX/1/1"
+	GPXTrackPointExtensionNamespace      = "http://www.garmin.com/xmlschemas/TrackPointExtension/v1"
+	gpxSchemaLocation                    = GPXNamespace + " http://www.topografix.com/GPX/1/1/gpx.xsd " + GPXTrackPointExtensionNamespace + " " + gpxTrackPointExtensionSchemaLocation
+	gpxTrackPointExtensionSchemaLocation = "http://www.garmin.com/xmlschemas/TrackPointExtensionv1.xsd"
+)
+
+// GPXOptions changes the conversion of TCX data to GPX
+type GPXOptions struct {
+	Creator string // Creator is written as creator of the GPX file (default: go-fitbit)
+	// InterpolatePositions keeps track points without a position between two track points with a position
+	// and places them on the line between them by time, the position of these points is estimated and not measured
+	InterpolatePositions bool
+}
+
+// GPX contains the content of a GPX 1.1 file
+// https://www.topografix.com/GPX/1/1/
+type GPX struct {
+	XMLName  xml.Name     `xml:"gpx"`
+	Version  string       `xml:"version,attr"`
+	Creator  string       `xml:"creator,attr"`
+	Metadata *GPXMetadata `xml:"metadata,omitempty"`
+	Track    []GPXTrack   `xml:"trk"`
+}
+
+// MarshalXML writes the GPX file with the GPX and Garmin track point extension namespaces declared on the root element
+func (g GPX) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	// the alias type prevents a recursive call of MarshalXML
+	type gpx GPX
+	start = xml.StartElement{
+		Name: xml.Name{Local: "gpx"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: GPXNamespace},
+			{Name: xml.Name{Local: "xmlns:gpxtpx"}, Value: GPXTrackPointExtensionNamespace},
+			{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+			{Name: xml.Name{Local: "xsi:schemaLocation"}, Value: gpxSchemaLocation},
+		},
+	}
+	return e.EncodeElement(gpx(g), start)
+}
+
+// GPXMetadata contains information about the GPX file
+type GPXMetadata struct {
+	Name string     `xml:"name,omitempty"`
+	Time *time.Time `xml:"time,omitempty"`
+}
+
+// GPXTrack contains a single track, a track is created for every TCX activity
+type GPXTrack struct {
+	Name    string            `xml:"name,omitempty"`
+	Type    string            `xml:"type,omitempty"`
+	Segment []GPXTrackSegment `xml:"trkseg"`
+}
+
+// GPXTrackSegment contains the points of a track segment, a segment is created for every TCX lap
+type GPXTrackSegment struct {
+	Point []GPXTrackPoint `xml:"trkpt"`
+}
+
+// GPXTrackPoint contains a single point of a track segment
+type GPXTrackPoint struct {
+	Latitude   float64        `xml:"lat,attr"`
+	Longitude  float64        `xml:"lon,attr"`
+	Elevation  *float64       `xml:"ele,omitempty"`
+	Time       time.Time      `xml:"time"`
+	Extensions *GPXExtensions `xml:"extensions,omitempty"`
+}
+
+// GPXExtensions contains the extensions of a track point
+type GPXExtensions struct {
+	TrackPointExtension *GPXTrackPointExtension `xml:"gpxtpx:TrackPointExtension,omitempty"`
+}
+
+// GPXTrackPointExtension contains the heart rate and cadence of a track point as defined by the Garmin TrackPointExtension v1
+type GPXTrackPointExtension struct {
+	HeartRate *int `xml:"gpxtpx:hr,omitempty"`
+	Cadence   *int `xml:"gpxtpx:cad,omitempty"`
+}
+
+// TCXToGPX converts parsed TCX data to GPX
+// Every activity is converted to a track and every lap to a segment of the track
+// Track points without a position are dropped unless InterpolatePositions is set
+func TCXToGPX(tcx GarminTrainingCenterDatabasev2, options GPXOptions) GPX {
+	if options.Creator == "" {
+		options.Creator = "go-fitbit"
+	}
+
+	gpx := GPX{
+		Version: "1.1",
+		Creator: options.Creator,
+	}
+	for _, activity := range tcxActivities(tcx) {
+		if gpx.Metadata == nil {
+			start := activity.ID.Time
+			gpx.Metadata = &GPXMetadata{Time: &start}
+		}
+		gpx.Track = append(gpx.Track, activityToGPX(activity, options))
+	}
+
+	return gpx
+}
+
+// activityToGPX converts a single TCX activity to a GPX track
+func activityToGPX(activity TCXActivity, options GPXOptions) GPXTrack {
+	track := GPXTrack{
+		Name: activity.ID.Format(time.RFC3339),
+		Type: activity.Sport,
+	}
+
+	// positions are interpolated across laps, points are split into segments by their lap afterwards
+	var points []TCXTrackpoint
+	var laps []int
+	for i, lap := range activity.Lap {
+		for _, t := range lap.Track {
+			points = append(points, t.Trackpoint...)
+			for range t.Trackpoint {
+				laps = append(laps, i)
+			}
+		}
+	}
+	positions := trackpointPositions(points, options.InterpolatePositions)
+
+	segment := GPXTrackSegment{}
+	for i, point := range points {
+		if i > 0 && laps[i] != laps[i-1] && len(segment.Point) > 0 {
+			track.Segment = append(track.Segment, segment)
+			segment = GPXTrackSegment{}
+		}
+		if positions[i] == nil {
+			continue
+		}
+		segment.Point = append(segment.Point, trackpointToGPX(point, *positions[i]))
+	}
+	if len(segment.Point) > 0 {
+		track.Segment = append(track.Segment, segment)
+	}
+
+	return track
+}
+
+// trackpointPositions returns the position of every track point, nil if the point has no position
+// If interpolate is set, points between two points with a position get a position interpolated linearly by time
+func trackpointPositions(points []TCXTrackpoint, interpolate bool) []*TCXPosition {
+	positions := make([]*TCXPosition, len(points))
+	previous := -1
+	for i, point := range points {
+		if point.Position == nil {
+			continue
+		}
+		positions[i] = point.Position
+		if interpolate && previous >= 0 && previous < i-1 {
+			from, to := points[previous], point
+			duration := to.Time.Sub(from.Time.Time)
+			for j := previous + 1; j < i; j++ {
+				// points with an unusable time are placed by their index
+				ratio := float64(j-previous) / float64(i-previous)
+				if duration > 0 && !points[j].Time.Before(from.Time.Time) && !points[j].Time.After(to.Time.Time) {
+					ratio = float64(points[j].Time.Sub(from.Time.Time)) / float64(duration)
+				}
+				positions[j] = &TCXPosition{
+					LatitudeDegrees:  from.Position.LatitudeDegrees + (to.Position.LatitudeDegrees-from.Position.LatitudeDegrees)*ratio,
+					LongitudeDegrees: from.Position.LongitudeDegrees + (to.Position.LongitudeDegrees-from.Position.LongitudeDegrees)*ratio,
+				}
+			}
+		}
+		previous = i
+	}
+	return positions
+}
+
+// trackpointToGPX converts a single TCX track point located at the given position to a GPX track point
+func trackpointToGPX(point TCXTrackpoint, position TCXPosition) GPXTrackPoint {
+	gpxPoint := GPXTrackPoint{
+		Latitude:  position.LatitudeDegrees,
+		Longitude: position.LongitudeDegrees,
+		Elevation: point.AltitudeMeters,
+		Time:      point.Time.Time,
+	}
+
+	extension := GPXTrackPointExtension{Cadence: point.Cadence}
+	if point.HeartRateBpm != nil {
+		heartRate := point.HeartRateBpm.Value
+		extension.HeartRate = &heartRate
+	}
+	if extension.Cadence == nil && point.Extensions != nil && point.Extensions.TPX != nil {
+		extension.Cadence = point.Extensions.TPX.RunCadence
+	}
+	if extension.HeartRate != nil || extension.Cadence != nil {
+		gpxPoint.Extensions = &GPXExtensions{TrackPointExtension: &extension}
+	}
+
+	return gpxPoint
+}
+
+// WriteGPX encodes the GPX data including the XML header
+func WriteGPX(gpx GPX) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(gpx); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
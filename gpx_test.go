@@ -0,0 +1,72 @@
+package fitbit_test
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+// readTCXFixture returns the parsed TCX fixture
+func readTCXFixture(t *testing.T) fitbit.GarminTrainingCenterDatabasev2 {
+	t.Helper()
+	data, err := os.ReadFile("testdata/activity.tcx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcx, err := fitbit.ReadTCX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tcx
+}
+
+func TestTCXToGPXDropsPointsWithoutPosition(t *testing.T) {
+	gpx := fitbit.TCXToGPX(readTCXFixture(t), fitbit.GPXOptions{})
+	if len(gpx.Track) != 1 || len(gpx.Track[0].Segment) != 1 {
+		t.Fatalf("unexpected tracks %+v", gpx.Track)
+	}
+	points := gpx.Track[0].Segment[0].Point
+	if len(points) != 2 {
+		t.Fatalf("%d points, expected the point without position to be dropped", len(points))
+	}
+	if points[0].Latitude != 48.2081743 || *points[0].Extensions.TrackPointExtension.HeartRate != 131 || *points[0].Extensions.TrackPointExtension.Cadence != 84 {
+		t.Errorf("unexpected first point %+v", points[0])
+	}
+
+	data, err := fitbit.WriteGPX(gpx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNamespacesDeclared(t, data)
+	if !strings.Contains(string(data), "<gpxtpx:hr>151</gpxtpx:hr>") {
+		t.Errorf("heart rate extension missing:\n%s", data)
+	}
+}
+
+func TestTCXToGPXInterpolatesPositions(t *testing.T) {
+	gpx := fitbit.TCXToGPX(readTCXFixture(t), fitbit.GPXOptions{InterpolatePositions: true})
+	points := gpx.Track[0].Segment[0].Point
+	if len(points) != 3 {
+		t.Fatalf("%d points, expected 3", len(points))
+	}
+	// the point without position is recorded after half of the time between its neighbors
+	latitude := (48.2081743 + 48.2093611) / 2
+	longitude := (16.3738189 + 16.3775264) / 2
+	if math.Abs(points[1].Latitude-latitude) > 1e-9 || math.Abs(points[1].Longitude-longitude) > 1e-9 {
+		t.Errorf("interpolated position %f %f, expected %f %f", points[1].Latitude, points[1].Longitude, latitude, longitude)
+	}
+}
+
+func TestTCXToGPXWithoutAnyPosition(t *testing.T) {
+	tcx := readTCXFixture(t)
+	for i := range tcx.Activities.Activity[0].Lap[0].Track[0].Trackpoint {
+		tcx.Activities.Activity[0].Lap[0].Track[0].Trackpoint[i].Position = nil
+	}
+	gpx := fitbit.TCXToGPX(tcx, fitbit.GPXOptions{InterpolatePositions: true})
+	if len(gpx.Track) != 1 || len(gpx.Track[0].Segment) != 0 {
+		t.Errorf("positions were invented for an activity without positions: %+v", gpx.Track)
+	}
+}
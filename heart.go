@@ -1,8 +1,9 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"time"
 )
 
 // HeartDay contains a summary of heartrates for a given date range
@@ -10,7 +11,7 @@ type HeartDay struct {
 	ActivitiesHeart []struct {
 		DateTime string `json:"dateTime"`
 		Value    struct {
-			CustomHeartRateZones []interface{}    `json:"customHeartRateZones,omitempty"`
+			CustomHeartRateZones []HeartRateZones `json:"customHeartRateZones,omitempty"`
 			HeartRateZones       []HeartRateZones `json:"heartRateZones"`
 			RestingHeartRate     int              `json:"restingHeartRate"`
 		} `json:"value"`
@@ -21,7 +22,7 @@ type HeartDay struct {
 // HeartIntraday with slightly different structure to HeartDay
 type HeartIntraday struct {
 	ActivitiesHeart []struct {
-		CustomHeartRateZones []interface{}    `json:"customHeartRateZones"`
+		CustomHeartRateZones []HeartRateZones `json:"customHeartRateZones"`
 		DateTime             string           `json:"dateTime"`
 		HeartRateZones       []HeartRateZones `json:"heartRateZones"`
 		Value                string           `json:"value"`
@@ -74,7 +75,7 @@ type HRVIntraday struct {
 }
 
 type HRVMinutes struct {
-	Minute string           `json:"minutes"`
+	Minute string           `json:"minute"`
 	Value  HRVIntradayValue `json:"value"`
 }
 
@@ -86,34 +87,57 @@ type HRVIntradayValue struct {
 }
 
 // HeartLogByDay returns the heart log by a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) HeartLogByDay(day string) (HeartDay, error) {
-	// If not day is given assume today
-	if day == "" {
-		day = "today"
+	return m.HeartLogByDayContext(context.Background(), day)
+}
+
+// HeartLogByDayContext is like HeartLogByDay but uses ctx for the request
+func (m *Session) HeartLogByDayContext(ctx context.Context, day string) (HeartDay, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return HeartDay{}, err
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/heart/date/%s/1d.json", day))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/heart/date/%s/1d.json", m.apiURL, day))
 	if err != nil {
 		return HeartDay{}, err
 	}
 
 	foods := HeartDay{}
-	if err := json.Unmarshal(contents, &foods); err != nil {
+	if err := m.decode("HeartLogByDay", contents, &foods); err != nil {
 		return HeartDay{}, err
 	}
 
 	return foods, nil
 }
 
+// HeartLogOn is like HeartLogByDay but takes a Date
+func (m *Session) HeartLogOn(day Date) (HeartDay, error) {
+	return m.HeartLogOnContext(context.Background(), day)
+}
+
+// HeartLogOnContext is like HeartLogOn but uses ctx for the request
+func (m *Session) HeartLogOnContext(ctx context.Context, day Date) (HeartDay, error) {
+	if err := checkDates(day); err != nil {
+		return HeartDay{}, err
+	}
+	return m.HeartLogByDayContext(ctx, day.String())
+}
+
 // HeartIntraday returns the heart log by a given date in the given resolution
 // date must be in the format yyyy-MM-dd, default is today
 // resolution can be 1min or 1sec, 1sec is default
 // timeFrom and timeTo are in the format 00:00 for hour:minute, default entire day
 func (m *Session) HeartIntraday(day string, resolution string, timeFrom string, timeTo string) (HeartIntraday, error) {
-	// If not day is given assume today
-	if day == "" {
-		day = "today"
+	return m.HeartIntradayContext(context.Background(), day, resolution, timeFrom, timeTo)
+}
+
+// HeartIntradayContext is like HeartIntraday but uses ctx for the request
+func (m *Session) HeartIntradayContext(ctx context.Context, day string, resolution string, timeFrom string, timeTo string) (HeartIntraday, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return HeartIntraday{}, err
 	}
 
 	if timeFrom == "" {
@@ -122,35 +146,83 @@ func (m *Session) HeartIntraday(day string, resolution string, timeFrom string,
 	if timeTo == "" {
 		timeTo = "23:59"
 	}
+	if err := checkTimeOfDay(timeFrom); err != nil {
+		return HeartIntraday{}, err
+	}
+	if err := checkTimeOfDay(timeTo); err != nil {
+		return HeartIntraday{}, err
+	}
 
 	// default to 1sec if resolution dos not match to 1min
 	if resolution != "1min" {
 		resolution = "1sec"
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/heart/date/%s/1d/%s/time/%s/%s.json", day, resolution, timeFrom, timeTo))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/heart/date/%s/1d/%s/time/%s/%s.json", m.apiURL, day, resolution, timeFrom, timeTo))
 	if err != nil {
 		return HeartIntraday{}, err
 	}
 
 	heartintra := HeartIntraday{}
-	if err := json.Unmarshal(contents, &heartintra); err != nil {
+	if err := m.decode("HeartIntraday", contents, &heartintra); err != nil {
 		return HeartIntraday{}, err
 	}
 
 	return heartintra, nil
 }
 
+// HeartIntradayOn is like HeartIntraday but takes a Date and TimeOfDay values
+func (m *Session) HeartIntradayOn(day Date, resolution string, timeFrom TimeOfDay, timeTo TimeOfDay) (HeartIntraday, error) {
+	return m.HeartIntradayOnContext(context.Background(), day, resolution, timeFrom, timeTo)
+}
+
+// HeartIntradayOnContext is like HeartIntradayOn but uses ctx for the request
+func (m *Session) HeartIntradayOnContext(ctx context.Context, day Date, resolution string, timeFrom TimeOfDay, timeTo TimeOfDay) (HeartIntraday, error) {
+	if err := checkDates(day); err != nil {
+		return HeartIntraday{}, err
+	}
+	return m.HeartIntradayContext(ctx, day.String(), resolution, timeFrom.String(), timeTo.String())
+}
+
 // HeartLogByDateRange returns the heart log of a given time range by date in default resolution
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysHeartRate days are split into multiple requests
 func (m *Session) HeartLogByDateRange(startDay string, endDay string) (HeartDay, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/heart/date/%s/%s.json", startDay, endDay))
+	return m.HeartLogByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// HeartLogByDateRangeContext is like HeartLogByDateRange but uses ctx for the request
+func (m *Session) HeartLogByDateRangeContext(ctx context.Context, startDay string, endDay string) (HeartDay, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return HeartDay{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysHeartRate, m.heartLogByDateRange)
+}
+
+// HeartLogBetween is like HeartLogByDateRange but takes Dates
+func (m *Session) HeartLogBetween(startDay Date, endDay Date) (HeartDay, error) {
+	return m.HeartLogBetweenContext(context.Background(), startDay, endDay)
+}
+
+// HeartLogBetweenContext is like HeartLogBetween but uses ctx for the request
+func (m *Session) HeartLogBetweenContext(ctx context.Context, startDay Date, endDay Date) (HeartDay, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return HeartDay{}, err
+	}
+	return m.HeartLogByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// heartLogByDateRange requests a single date range
+func (m *Session) heartLogByDateRange(ctx context.Context, startDay string, endDay string) (HeartDay, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/heart/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return HeartDay{}, err
 	}
 
 	heart := HeartDay{}
-	if err := json.Unmarshal(contents, &heart); err != nil {
+	if err := m.decode("HeartLogByDateRange", contents, &heart); err != nil {
 		return HeartDay{}, err
 	}
 
@@ -160,19 +232,92 @@ func (m *Session) HeartLogByDateRange(startDay string, endDay string) (HeartDay,
 // HeartLogByDateRangeIntraday returns the heart log by a given date range in the given resolution
 // date must be in the format yyyy-MM-dd
 // resolution can be 1min or 1sec, 1sec is default
+// The intraday dataset only contains the time of day, ranges longer than one day return an error, see HeartLogIntradayDays
 func (m *Session) HeartLogByDateRangeIntraday(startDay string, endDay string, resolution string) (HeartDay, error) {
+	return m.HeartLogByDateRangeIntradayContext(context.Background(), startDay, endDay, resolution)
+}
+
+// HeartLogByDateRangeIntradayContext is like HeartLogByDateRangeIntraday but uses ctx for the request
+func (m *Session) HeartLogByDateRangeIntradayContext(ctx context.Context, startDay string, endDay string, resolution string) (HeartDay, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return HeartDay{}, err
+	}
+	startDay, endDay, err = m.resolveToday(ctx, startDay, endDay, MaxDaysIntraday)
+	if err != nil {
+		return HeartDay{}, err
+	}
+	if len(splitDateRange(startDay, endDay, MaxDaysIntraday)) > 1 {
+		return HeartDay{}, fmt.Errorf("intraday heart rate from %s to %s: ranges longer than one day are not supported, use HeartLogIntradayDays", startDay, endDay)
+	}
+
+	return m.heartLogByDateRangeIntraday(ctx, startDay, endDay, resolution)
+}
+
+// HeartLogIntradayBetween is like HeartLogByDateRangeIntraday but takes Dates
+func (m *Session) HeartLogIntradayBetween(startDay Date, endDay Date, resolution string) (HeartDay, error) {
+	return m.HeartLogIntradayBetweenContext(context.Background(), startDay, endDay, resolution)
+}
+
+// HeartLogIntradayBetweenContext is like HeartLogIntradayBetween but uses ctx for the request
+func (m *Session) HeartLogIntradayBetweenContext(ctx context.Context, startDay Date, endDay Date, resolution string) (HeartDay, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return HeartDay{}, err
+	}
+	return m.HeartLogByDateRangeIntradayContext(ctx, startDay.String(), endDay.String(), resolution)
+}
+
+// HeartLogIntradayDays returns the intraday heart log of every day of the date range in the given resolution
+// date must be in the format yyyy-MM-dd
+// resolution can be 1min or 1sec, 1sec is default
+// Every day is requested separately and returned in chronological order
+func (m *Session) HeartLogIntradayDays(startDay string, endDay string, resolution string) ([]HeartDay, error) {
+	return m.HeartLogIntradayDaysContext(context.Background(), startDay, endDay, resolution)
+}
+
+// HeartLogIntradayDaysContext is like HeartLogIntradayDays but uses ctx for the request
+func (m *Session) HeartLogIntradayDaysContext(ctx context.Context, startDay string, endDay string, resolution string) ([]HeartDay, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysIntraday, func(ctx context.Context, startDay string, endDay string) ([]HeartDay, error) {
+		heart, err := m.heartLogByDateRangeIntraday(ctx, startDay, endDay, resolution)
+		if err != nil {
+			return nil, err
+		}
+		return []HeartDay{heart}, nil
+	})
+}
+
+// HeartLogIntradayDaysBetween is like HeartLogIntradayDays but takes Dates
+func (m *Session) HeartLogIntradayDaysBetween(startDay Date, endDay Date, resolution string) ([]HeartDay, error) {
+	return m.HeartLogIntradayDaysBetweenContext(context.Background(), startDay, endDay, resolution)
+}
+
+// HeartLogIntradayDaysBetweenContext is like HeartLogIntradayDaysBetween but uses ctx for the request
+func (m *Session) HeartLogIntradayDaysBetweenContext(ctx context.Context, startDay Date, endDay Date, resolution string) ([]HeartDay, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return nil, err
+	}
+	return m.HeartLogIntradayDaysContext(ctx, startDay.String(), endDay.String(), resolution)
+}
+
+// heartLogByDateRangeIntraday requests a single date range
+func (m *Session) heartLogByDateRangeIntraday(ctx context.Context, startDay string, endDay string, resolution string) (HeartDay, error) {
 	// default to 1sec if resolution dos not match to 1min
 	if resolution != "1min" {
 		resolution = "1sec"
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/activities/heart/date/%s/%s/%s.json", startDay, endDay, resolution))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/activities/heart/date/%s/%s/%s.json", m.apiURL, startDay, endDay, resolution))
 	if err != nil {
 		return HeartDay{}, err
 	}
 
 	heart := HeartDay{}
-	if err := json.Unmarshal(contents, &heart); err != nil {
+	if err := m.decode("HeartLogByDateRangeIntraday", contents, &heart); err != nil {
 		return HeartDay{}, err
 	}
 
@@ -182,14 +327,43 @@ func (m *Session) HeartLogByDateRangeIntraday(startDay string, endDay string, re
 // HRVSummaryByDateRange the Heart Rate Variability (HRV) data for a date range.
 // HRV data applies specifically to a user’s “main sleep,” which is the longest single period of time asleep on a given date.
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysHRV days are split into multiple requests
 func (m *Session) HRVSummaryByDateRange(startDay string, endDay string) (HeartRateVariabilitySummary, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/hrv/date/%s/%s.json", startDay, endDay))
+	return m.HRVSummaryByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// HRVSummaryByDateRangeContext is like HRVSummaryByDateRange but uses ctx for the request
+func (m *Session) HRVSummaryByDateRangeContext(ctx context.Context, startDay string, endDay string) (HeartRateVariabilitySummary, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return HeartRateVariabilitySummary{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysHRV, m.hrvSummaryByDateRange)
+}
+
+// HRVSummaryBetween is like HRVSummaryByDateRange but takes Dates
+func (m *Session) HRVSummaryBetween(startDay Date, endDay Date) (HeartRateVariabilitySummary, error) {
+	return m.HRVSummaryBetweenContext(context.Background(), startDay, endDay)
+}
+
+// HRVSummaryBetweenContext is like HRVSummaryBetween but uses ctx for the request
+func (m *Session) HRVSummaryBetweenContext(ctx context.Context, startDay Date, endDay Date) (HeartRateVariabilitySummary, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return HeartRateVariabilitySummary{}, err
+	}
+	return m.HRVSummaryByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// hrvSummaryByDateRange requests a single date range
+func (m *Session) hrvSummaryByDateRange(ctx context.Context, startDay string, endDay string) (HeartRateVariabilitySummary, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/hrv/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return HeartRateVariabilitySummary{}, err
 	}
 
 	hrv := HeartRateVariabilitySummary{}
-	if err := json.Unmarshal(contents, &hrv); err != nil {
+	if err := m.decode("HRVSummaryByDateRange", contents, &hrv); err != nil {
 		return HeartRateVariabilitySummary{}, err
 	}
 
@@ -198,37 +372,84 @@ func (m *Session) HRVSummaryByDateRange(startDay string, endDay string) (HeartRa
 
 // HRVSummaryByDate the Heart Rate Variability (HRV) data for a date.
 // HRV data applies specifically to a user’s “main sleep,” which is the longest single period of time asleep on a given date.
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) HRVSummaryByDate(day string) (HeartRateVariabilitySummary, error) {
-	// If not day is given assume today
-	if day == "" {
-		day = "today"
+	return m.HRVSummaryByDateContext(context.Background(), day)
+}
+
+// HRVSummaryByDateContext is like HRVSummaryByDate but uses ctx for the request
+func (m *Session) HRVSummaryByDateContext(ctx context.Context, day string) (HeartRateVariabilitySummary, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return HeartRateVariabilitySummary{}, err
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/hrv/date/%s.json", day))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/hrv/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return HeartRateVariabilitySummary{}, err
 	}
 
 	hrv := HeartRateVariabilitySummary{}
-	if err := json.Unmarshal(contents, &hrv); err != nil {
+	if err := m.decode("HRVSummaryByDate", contents, &hrv); err != nil {
 		return HeartRateVariabilitySummary{}, err
 	}
 
 	return hrv, nil
 }
 
+// HRVSummaryOn is like HRVSummaryByDate but takes a Date
+func (m *Session) HRVSummaryOn(day Date) (HeartRateVariabilitySummary, error) {
+	return m.HRVSummaryOnContext(context.Background(), day)
+}
+
+// HRVSummaryOnContext is like HRVSummaryOn but uses ctx for the request
+func (m *Session) HRVSummaryOnContext(ctx context.Context, day Date) (HeartRateVariabilitySummary, error) {
+	if err := checkDates(day); err != nil {
+		return HeartRateVariabilitySummary{}, err
+	}
+	return m.HRVSummaryByDateContext(ctx, day.String())
+}
+
 // HRVSummaryByDateRange the Heart Rate Variability (HRV) data for a date range.
 // HRV data applies specifically to a user’s “main sleep,” which is the longest single period of time asleep on a given date.
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysHRV days are split into multiple requests
 func (m *Session) HRVIntradayByDateRange(startDay string, endDay string) (HeartRateVariabilityIntraday, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/hrv/date/%s/%s/all.json", startDay, endDay))
+	return m.HRVIntradayByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// HRVIntradayByDateRangeContext is like HRVIntradayByDateRange but uses ctx for the request
+func (m *Session) HRVIntradayByDateRangeContext(ctx context.Context, startDay string, endDay string) (HeartRateVariabilityIntraday, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return HeartRateVariabilityIntraday{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysHRV, m.hrvIntradayByDateRange)
+}
+
+// HRVIntradayBetween is like HRVIntradayByDateRange but takes Dates
+func (m *Session) HRVIntradayBetween(startDay Date, endDay Date) (HeartRateVariabilityIntraday, error) {
+	return m.HRVIntradayBetweenContext(context.Background(), startDay, endDay)
+}
+
+// HRVIntradayBetweenContext is like HRVIntradayBetween but uses ctx for the request
+func (m *Session) HRVIntradayBetweenContext(ctx context.Context, startDay Date, endDay Date) (HeartRateVariabilityIntraday, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return HeartRateVariabilityIntraday{}, err
+	}
+	return m.HRVIntradayByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// hrvIntradayByDateRange requests a single date range
+func (m *Session) hrvIntradayByDateRange(ctx context.Context, startDay string, endDay string) (HeartRateVariabilityIntraday, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/hrv/date/%s/%s/all.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return HeartRateVariabilityIntraday{}, err
 	}
 
 	hrv := HeartRateVariabilityIntraday{}
-	if err := json.Unmarshal(contents, &hrv); err != nil {
+	if err := m.decode("HRVIntradayByDateRange", contents, &hrv); err != nil {
 		return HeartRateVariabilityIntraday{}, err
 	}
 
@@ -237,22 +458,121 @@ func (m *Session) HRVIntradayByDateRange(startDay string, endDay string) (HeartR
 
 // HRVSummaryByDate the Heart Rate Variability (HRV) data for a date.
 // HRV data applies specifically to a user’s “main sleep,” which is the longest single period of time asleep on a given date.
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) HRVIntradayByDate(day string) (HeartRateVariabilityIntraday, error) {
-	// If not day is given assume today
-	if day == "" {
-		day = "today"
+	return m.HRVIntradayByDateContext(context.Background(), day)
+}
+
+// HRVIntradayByDateContext is like HRVIntradayByDate but uses ctx for the request
+func (m *Session) HRVIntradayByDateContext(ctx context.Context, day string) (HeartRateVariabilityIntraday, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return HeartRateVariabilityIntraday{}, err
 	}
 
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/hrv/date/%s/all.json", day))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/hrv/date/%s/all.json", m.apiURL, day))
 	if err != nil {
 		return HeartRateVariabilityIntraday{}, err
 	}
 
 	hrv := HeartRateVariabilityIntraday{}
-	if err := json.Unmarshal(contents, &hrv); err != nil {
+	if err := m.decode("HRVIntradayByDate", contents, &hrv); err != nil {
 		return HeartRateVariabilityIntraday{}, err
 	}
 
 	return hrv, nil
 }
+
+// HRVIntradayOn is like HRVIntradayByDate but takes a Date
+func (m *Session) HRVIntradayOn(day Date) (HeartRateVariabilityIntraday, error) {
+	return m.HRVIntradayOnContext(context.Background(), day)
+}
+
+// HRVIntradayOnContext is like HRVIntradayOn but uses ctx for the request
+func (m *Session) HRVIntradayOnContext(ctx context.Context, day Date) (HeartRateVariabilityIntraday, error) {
+	if err := checkDates(day); err != nil {
+		return HeartRateVariabilityIntraday{}, err
+	}
+	return m.HRVIntradayByDateContext(ctx, day.String())
+}
+
+// Series returns the resting heart rate and the intraday heart rate as normalized time series
+// Days without a resting heart rate are skipped, loc is the timezone of the user
+func (h HeartDay) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, day := range h.ActivitiesHeart {
+		if day.Value.RestingHeartRate == 0 {
+			continue
+		}
+		if err := b.addAt(MetricRestingHeartRate, false, day.DateTime, float64(day.Value.RestingHeartRate), UnitBPM); err != nil {
+			return nil, err
+		}
+	}
+	if len(h.ActivitiesHeart) > 0 {
+		if err := h.ActivitiesHeartIntraday.series(b, h.ActivitiesHeart[0].DateTime); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
+
+// Series returns the intraday heart rate as normalized time series, loc is the timezone of the user
+func (h HeartIntraday) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	if len(h.ActivitiesHeart) > 0 {
+		if err := h.ActivitiesHeartIntraday.series(b, h.ActivitiesHeart[0].DateTime); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
+
+// series adds the intraday dataset of the given date to b
+func (a ActivitiesHeartIntraday) series(b *seriesBuilder, date string) error {
+	if len(a.Dataset) == 0 {
+		return nil
+	}
+	add, err := b.intraday(MetricHeartRate, date, UnitBPM)
+	if err != nil {
+		return err
+	}
+	for _, entry := range a.Dataset {
+		if err := add(entry.Time, float64(entry.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Series returns the daily and deep sleep RMSSD as normalized time series, loc is the timezone of the user
+func (h HeartRateVariabilitySummary) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, day := range h.Hrv {
+		if err := b.addAt(MetricHRVDailyRmssd, false, day.DateTime, day.Value.DailyRmssd, UnitMilliseconds); err != nil {
+			return nil, err
+		}
+		if err := b.addAt(MetricHRVDeepRmssd, false, day.DateTime, day.Value.DeepRmssd, UnitMilliseconds); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
+
+// Series returns the intraday RMSSD, coverage and frequency powers as normalized time series
+// loc is the timezone of the user
+func (h HeartRateVariabilityIntraday) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, day := range h.Hrv {
+		for _, minute := range day.Minutes {
+			t, err := b.parseTime(minute.Minute)
+			if err != nil {
+				return nil, err
+			}
+			b.add(MetricHRVRmssd, true, t, minute.Value.Rmssd, UnitMilliseconds)
+			b.add(MetricHRVCoverage, true, t, minute.Value.Coverage*100, UnitPercent)
+			b.add(MetricHRVHighFrequency, true, t, minute.Value.Hf, UnitMsSquared)
+			b.add(MetricHRVLowFrequency, true, t, minute.Value.Lf, UnitMsSquared)
+		}
+	}
+	return b.result(), nil
+}
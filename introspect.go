@@ -1,7 +1,7 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 )
 
 // IntrospectResponse contains the response of the introspect request
@@ -17,18 +17,28 @@ type IntrospectResponse struct {
 
 // Introspect checks if the currently used oauth token is still valid
 func (m *Session) Introspect() (IntrospectResponse, error) {
+	return m.IntrospectContext(context.Background())
+}
+
+// IntrospectContext is like Introspect but uses ctx for the request
+func (m *Session) IntrospectContext(ctx context.Context) (IntrospectResponse, error) {
+	token := m.Token()
+	if token == nil {
+		return IntrospectResponse{}, errNoToken
+	}
+
 	// Build request
 	postRequestBody := map[string]string{
-		"token": m.token.AccessToken,
+		"token": token.AccessToken,
 	}
 
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1.1/oauth2/introspect", postRequestBody)
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1.1/oauth2/introspect", postRequestBody)
 	if err != nil {
 		return IntrospectResponse{}, err
 	}
 
 	intro := IntrospectResponse{}
-	if err := json.Unmarshal(contents, &intro); err != nil {
+	if err := m.decode("Introspect", contents, &intro); err != nil {
 		return IntrospectResponse{}, err
 	}
 
@@ -0,0 +1,103 @@
+package fitbit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// loginFlowTimeout is the duration after which a started login can't be completed anymore
+const loginFlowTimeout = 15 * time.Minute
+
+// maxPendingLogins is the maximum number of started logins remembered by a session, the oldest one is dropped if exceeded
+const maxPendingLogins = 1000
+
+// pendingLogin contains the PKCE verifier of a started login
+type pendingLogin struct {
+	verifier string
+	created  time.Time
+}
+
+// NewLogin starts a new login using PKCE and returns the OAuth login url and the random state of the login
+// The verifier of the login is remembered by the session until CompleteLogin is called with the returned state
+// https://dev.fitbit.com/build/reference/web-api/developer-guide/authorization/#Authorization-Code-Grant-Flow-with-PKCE
+func (m *Session) NewLogin() (string, string, error) {
+	state, err := randomState()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	m.mutex.Lock()
+	if m.pendingLogins == nil {
+		m.pendingLogins = make(map[string]pendingLogin)
+	}
+	m.prunePendingLogins(maxPendingLogins - 1)
+	m.pendingLogins[state] = pendingLogin{
+		verifier: verifier,
+		created:  time.Now(),
+	}
+	m.mutex.Unlock()
+
+	return m.oAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// CompleteLogin validates the state of a login started with NewLogin and exchanges the code using the PKCE verifier
+// state and code are the query parameters of the callback request
+// Returns ErrInvalidState if the state is unknown, expired or was already used
+func (m *Session) CompleteLogin(state string, code string) (*oauth2.Token, error) {
+	return m.CompleteLoginContext(context.Background(), state, code)
+}
+
+// CompleteLoginContext is like CompleteLogin but uses ctx for the token request
+func (m *Session) CompleteLoginContext(ctx context.Context, state string, code string) (*oauth2.Token, error) {
+	if code == "" {
+		return nil, errors.New("no code given")
+	}
+
+	// every state can be used only once
+	m.mutex.Lock()
+	login, ok := m.pendingLogins[state]
+	delete(m.pendingLogins, state)
+	m.prunePendingLogins(maxPendingLogins)
+	m.mutex.Unlock()
+
+	if !ok || state == "" || time.Since(login.created) > loginFlowTimeout {
+		return nil, ErrInvalidState
+	}
+
+	return m.exchange(ctx, code, oauth2.VerifierOption(login.verifier))
+}
+
+// prunePendingLogins removes expired logins which were never completed and the oldest logins
+// exceeding limit, the caller must hold m.mutex
+func (m *Session) prunePendingLogins(limit int) {
+	for state, login := range m.pendingLogins {
+		if time.Since(login.created) > loginFlowTimeout {
+			delete(m.pendingLogins, state)
+		}
+	}
+	for len(m.pendingLogins) > limit {
+		var oldestState string
+		var oldest time.Time
+		for state, login := range m.pendingLogins {
+			if oldestState == "" || login.created.Before(oldest) {
+				oldestState, oldest = state, login.created
+			}
+		}
+		delete(m.pendingLogins, oldestState)
+	}
+}
+
+// randomState returns a cryptographically random state
+func randomState() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
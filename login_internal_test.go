@@ -0,0 +1,55 @@
+package fitbit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPendingLoginsAreBounded(t *testing.T) {
+	session := New(Config{ClientID: "client"})
+
+	_, first, err := session.NewLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxPendingLogins; i++ {
+		if _, _, err := session.NewLogin(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	session.mutex.Lock()
+	pending := len(session.pendingLogins)
+	_, ok := session.pendingLogins[first]
+	session.mutex.Unlock()
+	if pending != maxPendingLogins {
+		t.Errorf("%d pending logins, expected %d", pending, maxPendingLogins)
+	}
+	if ok {
+		t.Error("oldest login was not dropped")
+	}
+}
+
+func TestExpiredLoginsArePrunedOnComplete(t *testing.T) {
+	session := New(Config{ClientID: "client"})
+	_, state, err := session.NewLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session.mutex.Lock()
+	login := session.pendingLogins[state]
+	login.created = time.Now().Add(-2 * loginFlowTimeout)
+	session.pendingLogins[state] = login
+	session.mutex.Unlock()
+
+	if _, err := session.CompleteLogin("unknown", "code"); err != ErrInvalidState {
+		t.Fatalf("expected ErrInvalidState, got %v", err)
+	}
+
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if len(session.pendingLogins) != 0 {
+		t.Errorf("expired login was not pruned")
+	}
+}
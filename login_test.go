@@ -0,0 +1,72 @@
+package fitbit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+// authorize follows the login url like a browser and returns the callback query
+func authorize(t *testing.T, loginURL string) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(loginURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: unexpected status %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	return location.Query()
+}
+
+func TestLogin(t *testing.T) {
+	server, session := newTestSession(t, nil)
+
+	loginURL, state, err := session.NewLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := authorize(t, loginURL)
+	if callback.Get("state") != state {
+		t.Fatalf("state %q returned instead of %q", callback.Get("state"), state)
+	}
+
+	token, err := session.CompleteLogin(callback.Get("state"), callback.Get("code"))
+	if err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if userID, _ := token.Extra("user_id").(string); userID != "ABC123" {
+		t.Errorf("token of user %q returned", userID)
+	}
+	if _, err := session.Profile(0); err != nil {
+		t.Errorf("request using the new token: %v", err)
+	}
+
+	// a state can be used only once
+	_, err = session.CompleteLogin(callback.Get("state"), server.Authorize("ABC123"))
+	if !errors.Is(err, fitbit.ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState for a reused state, got %v", err)
+	}
+}
+
+func TestLoginUnknownState(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	if _, _, err := session.NewLogin(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := session.CompleteLogin("unknown", server.Authorize("ABC123"))
+	if !errors.Is(err, fitbit.ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState, got %v", err)
+	}
+}
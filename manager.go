@@ -0,0 +1,281 @@
+package fitbit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// UserTokenStore persists tokens of multiple users by their Fitbit user ID
+type UserTokenStore interface {
+	// Load returns the stored token of the user or ErrTokenNotFound if no token is stored
+	Load(userID string) (*oauth2.Token, error)
+	// Save persists the token of the user, a nil token removes the stored token
+	Save(userID string, token *oauth2.Token) error
+}
+
+// MemoryUserTokenStore keeps the tokens of multiple users in memory
+type MemoryUserTokenStore struct {
+	mutex  sync.RWMutex
+	tokens map[string]oauth2.Token
+}
+
+// NewMemoryUserTokenStore creates a new in-memory token store for multiple users
+func NewMemoryUserTokenStore() *MemoryUserTokenStore {
+	return &MemoryUserTokenStore{tokens: make(map[string]oauth2.Token)}
+}
+
+// Load returns a copy of the stored token of the user
+func (s *MemoryUserTokenStore) Load(userID string) (*oauth2.Token, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	token, ok := s.tokens[userID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &token, nil
+}
+
+// Save stores a copy of the token of the user
+func (s *MemoryUserTokenStore) Save(userID string, token *oauth2.Token) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if token == nil {
+		delete(s.tokens, userID)
+		return nil
+	}
+	s.tokens[userID] = *token
+	return nil
+}
+
+// userTokenStore adapts a UserTokenStore to the TokenStore of a single user
+type userTokenStore struct {
+	store  UserTokenStore
+	userID string
+}
+
+// Load returns the stored token of the user
+func (s userTokenStore) Load() (*oauth2.Token, error) {
+	return s.store.Load(s.userID)
+}
+
+// Save persists the token of the user
+func (s userTokenStore) Save(token *oauth2.Token) error {
+	return s.store.Save(s.userID, token)
+}
+
+// Delete removes the stored token of the user
+func (s userTokenStore) Delete() error {
+	return s.store.Save(s.userID, nil)
+}
+
+// ManagerConfig describes the configuration of a Manager
+type ManagerConfig struct {
+	Config      Config              // Config is the configuration shared by all sessions, Config.TokenStore is not used
+	TokenStore  UserTokenStore      // TokenStore persists the tokens of all users (required)
+	IdleTimeout time.Duration       // IdleTimeout is the duration after which unused sessions are evicted (default: 30m)
+	RetryPolicy RetryPolicy         // RetryPolicy is set for every session
+	RateLimiter func() *RateLimiter // RateLimiter creates the rate limiter of a new session, rate limits are tracked per user
+}
+
+// Manager manages the sessions of multiple users sharing one OAuth configuration and transport
+// Sessions are created on first use based on the token store and evicted after being idle
+type Manager struct {
+	config ManagerConfig
+
+	// base session providing the shared OAuth config and transport, also used for logins
+	base *Session
+
+	sessions  map[string]*managedSession
+	lastEvict time.Time
+	mutex     sync.Mutex
+}
+
+// managedSession is a session of a user within the manager
+type managedSession struct {
+	session  *Session
+	lastUsed time.Time
+}
+
+// idle returns true if the session has no running request and was neither returned by the manager
+// nor used for a request within timeout
+func (s *managedSession) idle(timeout time.Duration) bool {
+	if s.session.inFlight.Load() > 0 {
+		return false
+	}
+	lastUsed := s.lastUsed
+	if lastRequest := time.Unix(0, s.session.lastUsed.Load()); lastRequest.After(lastUsed) {
+		lastUsed = lastRequest
+	}
+	return time.Since(lastUsed) > timeout
+}
+
+// NewManager creates a new manager for sessions of multiple users
+func NewManager(config ManagerConfig) *Manager {
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 30 * time.Minute
+	}
+	config.Config.TokenStore = nil
+
+	return &Manager{
+		config:   config,
+		base:     New(config.Config),
+		sessions: make(map[string]*managedSession),
+	}
+}
+
+// Session returns the session of the given Fitbit user ID, e.g. IntrospectResponse.UserID
+// The session is created using the token of the token store if it isn't in use yet
+func (m *Manager) Session(userID string) (*Session, error) {
+	if userID == "" {
+		return nil, errors.New("no user id given")
+	}
+
+	m.mutex.Lock()
+	m.evictIdleLocked()
+	if managed, ok := m.sessions[userID]; ok {
+		managed.lastUsed = time.Now()
+		m.mutex.Unlock()
+		return managed.session, nil
+	}
+	m.mutex.Unlock()
+
+	// load token outside of the lock to not block other users on slow stores
+	token, err := m.config.TokenStore.Load(userID)
+	if err != nil {
+		return nil, err
+	}
+	return m.register(userID, token, false), nil
+}
+
+// SessionForSubscription returns the session of the owner of a subscription notification
+func (m *Manager) SessionForSubscription(subscription Subscription) (*Session, error) {
+	return m.Session(subscription.OwnerID)
+}
+
+// Add saves the token of the user in the token store and returns the session of the user
+func (m *Manager) Add(userID string, token *oauth2.Token) (*Session, error) {
+	if userID == "" {
+		return nil, errors.New("no user id given")
+	}
+	if err := m.config.TokenStore.Save(userID, token); err != nil {
+		return nil, err
+	}
+	return m.register(userID, token, true), nil
+}
+
+// Remove removes the session of the user from the manager, the stored token is kept
+func (m *Manager) Remove(userID string) {
+	m.mutex.Lock()
+	delete(m.sessions, userID)
+	m.mutex.Unlock()
+}
+
+// Logout revokes the token of the user, deletes it from the token store and removes the session
+func (m *Manager) Logout(ctx context.Context, userID string) error {
+	session, err := m.Session(userID)
+	if err != nil {
+		return err
+	}
+	m.Remove(userID)
+	return session.LogoutContext(ctx)
+}
+
+// Ratelimit returns the rate limit information of the user if the user has an active session
+func (m *Manager) Ratelimit(userID string) (Ratelimit, bool) {
+	m.mutex.Lock()
+	managed, ok := m.sessions[userID]
+	m.mutex.Unlock()
+	if !ok {
+		return Ratelimit{}, false
+	}
+	return managed.session.GetRatelimit(), true
+}
+
+// NewLogin starts a new login using PKCE, see Session.NewLogin
+func (m *Manager) NewLogin() (string, string, error) {
+	return m.base.NewLogin()
+}
+
+// CompleteLogin completes a login started with NewLogin, see Session.CompleteLogin
+// The user is determined by the token response and the token is added to the manager
+func (m *Manager) CompleteLogin(state string, code string) (*Session, string, error) {
+	return m.CompleteLoginContext(context.Background(), state, code)
+}
+
+// CompleteLoginContext is like CompleteLogin but uses ctx for the token request
+func (m *Manager) CompleteLoginContext(ctx context.Context, state string, code string) (*Session, string, error) {
+	token, err := m.base.CompleteLoginContext(ctx, state, code)
+	if err != nil {
+		return nil, "", err
+	}
+	userID, _ := token.Extra("user_id").(string)
+	if userID == "" {
+		return nil, "", errors.New("token response contains no user_id")
+	}
+	session, err := m.Add(userID, token)
+	return session, userID, err
+}
+
+// EvictIdle removes all sessions which were not used within the idle timeout
+// and returns the number of evicted sessions, sessions with running requests are kept
+func (m *Manager) EvictIdle() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.evictIdle()
+}
+
+// evictIdleLocked evicts idle sessions at most once per minute, m.mutex must be held
+func (m *Manager) evictIdleLocked() {
+	if time.Since(m.lastEvict) < time.Minute {
+		return
+	}
+	m.evictIdle()
+}
+
+// evictIdle removes idle sessions, m.mutex must be held
+func (m *Manager) evictIdle() int {
+	m.lastEvict = time.Now()
+	evicted := 0
+	for userID, managed := range m.sessions {
+		if managed.idle(m.config.IdleTimeout) {
+			delete(m.sessions, userID)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// register creates a session of the user sharing the OAuth config and transport of the manager
+// An existing session of the user only gets the new token if replace is set, a token loaded from the
+// token store may be older than the token of a session which was created and refreshed meanwhile
+func (m *Manager) register(userID string, token *oauth2.Token, replace bool) *Session {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if managed, ok := m.sessions[userID]; ok {
+		managed.lastUsed = time.Now()
+		if current := managed.session.Token(); replace && (current == nil || current.AccessToken != token.AccessToken) {
+			managed.session.SetToken(token)
+		}
+		return managed.session
+	}
+
+	config := m.base.config
+	config.TokenStore = userTokenStore{store: m.config.TokenStore, userID: userID}
+	session := newSession(config, m.base.oAuthConfig, m.base.transport)
+	session.SetRetryPolicy(m.config.RetryPolicy)
+	if m.config.RateLimiter != nil {
+		session.SetRateLimiter(m.config.RateLimiter())
+	}
+	session.SetToken(token)
+
+	m.sessions[userID] = &managedSession{
+		session:  session,
+		lastUsed: time.Now(),
+	}
+	return session
+}
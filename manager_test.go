@@ -0,0 +1,210 @@
+package fitbit_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+	"golang.org/x/oauth2"
+)
+
+// blockingUserStore blocks the first Load after reading the token until release is closed
+type blockingUserStore struct {
+	*fitbit.MemoryUserTokenStore
+	loaded  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingUserStore) Load(userID string) (*oauth2.Token, error) {
+	token, err := s.MemoryUserTokenStore.Load(userID)
+	select {
+	case <-s.loaded:
+	default:
+		close(s.loaded)
+		<-s.release
+	}
+	return token, err
+}
+
+func TestManagerSessionKeepsNewerToken(t *testing.T) {
+	server, _ := newTestSession(t, nil)
+	store := &blockingUserStore{
+		MemoryUserTokenStore: fitbit.NewMemoryUserTokenStore(),
+		loaded:               make(chan struct{}),
+		release:              make(chan struct{}),
+	}
+	manager := fitbit.NewManager(fitbit.ManagerConfig{Config: server.Config(), TokenStore: store})
+	if err := store.Save("ABC123", server.Token("ABC123")); err != nil {
+		t.Fatal(err)
+	}
+
+	// load the stored token and create the session with a newer token while the load is running
+	result := make(chan *fitbit.Session)
+	go func() {
+		session, err := manager.Session("ABC123")
+		if err != nil {
+			t.Error(err)
+		}
+		result <- session
+	}()
+	<-store.loaded
+	fresh := server.Token("ABC123")
+	added, err := manager.Add("ABC123", fresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(store.release)
+
+	session := <-result
+	if session != added {
+		t.Fatal("a second session was created for the user")
+	}
+	if session.Token().AccessToken != fresh.AccessToken {
+		t.Error("newer token was replaced by the token loaded from the store")
+	}
+	if _, err := session.Profile(0); err != nil {
+		t.Errorf("request of the managed session: %v", err)
+	}
+}
+
+func TestManagerSessionUsesStoredToken(t *testing.T) {
+	server, _ := newTestSession(t, nil)
+	store := fitbit.NewMemoryUserTokenStore()
+	manager := fitbit.NewManager(fitbit.ManagerConfig{Config: server.Config(), TokenStore: store})
+
+	if _, err := manager.Session("ABC123"); err == nil {
+		t.Fatal("session of a user without stored token was created")
+	}
+	if err := store.Save("ABC123", server.Token("ABC123")); err != nil {
+		t.Fatal(err)
+	}
+	session, err := manager.Session("ABC123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := manager.Session("ABC123"); again != session {
+		t.Error("session was not reused")
+	}
+
+	// rotated tokens are persisted in the store of the manager
+	expire(session)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatalf("request with refresh: %v", err)
+	}
+	stored, err := store.Load("ABC123")
+	if err != nil || stored.RefreshToken != session.Token().RefreshToken {
+		t.Errorf("rotated token was not stored, got %v", err)
+	}
+}
+
+func TestManagerEvictsIdleSessions(t *testing.T) {
+	server, _ := newTestSession(t, nil)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	config := server.Config()
+	config.Middlewares = []fitbit.Middleware{func(next http.RoundTripper) http.RoundTripper {
+		return fitbit.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/1/user/-/activities/heart/date/2024-01-01/1d.json" {
+				close(started)
+				<-release
+			}
+			return next.RoundTrip(req)
+		})
+	}}
+	manager := fitbit.NewManager(fitbit.ManagerConfig{
+		Config:      config,
+		TokenStore:  fitbit.NewMemoryUserTokenStore(),
+		IdleTimeout: 50 * time.Millisecond,
+	})
+	session, err := manager.Add("ABC123", server.Token("ABC123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a session with a running request is kept, also if it was not returned by the manager within the timeout
+	done := make(chan error)
+	go func() {
+		_, err := session.HeartLogByDay("2024-01-01")
+		done <- err
+	}()
+	<-started
+	time.Sleep(100 * time.Millisecond)
+	if evicted := manager.EvictIdle(); evicted != 0 {
+		t.Errorf("session with a running request was evicted")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the finished request counts as use of the session
+	if evicted := manager.EvictIdle(); evicted != 0 {
+		t.Errorf("session was evicted right after a request")
+	}
+	if again, err := manager.Session("ABC123"); err != nil || again != session {
+		t.Fatalf("a second session was created for the user, got %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if evicted := manager.EvictIdle(); evicted != 1 {
+		t.Errorf("expected the idle session to be evicted, evicted %d", evicted)
+	}
+	if _, ok := manager.Ratelimit("ABC123"); ok {
+		t.Error("evicted session is still active")
+	}
+}
+
+func TestManagerRateLimitsPerUser(t *testing.T) {
+	server, _ := newTestSession(t, nil)
+	server.AddUser(fitbittest.User{ID: "DEF456", Timezone: "Europe/Vienna"})
+	server.SetRateLimit(3, time.Hour)
+
+	limiters := 0
+	manager := fitbit.NewManager(fitbit.ManagerConfig{
+		Config:     server.Config(),
+		TokenStore: fitbit.NewMemoryUserTokenStore(),
+		RateLimiter: func() *fitbit.RateLimiter {
+			limiters++
+			return &fitbit.RateLimiter{FailFast: true}
+		},
+	})
+	first, err := manager.Add("ABC123", server.Token("ABC123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := manager.Add("DEF456", server.Token("DEF456"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limiters != 2 {
+		t.Fatalf("expected a rate limiter per user, created %d", limiters)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := first.Profile(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the limiter of the first user knows that the window is exhausted and fails without sending a request
+	requests := len(server.Requests())
+	if _, err := first.Profile(0); !errors.Is(err, fitbit.ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+	if len(server.Requests()) != requests {
+		t.Error("request exceeding the rate limit was sent")
+	}
+
+	// the requests of the first user don't count against the rate limit of the second one
+	if _, err := second.Profile(0); err != nil {
+		t.Fatalf("request of the second user: %v", err)
+	}
+	if ratelimit, ok := manager.Ratelimit("ABC123"); !ok || ratelimit.RateLimitUsed != 3 {
+		t.Errorf("unexpected rate limit of the first user %+v", ratelimit)
+	}
+	if ratelimit, ok := manager.Ratelimit("DEF456"); !ok || ratelimit.RateLimitUsed != 1 {
+		t.Errorf("unexpected rate limit of the second user %+v", ratelimit)
+	}
+}
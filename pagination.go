@@ -0,0 +1,49 @@
+package fitbit
+
+import (
+	"context"
+	"iter"
+	"net/url"
+)
+
+// paginate returns an iterator over all entries of a paginated list
+// fetch requests a single page using the given query and returns its entries and the url of the next page
+// the query of the next page is used for the following request until no next page is available
+func paginate[E any](ctx context.Context, query url.Values, queryErr error, fetch func(ctx context.Context, query url.Values) ([]E, string, error)) iter.Seq2[E, error] {
+	return func(yield func(E, error) bool) {
+		var empty E
+		if queryErr != nil {
+			yield(empty, queryErr)
+			return
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(empty, err)
+				return
+			}
+
+			entries, next, err := fetch(ctx, query)
+			if err != nil {
+				yield(empty, err)
+				return
+			}
+			for _, entry := range entries {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+			if next == "" || len(entries) == 0 {
+				return
+			}
+
+			// only the query of the next url is used to keep requests at the configured API url
+			nextURL, err := url.Parse(next)
+			if err != nil {
+				yield(empty, err)
+				return
+			}
+			query = nextURL.Query()
+		}
+	}
+}
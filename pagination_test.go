@@ -0,0 +1,209 @@
+package fitbit_test
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+)
+
+// seedLogs adds n activities, sleeps and ECG readings on consecutive days starting at 2024-01-01
+func seedLogs(server *fitbittest.Server, n int) {
+	server.Update("ABC123", func(u *fitbittest.User) {
+		for i := 0; i < n; i++ {
+			start := time.Date(2024, 1, 1+i, 7, 0, 0, 0, time.UTC)
+			u.Activities = append(u.Activities, fitbittest.Activity{LogID: int64(100 + i), ActivityTypeID: 90009, Name: "Run", Start: start, Duration: 30 * time.Minute})
+			u.Sleep = append(u.Sleep, fitbittest.Sleep{LogID: int64(200 + i), Start: start.Add(-8 * time.Hour), End: start, MinutesAsleep: 420})
+			u.ECG = append(u.ECG, fitbittest.ECG{Start: start, AverageHeartRate: 60 + i})
+		}
+	})
+}
+
+// collect returns all entries of the iterator until the first error
+func collect[E any](seq iter.Seq2[E, error]) ([]E, error) {
+	var entries []E
+	for entry, err := range seq {
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// pageRequests returns the requests of the given path
+func pageRequests(server *fitbittest.Server, path string) []fitbittest.Request {
+	var requests []fitbittest.Request
+	for _, request := range server.Requests() {
+		if request.Path == path {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func TestLogIterFollowsNext(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	seedLogs(server, 5)
+	params := fitbit.LogListParameters{AfterDate: "2023-12-31", Limit: 2}
+
+	tests := []struct {
+		name string
+		path string
+		ids  func() ([]int64, error)
+	}{
+		{"activities", "/1/user/-/activities/list.json", func() ([]int64, error) {
+			activities, err := collect(session.ActivityLogIter(context.Background(), params))
+			var ids []int64
+			for _, activity := range activities {
+				ids = append(ids, activity.LogID)
+			}
+			return ids, err
+		}},
+		{"sleep", "/1.2/user/-/sleep/list.json", func() ([]int64, error) {
+			sleeps, err := collect(session.SleepLogIter(context.Background(), params))
+			var ids []int64
+			for _, sleep := range sleeps {
+				ids = append(ids, sleep.LogID)
+			}
+			return ids, err
+		}},
+		{"ecg", "/1/user/-/ecg/list.json", func() ([]int64, error) {
+			readings, err := collect(session.ECGLogIter(context.Background(), params))
+			var ids []int64
+			for _, reading := range readings {
+				ids = append(ids, int64(reading.AverageHeartRate))
+			}
+			return ids, err
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids, err := test.ids()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != 5 {
+				t.Fatalf("expected 5 entries, got %v", ids)
+			}
+			for i := 1; i < len(ids); i++ {
+				if ids[i] != ids[0]+int64(i) {
+					t.Errorf("entries are not in ascending order: %v", ids)
+				}
+			}
+
+			// all pages use the query of the next url of the previous page
+			requests := pageRequests(server, test.path)
+			if len(requests) != 3 {
+				t.Fatalf("expected 3 page requests, got %d", len(requests))
+			}
+			for i, request := range requests {
+				query := request.Query
+				if query.Get("afterDate") != "2023-12-31" || query.Get("sort") != "asc" || query.Has("beforeDate") {
+					t.Errorf("page %d was requested with %v", i, query)
+				}
+				if offset := query.Get("offset"); offset != []string{"0", "2", "4"}[i] {
+					t.Errorf("page %d was requested with offset %s", i, offset)
+				}
+			}
+		})
+	}
+}
+
+func TestLogIterBeforeDate(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	seedLogs(server, 3)
+
+	sleeps, err := collect(session.SleepLogIter(context.Background(), fitbit.LogListParameters{BeforeDate: "2024-01-04", Limit: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sleeps) != 3 || sleeps[0].LogID != 202 || sleeps[2].LogID != 200 {
+		t.Errorf("expected all sleeps in descending order, got %+v", sleeps)
+	}
+	for _, request := range pageRequests(server, "/1.2/user/-/sleep/list.json") {
+		if request.Query.Get("beforeDate") != "2024-01-04" || request.Query.Get("sort") != "desc" {
+			t.Errorf("page was requested with %v", request.Query)
+		}
+	}
+}
+
+func TestLogIterBreak(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	seedLogs(server, 5)
+
+	var ids []int64
+	for activity, err := range session.ActivityLogIter(context.Background(), fitbit.LogListParameters{AfterDate: "2023-12-31", Limit: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, activity.LogID)
+		if len(ids) == 2 {
+			break
+		}
+	}
+	if len(ids) != 2 {
+		t.Errorf("expected 2 activities, got %v", ids)
+	}
+	if requests := pageRequests(server, "/1/user/-/activities/list.json"); len(requests) != 1 {
+		t.Errorf("expected no page after the break, got %d requests", len(requests))
+	}
+}
+
+func TestLogIterContextCanceled(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	seedLogs(server, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var ids []int64
+	var iterErr error
+	for activity, err := range session.ActivityLogIter(ctx, fitbit.LogListParameters{AfterDate: "2023-12-31", Limit: 2}) {
+		if err != nil {
+			iterErr = err
+			break
+		}
+		ids = append(ids, activity.LogID)
+		cancel()
+	}
+
+	// the entries of the current page are returned, the next page is not requested
+	if !errors.Is(iterErr, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", iterErr)
+	}
+	if len(ids) != 2 {
+		t.Errorf("expected the activities of the first page, got %v", ids)
+	}
+	if requests := pageRequests(server, "/1/user/-/activities/list.json"); len(requests) != 1 {
+		t.Errorf("expected 1 page request, got %d", len(requests))
+	}
+}
+
+func TestLogIterErrors(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	seedLogs(server, 5)
+
+	// invalid parameters are returned without request
+	if _, err := collect(session.ECGLogIter(context.Background(), fitbit.LogListParameters{Limit: 2})); err == nil {
+		t.Error("expected an error without beforeDate and afterDate")
+	}
+	if _, err := collect(session.ECGLogIter(context.Background(), fitbit.LogListParameters{AfterDate: "2023-12-31", Limit: 11})); err == nil {
+		t.Error("expected an error for a limit above the maximum of the endpoint")
+	}
+	if requests := pageRequests(server, "/1/user/-/ecg/list.json"); len(requests) != 0 {
+		t.Errorf("invalid parameters were sent, got %d requests", len(requests))
+	}
+
+	// a failing page ends the iteration with its error
+	server.Inject(fitbittest.Fault{Path: "/1/user/-/activities/list.json", StatusCode: http.StatusNotFound, ErrorType: "not_found"})
+	activities, err := collect(session.ActivityLogIter(context.Background(), fitbit.LogListParameters{AfterDate: "2023-12-31", Limit: 2}))
+	if !errors.Is(err, fitbit.ErrNotFound) || len(activities) != 0 {
+		t.Errorf("expected ErrNotFound without activities, got %v and %d activities", err, len(activities))
+	}
+}
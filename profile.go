@@ -1,7 +1,7 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strconv"
 )
@@ -69,18 +69,23 @@ type Profile struct {
 
 // Profile returns the current users profile if 0 is used or the profile of a friend with matching ID
 func (m *Session) Profile(userID uint64) (Profile, error) {
+	return m.ProfileContext(context.Background(), userID)
+}
+
+// ProfileContext is like Profile but uses ctx for the request
+func (m *Session) ProfileContext(ctx context.Context, userID uint64) (Profile, error) {
 	// Default "-" is current logged in user
 	requestID := "-"
 	if userID > 0 {
 		requestID = strconv.FormatUint(userID, 10)
 	}
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/%s/profile.json", requestID))
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/%s/profile.json", m.apiURL, requestID))
 	if err != nil {
 		return Profile{}, err
 	}
 
 	profile := Profile{}
-	if err := json.Unmarshal(contents, &profile); err != nil {
+	if err := m.decode("Profile", contents, &profile); err != nil {
 		return Profile{}, err
 	}
 
@@ -90,18 +95,23 @@ func (m *Session) Profile(userID uint64) (Profile, error) {
 // SetProfile updates the current users profile information
 // userID 0 is current user
 func (m *Session) SetProfile(userID uint64, params map[string]string) (Profile, error) {
+	return m.SetProfileContext(context.Background(), userID, params)
+}
+
+// SetProfileContext is like SetProfile but uses ctx for the request
+func (m *Session) SetProfileContext(ctx context.Context, userID uint64, params map[string]string) (Profile, error) {
 	// Default "-" is current logged in user
 	requestID := "-"
 	if userID > 0 {
 		requestID = strconv.FormatUint(userID, 10)
 	}
-	contents, err := m.makePOSTRequest(fmt.Sprintf("https://api.fitbit.com/1/user/%s/profile.json", requestID), params)
+	contents, err := m.makePOSTRequest(ctx, fmt.Sprintf("%s/1/user/%s/profile.json", m.apiURL, requestID), params)
 	if err != nil {
 		return Profile{}, err
 	}
 
 	profile := Profile{}
-	if err := json.Unmarshal(contents, &profile); err != nil {
+	if err := m.decode("SetProfile", contents, &profile); err != nil {
 		return Profile{}, err
 	}
 
@@ -0,0 +1,144 @@
+package fitbit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimiter paces the requests of a session based on the rate limit reported by Fitbit
+// Fitbit allows 150 requests per user and hour, the limiter is refreshed after every response
+// https://dev.fitbit.com/build/reference/web-api/developer-guide/application-design/#Rate-Limits
+type RateLimiter struct {
+	Reserve  int  // Reserve is the number of requests kept unused within a rate limit window
+	FailFast bool // FailFast returns a *RateLimitError instead of blocking until the window resets
+	Pace     bool // Pace spreads the remaining requests evenly across the remaining window instead of bursting
+
+	mutex     sync.Mutex
+	ratelimit Ratelimit
+	pending   int // pending is the number of allowed requests which were not answered yet
+	last      time.Time
+}
+
+// RateLimitError is returned by a fail fast RateLimiter if the reserve of the current window is reached
+// It matches ErrRateLimited using errors.Is
+type RateLimitError struct {
+	Ratelimit Ratelimit // Ratelimit is the rate limit information known by the limiter
+}
+
+// Error returns a readable representation of the error
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("fitbit: rate limit reserve reached, %d/%d requests used until %s",
+		e.Ratelimit.RateLimitUsed, e.Ratelimit.RateLimitAvailable, e.Ratelimit.RateLimitReset.Format(time.RFC3339))
+}
+
+// Is allows matching the error against ErrRateLimited using errors.Is
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Update sets the rate limit information used by the limiter
+// Requests allowed by Wait which are not answered yet are counted in addition to the used requests of ratelimit
+func (l *RateLimiter) Update(ratelimit Ratelimit) {
+	l.mutex.Lock()
+	l.ratelimit = ratelimit
+	l.mutex.Unlock()
+}
+
+// Wait blocks until a request is allowed to be sent within the current rate limit window
+// A fail fast limiter returns a *RateLimitError instead of blocking
+// The allowed request is counted until its response arrives, or until the window resets if it is not sent by a session
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		delay, err := l.reserve()
+		if err != nil || delay <= 0 {
+			return err
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a request from the budget if possible or returns the time to wait until the next try
+func (l *RateLimiter) reserve() (time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if !l.ratelimit.RateLimitReset.IsZero() && !now.Before(l.ratelimit.RateLimitReset) {
+		// the window is over, requests allowed within it don't count against the next one
+		l.ratelimit = Ratelimit{}
+		l.pending = 0
+	}
+	// no information about the current window, allow requests until the first response updates the limiter
+	if l.ratelimit.RateLimitAvailable == 0 {
+		l.pending++
+		return 0, nil
+	}
+
+	remaining := l.ratelimit.RateLimitAvailable - l.ratelimit.RateLimitUsed - l.pending - l.Reserve
+	if remaining <= 0 {
+		if l.FailFast {
+			ratelimit := l.ratelimit
+			ratelimit.RateLimitUsed += l.pending
+			return 0, &RateLimitError{Ratelimit: ratelimit}
+		}
+		return l.ratelimit.RateLimitReset.Sub(now), nil
+	}
+
+	if l.Pace && !l.last.IsZero() {
+		next := l.last.Add(l.ratelimit.RateLimitReset.Sub(now) / time.Duration(remaining+1))
+		if now.Before(next) {
+			return next.Sub(now), nil
+		}
+	}
+
+	// count the request to prevent concurrent callers from exceeding the budget before the response arrives
+	l.pending++
+	l.last = now
+	return 0, nil
+}
+
+// release stops counting a request allowed by Wait, the response updated the used requests already
+func (l *RateLimiter) release() {
+	l.mutex.Lock()
+	if l.pending > 0 {
+		l.pending--
+	}
+	l.mutex.Unlock()
+}
+
+// SetRateLimiter sets the rate limiter used by the session before every request
+// The limiter is seeded with the rate limit information of the last response, nil disables it
+func (m *Session) SetRateLimiter(limiter *RateLimiter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if limiter != nil && m.ratelimit.RateLimitAvailable > 0 {
+		limiter.Update(m.ratelimit)
+	}
+	m.rateLimiter = limiter
+}
+
+// Wait blocks until the rate limiter of the session allows a new request
+// It can be used to pace bulk requests and returns immediately if no rate limiter is set
+func (m *Session) Wait(ctx context.Context) error {
+	_, err := m.reserveRequest(ctx)
+	return err
+}
+
+// reserveRequest waits until the rate limiter of the session allows a new request
+// and returns the limiter to release the request after it was answered, nil if no rate limiter is set
+func (m *Session) reserveRequest(ctx context.Context) (*RateLimiter, error) {
+	m.mutex.RLock()
+	limiter := m.rateLimiter
+	m.mutex.RUnlock()
+	if limiter == nil {
+		return nil, nil
+	}
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return limiter, nil
+}
@@ -0,0 +1,129 @@
+package fitbit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+func TestRateLimiterFailFast(t *testing.T) {
+	limiter := &fitbit.RateLimiter{FailFast: true, Reserve: 2}
+	limiter.Update(fitbit.Ratelimit{RateLimitAvailable: 10, RateLimitUsed: 7, RateLimitReset: time.Now().Add(time.Hour)})
+
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("the last request before the reserve was not allowed: %v", err)
+	}
+	err := limiter.Wait(context.Background())
+	var rateLimitErr *fitbit.RateLimitError
+	if !errors.As(err, &rateLimitErr) || !errors.Is(err, fitbit.ErrRateLimited) {
+		t.Fatalf("expected a *RateLimitError matching ErrRateLimited, got %v", err)
+	}
+	if rateLimitErr.Ratelimit.RateLimitUsed != 8 || rateLimitErr.Ratelimit.RateLimitAvailable != 10 {
+		t.Errorf("expected the allowed request to be counted, got %+v", rateLimitErr.Ratelimit)
+	}
+}
+
+func TestRateLimiterBlocksUntilReset(t *testing.T) {
+	limiter := &fitbit.RateLimiter{}
+	limiter.Update(fitbit.Ratelimit{RateLimitAvailable: 1, RateLimitUsed: 1, RateLimitReset: time.Now().Add(200 * time.Millisecond)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to end with the context, got %v", err)
+	}
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 150*time.Millisecond {
+		t.Errorf("expected to wait until the reset, waited %s", waited)
+	}
+}
+
+func TestRateLimiterPace(t *testing.T) {
+	limiter := &fitbit.RateLimiter{Pace: true}
+	limiter.Update(fitbit.Ratelimit{RateLimitAvailable: 9, RateLimitReset: time.Now().Add(time.Second)})
+
+	// 9 requests within a second are spaced by about 100ms, the first one is not delayed
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if waited := time.Since(start); waited < 150*time.Millisecond || waited > 500*time.Millisecond {
+		t.Errorf("expected 3 requests to be spaced by about 100ms, took %s", waited)
+	}
+}
+
+func TestSetRateLimiterSeedsLimiter(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	server.SetRateLimit(1, time.Hour)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the limiter knows the exhausted window of the last response and fails without sending a request
+	session.SetRateLimiter(&fitbit.RateLimiter{FailFast: true})
+	requests := len(server.Requests())
+	_, err := session.Profile(0)
+	var rateLimitErr *fitbit.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("expected a *RateLimitError, got %v", err)
+	}
+	if len(server.Requests()) != requests {
+		t.Error("request exceeding the rate limit was sent")
+	}
+}
+
+func TestRateLimiterCountsRequestsInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server, session := newTestSession(t, func(c *fitbit.Config) {
+		c.Middlewares = []fitbit.Middleware{func(next http.RoundTripper) http.RoundTripper {
+			return fitbit.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == "/1/user/-/activities/heart/date/2024-01-01/1d.json" {
+					close(started)
+					<-release
+				}
+				return next.RoundTrip(req)
+			})
+		}}
+	})
+	server.SetRateLimit(3, time.Hour)
+	session.SetRateLimiter(&fitbit.RateLimiter{FailFast: true})
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the heart rate request is allowed and held back before it reaches the server
+	done := make(chan error)
+	go func() {
+		_, err := session.HeartLogByDay("2024-01-01")
+		done <- err
+	}()
+	<-started
+
+	// the response of the second request reports 2 used requests, the one in flight still counts
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+	var rateLimitErr *fitbit.RateLimitError
+	if _, err := session.Profile(0); !errors.As(err, &rateLimitErr) {
+		t.Errorf("expected a *RateLimitError while a request is in flight, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("request in flight: %v", err)
+	}
+	if ratelimit := session.GetRatelimit(); ratelimit.RateLimitUsed != 3 {
+		t.Errorf("expected the whole rate limit to be used, got %+v", ratelimit)
+	}
+}
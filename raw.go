@@ -0,0 +1,62 @@
+package fitbit
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// RawResponse contains the original response of the Fitbit API next to the parsed result of a call
+type RawResponse struct {
+	Method     string      // Method is the HTTP method of the request
+	URL        string      // URL is the requested url
+	StatusCode int         // StatusCode is the HTTP status code of the response
+	Header     http.Header // Header contains all headers of the response
+	Body       []byte      // Body is the unmodified body of the response
+	Ratelimit  Ratelimit   // Ratelimit is the rate limit information of the response
+}
+
+// rawResponseKey is the context key of the raw response recorder
+type rawResponseKey struct{}
+
+// rawResponseRecorder appends the raw responses of all requests using the same context
+type rawResponseRecorder struct {
+	mutex sync.Mutex
+	raw   *[]RawResponse
+}
+
+// WithRawResponse returns a context which appends the raw response of every request sent with it to raw
+// It can be used with every ...Context method to access the body, status, headers and rate limit of the responses,
+// also if the call fails with an *APIError. The responses are in the order of the requests: a retried request
+// is followed by its retries and date ranges split into multiple requests are in chronological order,
+// also if the chunks are requested concurrently
+func WithRawResponse(ctx context.Context, raw *[]RawResponse) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, &rawResponseRecorder{raw: raw})
+}
+
+// recordRawResponse appends the raw response if ctx was created using WithRawResponse
+func recordRawResponse(ctx context.Context, raw RawResponse) {
+	recorder, ok := ctx.Value(rawResponseKey{}).(*rawResponseRecorder)
+	if !ok || recorder.raw == nil {
+		return
+	}
+	recorder.mutex.Lock()
+	*recorder.raw = append(*recorder.raw, raw)
+	recorder.mutex.Unlock()
+}
+
+// withChunkRawResponse returns a context recording the raw responses of a single chunk of a date range separately
+// flush appends them to the responses of ctx, calling it for all chunks in order keeps the responses chronological
+func withChunkRawResponse(ctx context.Context) (chunkCtx context.Context, flush func()) {
+	recorder, ok := ctx.Value(rawResponseKey{}).(*rawResponseRecorder)
+	if !ok || recorder.raw == nil {
+		return ctx, func() {}
+	}
+
+	var chunk []RawResponse
+	return WithRawResponse(ctx, &chunk), func() {
+		recorder.mutex.Lock()
+		*recorder.raw = append(*recorder.raw, chunk...)
+		recorder.mutex.Unlock()
+	}
+}
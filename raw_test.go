@@ -0,0 +1,93 @@
+package fitbit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+)
+
+func TestRawResponse(t *testing.T) {
+	_, session := newTestSession(t, nil)
+
+	var raw []fitbit.RawResponse
+	profile, err := session.ProfileContext(fitbit.WithRawResponse(context.Background(), &raw), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 1 {
+		t.Fatalf("expected 1 raw response, got %d", len(raw))
+	}
+	if raw[0].Method != http.MethodGet || !strings.HasSuffix(raw[0].URL, "/1/user/-/profile.json") || raw[0].StatusCode != http.StatusOK {
+		t.Errorf("unexpected raw response %s %s: %d", raw[0].Method, raw[0].URL, raw[0].StatusCode)
+	}
+	if raw[0].Header.Get("Content-Type") == "" || raw[0].Ratelimit.RateLimitAvailable == 0 {
+		t.Errorf("expected headers and rate limit of the response, got %v and %+v", raw[0].Header, raw[0].Ratelimit)
+	}
+
+	var parsed fitbit.Profile
+	if err := json.Unmarshal(raw[0].Body, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.User.EncodedID != profile.User.EncodedID {
+		t.Errorf("raw body contains user %q, parsed %q", parsed.User.EncodedID, profile.User.EncodedID)
+	}
+}
+
+func TestRawResponseKeepsRetries(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	session.SetRetryPolicy(fitbit.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	server.Inject(fitbittest.Fault{Path: "/1/user/-/profile.json", StatusCode: http.StatusInternalServerError})
+
+	var raw []fitbit.RawResponse
+	if _, err := session.ProfileContext(fitbit.WithRawResponse(context.Background(), &raw), 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 2 {
+		t.Fatalf("expected 2 raw responses, got %d", len(raw))
+	}
+	if raw[0].StatusCode != http.StatusInternalServerError || raw[1].StatusCode != http.StatusOK {
+		t.Errorf("expected the failed attempt before the retry, got %d and %d", raw[0].StatusCode, raw[1].StatusCode)
+	}
+}
+
+func TestRawResponseOfFailedCall(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	server.Inject(fitbittest.Fault{Path: "/1.2/user/-/sleep", StatusCode: http.StatusNotFound, ErrorType: "not_found"})
+
+	var raw []fitbit.RawResponse
+	_, err := session.SleepByDayContext(fitbit.WithRawResponse(context.Background(), &raw), "2024-01-01")
+	if !errors.Is(err, fitbit.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if len(raw) != 1 || raw[0].StatusCode != http.StatusNotFound || !strings.Contains(string(raw[0].Body), "not_found") {
+		t.Errorf("expected the raw error response, got %+v", raw)
+	}
+}
+
+func TestRawResponseChunksInChronologicalOrder(t *testing.T) {
+	_, session := newTestSession(t, func(c *fitbit.Config) {
+		c.ChunkConcurrency = 4
+	})
+
+	var raw []fitbit.RawResponse
+	days, err := session.HeartLogIntradayDaysContext(fitbit.WithRawResponse(context.Background(), &raw), "2024-01-01", "2024-01-08", "1min")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 8 || len(raw) != 8 {
+		t.Fatalf("expected 8 days and raw responses, got %d and %d", len(days), len(raw))
+	}
+	for i, response := range raw {
+		day := time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+		if !strings.Contains(response.URL, "/date/"+day+"/"+day+"/") {
+			t.Errorf("raw response %d is of %s, expected %s", i, response.URL, day)
+		}
+	}
+}
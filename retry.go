@@ -0,0 +1,121 @@
+package fitbit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes if and how failed requests are retried
+// Requests answered with 429 Too Many Requests are retried after the rate limit reset,
+// server errors (5xx) and transient network errors are retried with jittered exponential backoff
+type RetryPolicy struct {
+	MaxAttempts        int           // MaxAttempts is the maximum number of attempts including the first one, 0 or 1 disables retries
+	BaseDelay          time.Duration // BaseDelay is the initial backoff delay which is doubled on every attempt (default: 1s)
+	MaxWait            time.Duration // MaxWait is the maximum time to wait before a single retry, a longer rate limit reset is not awaited (default: 1h)
+	RetryNonIdempotent bool          // RetryNonIdempotent allows retrying POST requests on server and network errors which may lead to duplicate entries
+}
+
+// SetRetryPolicy sets the retry policy used for all following requests of this session
+// Retries are disabled by default
+func (m *Session) SetRetryPolicy(policy RetryPolicy) {
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = time.Second
+	}
+	if policy.MaxWait <= 0 {
+		policy.MaxWait = time.Hour
+	}
+	m.mutex.Lock()
+	m.retryPolicy = policy
+	m.mutex.Unlock()
+}
+
+// retryDelay determines if a request which failed with err in the given attempt should be retried
+// and returns the time to wait until the next attempt
+func (p RetryPolicy) retryDelay(method string, attempt int, err error) (time.Duration, bool) {
+	if err == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			// request was not processed by Fitbit, so it is safe to retry every method
+			// wait until the rate limit window is reset if the reset is known
+			if !apiErr.Ratelimit.RateLimitReset.IsZero() {
+				delay := time.Until(apiErr.Ratelimit.RateLimitReset) + time.Second
+				if delay > p.MaxWait {
+					return 0, false
+				}
+				return p.jitter(delay), true
+			}
+			return p.backoff(attempt), true
+		case apiErr.StatusCode >= http.StatusInternalServerError:
+			if method == http.MethodPost && !p.RetryNonIdempotent {
+				return 0, false
+			}
+			return p.backoff(attempt), true
+		}
+		return 0, false
+	}
+
+	if !isTransientError(err) || (method == http.MethodPost && !p.RetryNonIdempotent) {
+		return 0, false
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff returns the jittered exponential backoff delay for the given attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxWait; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxWait {
+		delay = p.MaxWait
+	}
+	return p.jitter(delay)
+}
+
+// jitter adds up to 10% of the given delay to spread concurrent retries
+func (p RetryPolicy) jitter(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return 0
+	}
+	//nolint:gosec
+	delay += time.Duration(rand.Int63n(int64(delay)/10 + 1))
+	if delay > p.MaxWait {
+		delay = p.MaxWait
+	}
+	return delay
+}
+
+// isTransientError returns true for network errors which may succeed on a later attempt
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errExpiredToken) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
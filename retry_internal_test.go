@@ -0,0 +1,80 @@
+package fitbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxWait: time.Second}
+	nonIdempotent := policy
+	nonIdempotent.RetryNonIdempotent = true
+	patient := policy
+	patient.MaxWait = 10 * time.Second
+
+	serverErr := &APIError{StatusCode: http.StatusServiceUnavailable}
+	tooMany := &APIError{StatusCode: http.StatusTooManyRequests}
+	tooManyUntil := func(reset time.Duration) error {
+		return &APIError{StatusCode: http.StatusTooManyRequests, Ratelimit: Ratelimit{RateLimitReset: time.Now().Add(reset)}}
+	}
+	networkErr := &url.Error{Op: "Get", URL: "https://api.fitbit.com", Err: syscall.ECONNRESET}
+
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		method   string
+		attempt  int
+		err      error
+		retry    bool
+		minDelay time.Duration
+		maxDelay time.Duration
+	}{
+		{name: "success", policy: policy, method: http.MethodGet, attempt: 1},
+		{name: "last attempt", policy: policy, method: http.MethodGet, attempt: 10, err: serverErr},
+		{name: "retries disabled", policy: RetryPolicy{}, method: http.MethodGet, attempt: 1, err: serverErr},
+		{name: "client error", policy: policy, method: http.MethodGet, attempt: 1, err: &APIError{StatusCode: http.StatusNotFound}},
+		{name: "canceled", policy: policy, method: http.MethodGet, attempt: 1, err: fmt.Errorf("request: %w", context.Canceled)},
+		{name: "expired token", policy: policy, method: http.MethodGet, attempt: 1, err: &url.Error{Op: "Get", URL: "/", Err: errExpiredToken}},
+		{name: "unknown error", policy: policy, method: http.MethodGet, attempt: 1, err: errors.New("unknown")},
+
+		{name: "GET server error", policy: policy, method: http.MethodGet, attempt: 1, err: serverErr, retry: true, minDelay: 100 * time.Millisecond, maxDelay: 110 * time.Millisecond},
+		{name: "GET network error", policy: policy, method: http.MethodGet, attempt: 1, err: networkErr, retry: true, minDelay: 100 * time.Millisecond, maxDelay: 110 * time.Millisecond},
+		{name: "GET timeout", policy: policy, method: http.MethodGet, attempt: 1, err: &net.DNSError{Err: "timeout", IsTimeout: true}, retry: true, minDelay: 100 * time.Millisecond, maxDelay: 110 * time.Millisecond},
+		{name: "DELETE server error", policy: policy, method: http.MethodDelete, attempt: 1, err: serverErr, retry: true, minDelay: 100 * time.Millisecond, maxDelay: 110 * time.Millisecond},
+
+		// a POST may have been processed, a retry could create duplicate entries
+		{name: "POST server error", policy: policy, method: http.MethodPost, attempt: 1, err: serverErr},
+		{name: "POST network error", policy: policy, method: http.MethodPost, attempt: 1, err: networkErr},
+		{name: "POST server error non-idempotent", policy: nonIdempotent, method: http.MethodPost, attempt: 1, err: serverErr, retry: true, minDelay: 100 * time.Millisecond, maxDelay: 110 * time.Millisecond},
+		{name: "POST network error non-idempotent", policy: nonIdempotent, method: http.MethodPost, attempt: 1, err: networkErr, retry: true, minDelay: 100 * time.Millisecond, maxDelay: 110 * time.Millisecond},
+		{name: "POST too many requests", policy: policy, method: http.MethodPost, attempt: 1, err: tooMany, retry: true, minDelay: 100 * time.Millisecond, maxDelay: 110 * time.Millisecond},
+
+		// a known reset is awaited unless it is further away than MaxWait
+		{name: "too many requests until reset", policy: patient, method: http.MethodGet, attempt: 1, err: tooManyUntil(2 * time.Second), retry: true, minDelay: 2900 * time.Millisecond, maxDelay: 3300 * time.Millisecond},
+		{name: "reset after MaxWait", policy: policy, method: http.MethodGet, attempt: 1, err: tooManyUntil(2 * time.Second)},
+
+		// the backoff is doubled on every attempt and capped at MaxWait
+		{name: "backoff third attempt", policy: policy, method: http.MethodGet, attempt: 3, err: serverErr, retry: true, minDelay: 400 * time.Millisecond, maxDelay: 440 * time.Millisecond},
+		{name: "backoff capped", policy: policy, method: http.MethodGet, attempt: 8, err: serverErr, retry: true, minDelay: time.Second, maxDelay: time.Second},
+		{name: "backoff capped with jitter", policy: policy, method: http.MethodGet, attempt: 4, err: serverErr, retry: true, minDelay: 800 * time.Millisecond, maxDelay: time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delay, retry := test.policy.retryDelay(test.method, test.attempt, test.err)
+			if retry != test.retry {
+				t.Fatalf("retry %t, expected %t", retry, test.retry)
+			}
+			if delay < test.minDelay || delay > test.maxDelay {
+				t.Errorf("delay %s, expected between %s and %s", delay, test.minDelay, test.maxDelay)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package fitbit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Revoke revokes the given access or refresh token
+// Fitbit revokes all tokens of the user for this application, regardless of the type of the given token
+// https://dev.fitbit.com/build/reference/web-api/authorization/revoke-token/
+func (m *Session) Revoke(token string) error {
+	return m.RevokeContext(context.Background(), token)
+}
+
+// RevokeContext is like Revoke but uses ctx for the request
+func (m *Session) RevokeContext(ctx context.Context, token string) error {
+	if token == "" {
+		return errNoToken
+	}
+
+	// the revoke endpoint uses basic authentication of the application instead of the token of the user
+	form := url.Values{}
+	form.Set("token", token)
+	req, err := http.NewRequestWithContext(ctx, "POST", m.apiURL+"/oauth2/revoke", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(url.QueryEscape(m.config.ClientID), url.QueryEscape(m.config.ClientSecret))
+	req.Header.Set("User-Agent", "go-fitbit")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{
+		Transport: m.transport,
+		Timeout:   m.config.Timeout,
+	}
+	response, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	ratelimit := m.parseRatelimit(&response.Header)
+
+	contents, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return newAPIError(response.StatusCode, req.URL.Path, contents, ratelimit)
+	}
+	return nil
+}
+
+// Logout revokes the current token of the session, removes it from the session and deletes it from the token store
+// The token is only deleted from token stores implementing TokenDeleter, TokenChange is not called
+// Following requests of the session fail until a new token is set
+func (m *Session) Logout() error {
+	return m.LogoutContext(context.Background())
+}
+
+// LogoutContext is like Logout but uses ctx for the request
+func (m *Session) LogoutContext(ctx context.Context) error {
+	token := m.Token()
+	if token == nil {
+		return errNoToken
+	}
+
+	// prefer the refresh token, it is valid longer than the access token
+	revokeToken := token.RefreshToken
+	if revokeToken == "" {
+		revokeToken = token.AccessToken
+	}
+	if err := m.RevokeContext(ctx, revokeToken); err != nil {
+		return err
+	}
+
+	// clear the token and drop the client to stop refreshing the revoked token
+	m.storeMutex.Lock()
+	defer m.storeMutex.Unlock()
+	m.mutex.Lock()
+	m.token = nil
+	m.httpClient = nil
+	m.mutex.Unlock()
+
+	// delete the revoked token if the token store supports it
+	if store, ok := m.tokenStore().(TokenDeleter); ok {
+		return store.Delete()
+	}
+	return nil
+}
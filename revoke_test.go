@@ -0,0 +1,97 @@
+package fitbit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+	"golang.org/x/oauth2"
+)
+
+func TestRevoke(t *testing.T) {
+	_, session := newTestSession(t, nil)
+	token := session.Token()
+
+	if err := session.Revoke(token.RefreshToken); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	// all tokens of the user are revoked, also the access token
+	_, err := session.Profile(0)
+	if !errors.Is(err, fitbit.ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken after revoking, got %v", err)
+	}
+}
+
+func TestLogoutDeletesStoredToken(t *testing.T) {
+	store := fitbit.NewMemoryTokenStore(nil)
+	_, session := newTestSession(t, func(c *fitbit.Config) { c.TokenStore = store })
+	if _, err := store.Load(); err != nil {
+		t.Fatalf("token was not stored: %v", err)
+	}
+
+	if err := session.Logout(); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if _, err := store.Load(); !errors.Is(err, fitbit.ErrTokenNotFound) {
+		t.Errorf("token was not deleted, got %v", err)
+	}
+	if session.Token() != nil {
+		t.Error("token was not removed from the session")
+	}
+	if _, err := session.Profile(0); err == nil {
+		t.Error("request after logout succeeded")
+	}
+}
+
+func TestLogoutWithTokenChange(t *testing.T) {
+	_, session := newTestSession(t, nil)
+	var changes []*oauth2.Token
+	session.TokenChange = func(token *oauth2.Token) {
+		changes = append(changes, token)
+	}
+
+	if err := session.Logout(); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("TokenChange was called %d times during logout", len(changes))
+	}
+}
+
+func TestLogoutFailsIfRevokeFails(t *testing.T) {
+	store := fitbit.NewMemoryTokenStore(nil)
+	server, session := newTestSession(t, func(c *fitbit.Config) { c.TokenStore = store })
+	server.Inject(fitbittest.Fault{Path: "/oauth2/revoke", StatusCode: http.StatusInternalServerError})
+
+	if err := session.Logout(); err == nil {
+		t.Fatal("expected logout to fail")
+	}
+	if session.Token() == nil {
+		t.Error("token was removed although it was not revoked")
+	}
+	if _, err := store.Load(); err != nil {
+		t.Errorf("token was deleted although it was not revoked: %v", err)
+	}
+}
+
+func TestManagerLogout(t *testing.T) {
+	server, _ := newTestSession(t, nil)
+	store := fitbit.NewMemoryUserTokenStore()
+	manager := fitbit.NewManager(fitbit.ManagerConfig{Config: server.Config(), TokenStore: store})
+	if _, err := manager.Add("ABC123", server.Token("ABC123")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Logout(context.Background(), "ABC123"); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if _, err := store.Load("ABC123"); !errors.Is(err, fitbit.ErrTokenNotFound) {
+		t.Errorf("token was not deleted, got %v", err)
+	}
+	if _, err := manager.Session("ABC123"); !errors.Is(err, fitbit.ErrTokenNotFound) {
+		t.Errorf("session without token was returned, got %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package fitbit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point is a single value of a time series
+type Point struct {
+	Time  time.Time // Time is the start of the interval the value belongs to
+	Value float64   // Value is the measured value
+	Unit  string    // Unit is the unit of the value, empty for dimensionless values like steps
+}
+
+// Series is a normalized time series of a single metric
+type Series struct {
+	Metric   string  // Metric is the name of the metric, see the Metric constants
+	Intraday bool    // Intraday is true if the points are intraday values instead of daily values
+	Points   []Point // Points are the values of the series ordered as returned by the API
+}
+
+// Metric names of a Series
+const (
+	MetricSteps                    = "steps"
+	MetricCalories                 = "calories"
+	MetricDistance                 = "distance"
+	MetricFloors                   = "floors"
+	MetricElevation                = "elevation"
+	MetricMinutesSedentary         = "minutesSedentary"
+	MetricMinutesLightlyActive     = "minutesLightlyActive"
+	MetricMinutesFairlyActive      = "minutesFairlyActive"
+	MetricMinutesVeryActive        = "minutesVeryActive"
+	MetricActivityCalories         = "activityCalories"
+	MetricActiveZoneMinutes        = "activeZoneMinutes"
+	MetricFatBurnActiveZoneMinutes = "fatBurnActiveZoneMinutes"
+	MetricHeartRate                = "heartRate"
+	MetricRestingHeartRate         = "restingHeartRate"
+	MetricHRVDailyRmssd            = "hrvDailyRmssd"
+	MetricHRVDeepRmssd             = "hrvDeepRmssd"
+	MetricHRVRmssd                 = "hrvRmssd"
+	MetricHRVCoverage              = "hrvCoverage"
+	MetricHRVHighFrequency         = "hrvHighFrequency"
+	MetricHRVLowFrequency          = "hrvLowFrequency"
+	MetricSpO2                     = "spo2"
+	MetricSpO2Min                  = "spo2Min"
+	MetricSpO2Max                  = "spo2Max"
+	MetricTemperatureCore          = "temperatureCore"
+	MetricTemperatureSkinRelative  = "temperatureSkinRelative"
+	MetricBreathingRate            = "breathingRate"
+	MetricBreathingRateDeepSleep   = "breathingRateDeepSleep"
+	MetricBreathingRateLightSleep  = "breathingRateLightSleep"
+	MetricBreathingRateRemSleep    = "breathingRateRemSleep"
+	MetricVO2Max                   = "vo2Max"
+	MetricWeight                   = "weight"
+	MetricBMI                      = "bmi"
+	MetricBodyFat                  = "bodyFat"
+	MetricCaloriesIn               = "caloriesIn"
+	MetricWater                    = "water"
+	MetricMinutesAsleep            = "minutesAsleep"
+	MetricMinutesAwake             = "minutesAwake"
+	MetricTimeInBed                = "timeInBed"
+	MetricSleepEfficiency          = "sleepEfficiency"
+)
+
+// Units of the values of a Point
+const (
+	UnitBPM           = "bpm"
+	UnitMilliseconds  = "ms"
+	UnitMsSquared     = "ms²"
+	UnitPercent       = "%"
+	UnitMinutes       = "min"
+	UnitKilocalories  = "kcal"
+	UnitBreathsPerMin = "breaths/min"
+	UnitVO2Max        = "mL/kg/min"
+	UnitKgPerM2       = "kg/m²"
+	UnitCelsius       = "°C"
+	UnitFahrenheit    = "°F"
+	UnitKilometers    = "km"
+	UnitMiles         = "mi"
+	UnitMeters        = "m"
+	UnitFeet          = "ft"
+	UnitKilograms     = "kg"
+	UnitPounds        = "lb"
+	UnitStone         = "st"
+	UnitMilliliters   = "ml"
+	UnitFluidOunces   = "fl oz"
+)
+
+// UnitSystem is the unit system of the values returned by the Fitbit API, it depends on the locale of the session
+// https://dev.fitbit.com/build/reference/web-api/developer-guide/application-design/#Unit-Systems
+type UnitSystem string
+
+// Unit systems used by the Fitbit API
+const (
+	UnitSystemMetric UnitSystem = "METRIC"
+	UnitSystemUS     UnitSystem = "US"
+	UnitSystemUK     UnitSystem = "UK"
+)
+
+// UnitSystemOf returns the unit system used by the Fitbit API for the given locale
+func UnitSystemOf(locale string) UnitSystem {
+	switch locale {
+	case "en_US":
+		return UnitSystemUS
+	case "en_GB":
+		return UnitSystemUK
+	}
+	return UnitSystemMetric
+}
+
+// UnitSystem returns the unit system of the responses of this session
+func (m *Session) UnitSystem() UnitSystem {
+	return UnitSystemOf(m.locale)
+}
+
+// distance returns the unit of distances
+func (u UnitSystem) distance() string {
+	if u == UnitSystemUS {
+		return UnitMiles
+	}
+	return UnitKilometers
+}
+
+// elevation returns the unit of elevations
+func (u UnitSystem) elevation() string {
+	if u == UnitSystemUS {
+		return UnitFeet
+	}
+	return UnitMeters
+}
+
+// weight returns the unit of body weights
+func (u UnitSystem) weight() string {
+	switch u {
+	case UnitSystemUS:
+		return UnitPounds
+	case UnitSystemUK:
+		return UnitStone
+	}
+	return UnitKilograms
+}
+
+// temperature returns the unit of temperatures
+func (u UnitSystem) temperature() string {
+	if u == UnitSystemUS {
+		return UnitFahrenheit
+	}
+	return UnitCelsius
+}
+
+// liquid returns the unit of liquids
+func (u UnitSystem) liquid() string {
+	if u == UnitSystemUS {
+		return UnitFluidOunces
+	}
+	return UnitMilliliters
+}
+
+// seriesBuilder collects points of multiple metrics and returns them as series in the order of the first point
+type seriesBuilder struct {
+	loc    *time.Location
+	series []Series
+}
+
+// newSeriesBuilder returns a new builder parsing times in loc, nil is treated as UTC
+func newSeriesBuilder(loc *time.Location) *seriesBuilder {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &seriesBuilder{loc: loc}
+}
+
+// add appends a point to the series of the given metric
+func (b *seriesBuilder) add(metric string, intraday bool, t time.Time, value float64, unit string) {
+	for i := range b.series {
+		if b.series[i].Metric == metric && b.series[i].Intraday == intraday {
+			b.series[i].Points = append(b.series[i].Points, Point{Time: t, Value: value, Unit: unit})
+			return
+		}
+	}
+	b.series = append(b.series, Series{
+		Metric:   metric,
+		Intraday: intraday,
+		Points:   []Point{{Time: t, Value: value, Unit: unit}},
+	})
+}
+
+// addAt parses the time of a point and appends it to the series of the given metric
+func (b *seriesBuilder) addAt(metric string, intraday bool, at string, value float64, unit string) error {
+	t, err := b.parseTime(at)
+	if err != nil {
+		return err
+	}
+	b.add(metric, intraday, t, value, unit)
+	return nil
+}
+
+// addString parses the time and value of a point and appends it to the series of the given metric
+func (b *seriesBuilder) addString(metric string, intraday bool, at string, value string, unit string) error {
+	f, err := parseSeriesValue(value)
+	if err != nil {
+		return err
+	}
+	return b.addAt(metric, intraday, at, f, unit)
+}
+
+// parseTime parses a date or a local date time as returned by the Fitbit API
+func (b *seriesBuilder) parseTime(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", dateLayout} {
+		if t, err := time.ParseInLocation(layout, value, b.loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q within series", value)
+}
+
+// intraday returns a function adding intraday points of the given date
+// Intraday datasets only contain the time of day, all points of a dataset belong to the date of the dataset
+func (b *seriesBuilder) intraday(metric string, date string, unit string) (func(clock string, value float64) error, error) {
+	day, err := b.parseTime(date)
+	if err != nil {
+		return nil, err
+	}
+	return func(clock string, value float64) error {
+		c, err := time.Parse("15:04:05", clock)
+		if err != nil {
+			return fmt.Errorf("invalid time %q within series", clock)
+		}
+		t := time.Date(day.Year(), day.Month(), day.Day(), c.Hour(), c.Minute(), c.Second(), 0, b.loc)
+		b.add(metric, true, t, value, unit)
+		return nil
+	}, nil
+}
+
+// result returns the collected series
+func (b *seriesBuilder) result() []Series {
+	return b.series
+}
+
+// parseSeriesValue parses a numeric value returned as string, thousands separators are removed
+// A range like "44-48" (used by the cardio fitness score) returns the middle of the range
+func parseSeriesValue(value string) (float64, error) {
+	value = strings.ReplaceAll(value, ",", "")
+	if low, high, ok := strings.Cut(value, "-"); ok && low != "" {
+		l, errLow := strconv.ParseFloat(low, 64)
+		h, errHigh := strconv.ParseFloat(high, 64)
+		if errLow == nil && errHigh == nil {
+			return (l + h) / 2, nil
+		}
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q within series", value)
+	}
+	return f, nil
+}
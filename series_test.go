@@ -0,0 +1,53 @@
+package fitbit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+)
+
+func TestHeartIntradaySeriesWithEmptyDay(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	loc, err := session.Location(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second day has no heart rate, points of the third day must stay on the third day
+	first := time.Date(2024, time.January, 2, 23, 58, 0, 0, loc)
+	third := time.Date(2024, time.January, 4, 0, 1, 0, 0, loc)
+	server.Update("ABC123", func(u *fitbittest.User) {
+		u.Days["2024-01-02"] = &fitbittest.Day{HeartRate: []fitbittest.Sample{{Time: first, Value: 60}}}
+		u.Days["2024-01-04"] = &fitbittest.Day{HeartRate: []fitbittest.Sample{{Time: third, Value: 70}}}
+	})
+
+	days, err := session.HeartLogIntradayDaysBetween(fitbit.NewDate(2024, time.January, 2), fitbit.NewDate(2024, time.January, 4), "1min")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var points []fitbit.Point
+	for _, day := range days {
+		series, err := day.Series(loc, session.UnitSystem())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range series {
+			if s.Metric == fitbit.MetricHeartRate {
+				points = append(points, s.Points...)
+			}
+		}
+	}
+
+	if len(points) != 2 {
+		t.Fatalf("%d points, expected 2", len(points))
+	}
+	if !points[0].Time.Equal(first) || points[0].Value != 60 {
+		t.Errorf("first point %v, expected %v", points[0], first)
+	}
+	if !points[1].Time.Equal(third) || points[1].Value != 70 {
+		t.Errorf("point of the third day %v, expected %v", points[1], third)
+	}
+}
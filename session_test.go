@@ -0,0 +1,128 @@
+package fitbit_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+	"golang.org/x/oauth2"
+)
+
+// recordingStore is a token store remembering all saved refresh tokens
+type recordingStore struct {
+	mutex sync.Mutex
+	saved []*oauth2.Token
+}
+
+func (s *recordingStore) Load() (*oauth2.Token, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.saved) == 0 {
+		return nil, fitbit.ErrTokenNotFound
+	}
+	return s.saved[len(s.saved)-1], nil
+}
+
+func (s *recordingStore) Save(token *oauth2.Token) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.saved = append(s.saved, token)
+	return nil
+}
+
+// contains returns true if a token with the given refresh token was saved
+func (s *recordingStore) contains(refreshToken string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, token := range s.saved {
+		if token != nil && token.RefreshToken == refreshToken {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestSession returns a fake server with a single user and a session authorized for this user
+func newTestSession(t *testing.T, config func(c *fitbit.Config)) (*fitbittest.Server, *fitbit.Session) {
+	t.Helper()
+	server := fitbittest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetRateLimit(10000, time.Hour)
+	server.AddUser(fitbittest.User{
+		ID:       "ABC123",
+		Timezone: "Europe/Vienna",
+		Days: map[string]*fitbittest.Day{
+			"2024-01-01": {Steps: 8500, RestingHeartRate: 58},
+		},
+	})
+
+	c := server.Config()
+	if config != nil {
+		config(&c)
+	}
+	session := fitbit.New(c)
+	if _, err := session.Exchange(server.Authorize("ABC123")); err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	return server, session
+}
+
+func TestSessionConcurrentRequestsAndRefresh(t *testing.T) {
+	store := &recordingStore{}
+	server, session := newTestSession(t, func(c *fitbit.Config) { c.TokenStore = store })
+
+	const workers = 8
+	const iterations = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*iterations*2)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				if _, err := session.Profile(0); err != nil {
+					errs <- err
+				}
+				if _, err := session.HeartLogByDay("2024-01-01"); err != nil {
+					errs <- err
+				}
+				_ = session.GetRatelimit()
+				_ = session.Token()
+			}
+		}()
+	}
+
+	// replace the token while requests are running, every second token is expired to force a refresh
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < iterations; j++ {
+			token := server.Token("ABC123")
+			if j%2 == 0 {
+				token.Expiry = time.Now().Add(-time.Minute)
+			}
+			session.SetToken(token)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("request failed: %v", err)
+	}
+
+	// the token is refreshed after the last expired token was set
+	token := *session.Token()
+	token.Expiry = time.Now().Add(-time.Minute)
+	session.SetToken(&token)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatalf("request after refresh: %v", err)
+	}
+	if !store.contains(session.Token().RefreshToken) {
+		t.Errorf("rotated refresh token was not stored")
+	}
+}
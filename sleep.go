@@ -1,9 +1,10 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 	"time"
@@ -87,7 +88,7 @@ type SleepDay struct {
 		TotalMinutesAsleep int `json:"totalMinutesAsleep"`
 		TotalSleepRecords  int `json:"totalSleepRecords"`
 		TotalTimeInBed     int `json:"totalTimeInBed"`
-	} `json:"summary,omitempty"`
+	} `json:"summary,omitempty" merge:"sum"`
 	Meta struct {
 		RetryDuration int    `json:"retryDuration"`
 		State         string `json:"state"`
@@ -95,31 +96,83 @@ type SleepDay struct {
 }
 
 // SleepByDay returns the sleep data for a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) SleepByDay(day string) (SleepDay, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1.2/user/-/sleep/date/%s.json", day))
+	return m.SleepByDayContext(context.Background(), day)
+}
+
+// SleepByDayContext is like SleepByDay but uses ctx for the request
+func (m *Session) SleepByDayContext(ctx context.Context, day string) (SleepDay, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return SleepDay{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1.2/user/-/sleep/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return SleepDay{}, err
 	}
 
 	sleep := SleepDay{}
-	if err := json.Unmarshal(contents, &sleep); err != nil {
+	if err := m.decode("SleepByDay", contents, &sleep); err != nil {
 		return SleepDay{}, err
 	}
 
 	return sleep, nil
 }
 
+// SleepOn is like SleepByDay but takes a Date
+func (m *Session) SleepOn(day Date) (SleepDay, error) {
+	return m.SleepOnContext(context.Background(), day)
+}
+
+// SleepOnContext is like SleepOn but uses ctx for the request
+func (m *Session) SleepOnContext(ctx context.Context, day Date) (SleepDay, error) {
+	if err := checkDates(day); err != nil {
+		return SleepDay{}, err
+	}
+	return m.SleepByDayContext(ctx, day.String())
+}
+
 // SleepByDayRange returns the sleep data for a given date range
 // date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysSleep days are split into multiple requests
 func (m *Session) SleepByDayRange(startDay string, endDay string) (SleepDay, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1.2/user/-/sleep/date/%s/%s.json", startDay, endDay))
+	return m.SleepByDayRangeContext(context.Background(), startDay, endDay)
+}
+
+// SleepByDayRangeContext is like SleepByDayRange but uses ctx for the request
+func (m *Session) SleepByDayRangeContext(ctx context.Context, startDay string, endDay string) (SleepDay, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return SleepDay{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysSleep, m.sleepByDayRange)
+}
+
+// SleepBetween is like SleepByDayRange but takes Dates
+func (m *Session) SleepBetween(startDay Date, endDay Date) (SleepDay, error) {
+	return m.SleepBetweenContext(context.Background(), startDay, endDay)
+}
+
+// SleepBetweenContext is like SleepBetween but uses ctx for the request
+func (m *Session) SleepBetweenContext(ctx context.Context, startDay Date, endDay Date) (SleepDay, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return SleepDay{}, err
+	}
+	return m.SleepByDayRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// sleepByDayRange requests a single date range
+func (m *Session) sleepByDayRange(ctx context.Context, startDay string, endDay string) (SleepDay, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1.2/user/-/sleep/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return SleepDay{}, err
 	}
 
 	sleep := SleepDay{}
-	if err := json.Unmarshal(contents, &sleep); err != nil {
+	if err := m.decode("SleepByDayRange", contents, &sleep); err != nil {
 		return SleepDay{}, err
 	}
 
@@ -128,35 +181,38 @@ func (m *Session) SleepByDayRange(startDay string, endDay string) (SleepDay, err
 
 // SleepLogList returns the sleep log list for based on given parameters
 func (m *Session) SleepLogList(params LogListParameters) (SleepLogList, error) {
-	parameterList := url.Values{}
+	return m.SleepLogListContext(context.Background(), params)
+}
 
-	//nolint:gocritic
-	if params.BeforeDate != "" {
-		parameterList.Add("beforeDate", params.BeforeDate)
-		parameterList.Add("sort", "desc")
-	} else if params.AfterDate != "" {
-		parameterList.Add("afterDate", params.AfterDate)
-		parameterList.Add("sort", "asc")
-	} else {
-		return SleepLogList{}, errors.New("beforeDate or afterDate must be given")
+// SleepLogListContext is like SleepLogList but uses ctx for the request
+func (m *Session) SleepLogListContext(ctx context.Context, params LogListParameters) (SleepLogList, error) {
+	parameterList, err := params.query(20)
+	if err != nil {
+		return SleepLogList{}, err
 	}
 
-	if params.Limit > 0 {
-		if params.Limit > 20 {
-			return SleepLogList{}, errors.New("limit must be 20 or less")
-		}
-		parameterList.Add("limit", strconv.Itoa(params.Limit))
-	}
+	return m.sleepLogPage(ctx, parameterList)
+}
 
-	parameterList.Add("offset", strconv.Itoa(params.Offset))
+// SleepLogIter returns an iterator over all sleeps of the sleep log starting at the given parameters
+// Following pages are requested on demand until all sleeps are returned or the iteration is stopped
+func (m *Session) SleepLogIter(ctx context.Context, params LogListParameters) iter.Seq2[SleepLogEntry, error] {
+	parameterList, err := params.query(20)
+	return paginate(ctx, parameterList, err, func(ctx context.Context, query url.Values) ([]SleepLogEntry, string, error) {
+		page, err := m.sleepLogPage(ctx, query)
+		return page.Sleep, page.Pagination.Next, err
+	})
+}
 
-	contents, err := m.makeRequest("https://api.fitbit.com/1.2/user/-/sleep/list.json?" + parameterList.Encode())
+// sleepLogPage requests a single page of the sleep log list
+func (m *Session) sleepLogPage(ctx context.Context, parameterList url.Values) (SleepLogList, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1.2/user/-/sleep/list.json?"+parameterList.Encode())
 	if err != nil {
 		return SleepLogList{}, err
 	}
 
 	activityResponse := SleepLogList{}
-	if err := json.Unmarshal(contents, &activityResponse); err != nil {
+	if err := m.decode("SleepLogList", contents, &activityResponse); err != nil {
 		return SleepLogList{}, err
 	}
 
@@ -174,66 +230,81 @@ type SleepLogList struct {
 		Previous   string `json:"previous"`
 		Sort       string `json:"sort"`
 	} `json:"pagination"`
-	Sleep []struct {
-		AwakeCount      int    `json:"awakeCount,omitempty"`
-		AwakeDuration   int    `json:"awakeDuration,omitempty"`
-		AwakeningsCount int    `json:"awakeningsCount,omitempty"`
-		DateOfSleep     string `json:"dateOfSleep"`
-		Duration        int    `json:"duration"`
-		Efficiency      int    `json:"efficiency"`
-		EndTime         string `json:"endTime"`
-		InfoCode        int    `json:"infoCode"`
-		IsMainSleep     bool   `json:"isMainSleep"`
-		Levels          struct {
-			Data []struct {
-				DateTime string `json:"dateTime"`
-				Level    string `json:"level"`
-				Seconds  int    `json:"seconds"`
-			} `json:"data,omitempty"`
-			ShortData []struct {
-				DateTime string `json:"dateTime"`
-				Level    string `json:"level"`
-				Seconds  int    `json:"seconds"`
-			} `json:"shortData,omitempty"`
-			Summary struct {
-				Deep struct {
-					Count               int `json:"count"`
-					Minutes             int `json:"minutes"`
-					ThirtyDayAvgMinutes int `json:"thirtyDayAvgMinutes"`
-				} `json:"deep,omitempty"`
-				Light struct {
-					Count               int `json:"count"`
-					Minutes             int `json:"minutes"`
-					ThirtyDayAvgMinutes int `json:"thirtyDayAvgMinutes"`
-				} `json:"light,omitempty"`
-				Rem struct {
-					Count               int `json:"count"`
-					Minutes             int `json:"minutes"`
-					ThirtyDayAvgMinutes int `json:"thirtyDayAvgMinutes"`
-				} `json:"rem,omitempty"`
-				Wake struct {
-					Count               int `json:"count"`
-					Minutes             int `json:"minutes"`
-					ThirtyDayAvgMinutes int `json:"thirtyDayAvgMinutes"`
-				} `json:"wake,omitempty"`
-			} `json:"summary,omitempty"`
-		} `json:"levels,omitempty"`
-		LogID               int64  `json:"logId"`
-		MinutesAfterWakeup  int    `json:"minutesAfterWakeup"`
-		MinutesAsleep       int    `json:"minutesAsleep"`
-		MinutesAwake        int    `json:"minutesAwake"`
-		MinutesToFallAsleep int    `json:"minutesToFallAsleep"`
-		RestlessCount       int    `json:"restlessCount,omitempty"`
-		RestlessDuration    int    `json:"restlessDuration,omitempty"`
-		LogType             string `json:"logType"`
-		StartTime           string `json:"startTime"`
-		TimeInBed           int    `json:"timeInBed"`
-		Type                string `json:"type"`
-		MinuteData          []struct {
+	Sleep []SleepLogEntry `json:"sleep"`
+}
+
+// SleepLogEntry contains a single sleep of the sleep log list
+type SleepLogEntry struct {
+	AwakeCount      int    `json:"awakeCount,omitempty"`
+	AwakeDuration   int    `json:"awakeDuration,omitempty"`
+	AwakeningsCount int    `json:"awakeningsCount,omitempty"`
+	DateOfSleep     string `json:"dateOfSleep"`
+	Duration        int    `json:"duration"`
+	Efficiency      int    `json:"efficiency"`
+	EndTime         string `json:"endTime"`
+	InfoCode        int    `json:"infoCode"`
+	IsMainSleep     bool   `json:"isMainSleep"`
+	Levels          struct {
+		Data []struct {
 			DateTime string `json:"dateTime"`
-			Value    string `json:"value"`
-		} `json:"minuteData,omitempty"`
-	} `json:"sleep"`
+			Level    string `json:"level"`
+			Seconds  int    `json:"seconds"`
+		} `json:"data,omitempty"`
+		ShortData []struct {
+			DateTime string `json:"dateTime"`
+			Level    string `json:"level"`
+			Seconds  int    `json:"seconds"`
+		} `json:"shortData,omitempty"`
+		Summary struct {
+			Deep struct {
+				Count               int `json:"count"`
+				Minutes             int `json:"minutes"`
+				ThirtyDayAvgMinutes int `json:"thirtyDayAvgMinutes"`
+			} `json:"deep,omitempty"`
+			Light struct {
+				Count               int `json:"count"`
+				Minutes             int `json:"minutes"`
+				ThirtyDayAvgMinutes int `json:"thirtyDayAvgMinutes"`
+			} `json:"light,omitempty"`
+			Rem struct {
+				Count               int `json:"count"`
+				Minutes             int `json:"minutes"`
+				ThirtyDayAvgMinutes int `json:"thirtyDayAvgMinutes"`
+			} `json:"rem,omitempty"`
+			Wake struct {
+				Count               int `json:"count"`
+				Minutes             int `json:"minutes"`
+				ThirtyDayAvgMinutes int `json:"thirtyDayAvgMinutes"`
+			} `json:"wake,omitempty"`
+			Asleep struct {
+				Count   int `json:"count"`
+				Minutes int `json:"minutes"`
+			} `json:"asleep,omitempty"`
+			Awake struct {
+				Count   int `json:"count"`
+				Minutes int `json:"minutes"`
+			} `json:"awake,omitempty"`
+			Restless struct {
+				Count   int `json:"count"`
+				Minutes int `json:"minutes"`
+			} `json:"restless,omitempty"`
+		} `json:"summary,omitempty"`
+	} `json:"levels,omitempty"`
+	LogID               int64  `json:"logId"`
+	MinutesAfterWakeup  int    `json:"minutesAfterWakeup"`
+	MinutesAsleep       int    `json:"minutesAsleep"`
+	MinutesAwake        int    `json:"minutesAwake"`
+	MinutesToFallAsleep int    `json:"minutesToFallAsleep"`
+	RestlessCount       int    `json:"restlessCount,omitempty"`
+	RestlessDuration    int    `json:"restlessDuration,omitempty"`
+	LogType             string `json:"logType"`
+	StartTime           string `json:"startTime"`
+	TimeInBed           int    `json:"timeInBed"`
+	Type                string `json:"type"`
+	MinuteData          []struct {
+		DateTime string `json:"dateTime"`
+		Value    string `json:"value"`
+	} `json:"minuteData,omitempty"`
 }
 
 // AddSleep adds a new sleep record
@@ -241,38 +312,67 @@ type SleepLogList struct {
 // startTime in form of HH:mm
 // duration in milliseconds
 func (m *Session) AddSleep(date string, startTime string, duration int64) (SleepDay, error) {
+	return m.AddSleepContext(context.Background(), date, startTime, duration)
+}
+
+// AddSleepContext is like AddSleep but uses ctx for the request
+func (m *Session) AddSleepContext(ctx context.Context, date string, startTime string, duration int64) (SleepDay, error) {
 	parameterList := url.Values{}
 	if date != "" {
 		parameterList.Add("date", date)
 	} else {
 		return SleepDay{}, errors.New("date must be given")
 	}
+	if err := checkPostDate(date); err != nil {
+		return SleepDay{}, err
+	}
 	if startTime != "" {
 		parameterList.Add("startTime", startTime)
 	} else {
 		return SleepDay{}, errors.New("startTime must be given")
 	}
+	if err := checkTimeOfDay(startTime); err != nil {
+		return SleepDay{}, err
+	}
 
 	if duration > 0 {
 		parameterList.Add("duration", strconv.FormatInt(duration, 10))
 	}
 
-	contents, err := m.makeRequest("https://api.fitbit.com/1.2/user/-/sleep.json?" + parameterList.Encode())
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1.2/user/-/sleep.json?"+parameterList.Encode())
 	if err != nil {
 		return SleepDay{}, err
 	}
 
 	activityResponse := SleepDay{}
-	if err := json.Unmarshal(contents, &activityResponse); err != nil {
+	if err := m.decode("AddSleep", contents, &activityResponse); err != nil {
 		return SleepDay{}, err
 	}
 
 	return activityResponse, nil
 }
 
+// AddSleepOn is like AddSleep but takes a Date and TimeOfDay values
+func (m *Session) AddSleepOn(date Date, startTime TimeOfDay, duration int64) (SleepDay, error) {
+	return m.AddSleepOnContext(context.Background(), date, startTime, duration)
+}
+
+// AddSleepOnContext is like AddSleepOn but uses ctx for the request
+func (m *Session) AddSleepOnContext(ctx context.Context, date Date, startTime TimeOfDay, duration int64) (SleepDay, error) {
+	if err := checkDates(date); err != nil {
+		return SleepDay{}, err
+	}
+	return m.AddSleepContext(ctx, date.String(), startTime.String(), duration)
+}
+
 // RemoveSleep removes a sleep entry
 func (m *Session) RemoveSleep(sleepID uint64) error {
-	_, err := m.makeDELETERequest(fmt.Sprintf("https://api.fitbit.com/1.2/user/-/sleep/%d.json", sleepID))
+	return m.RemoveSleepContext(context.Background(), sleepID)
+}
+
+// RemoveSleepContext is like RemoveSleep but uses ctx for the request
+func (m *Session) RemoveSleepContext(ctx context.Context, sleepID uint64) error {
+	_, err := m.makeDELETERequest(ctx, fmt.Sprintf("%s/1.2/user/-/sleep/%d.json", m.apiURL, sleepID))
 	if err != nil {
 		return err
 	}
@@ -282,13 +382,18 @@ func (m *Session) RemoveSleep(sleepID uint64) error {
 
 // SleepGoal requests the sleep goal of the user
 func (m *Session) SleepGoal() (SleepGoal, error) {
-	contents, err := m.makeRequest("https://api.fitbit.com/1.2/user/-/sleep/goal.json")
+	return m.SleepGoalContext(context.Background())
+}
+
+// SleepGoalContext is like SleepGoal but uses ctx for the request
+func (m *Session) SleepGoalContext(ctx context.Context) (SleepGoal, error) {
+	contents, err := m.makeRequest(ctx, m.apiURL+"/1.2/user/-/sleep/goal.json")
 	if err != nil {
 		return SleepGoal{}, err
 	}
 
 	sleepGoalResponse := SleepGoal{}
-	if err := json.Unmarshal(contents, &sleepGoalResponse); err != nil {
+	if err := m.decode("SleepGoal", contents, &sleepGoalResponse); err != nil {
 		return SleepGoal{}, err
 	}
 
@@ -314,7 +419,12 @@ type SleepGoal struct {
 
 // SetSleepGoal requests the sleep goal of the user
 func (m *Session) SetSleepGoal(minDuration int) (SleepGoal, error) {
-	contents, err := m.makePOSTRequest("https://api.fitbit.com/1.2/user/-/sleep/goal.json", map[string]string{
+	return m.SetSleepGoalContext(context.Background(), minDuration)
+}
+
+// SetSleepGoalContext is like SetSleepGoal but uses ctx for the request
+func (m *Session) SetSleepGoalContext(ctx context.Context, minDuration int) (SleepGoal, error) {
+	contents, err := m.makePOSTRequest(ctx, m.apiURL+"/1.2/user/-/sleep/goal.json", map[string]string{
 		"duration": strconv.Itoa(minDuration),
 	})
 	if err != nil {
@@ -322,9 +432,26 @@ func (m *Session) SetSleepGoal(minDuration int) (SleepGoal, error) {
 	}
 
 	sleepGoalResponse := SleepGoal{}
-	if err := json.Unmarshal(contents, &sleepGoalResponse); err != nil {
+	if err := m.decode("SetSleepGoal", contents, &sleepGoalResponse); err != nil {
 		return SleepGoal{}, err
 	}
 
 	return sleepGoalResponse, nil
 }
+
+// Series returns the minutes asleep, minutes awake, time in bed and efficiency of each sleep as normalized time series
+// Points are located at the start time of the sleep, loc is the timezone of the user
+func (s SleepDay) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, sleep := range s.Sleep {
+		t, err := b.parseTime(sleep.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		b.add(MetricMinutesAsleep, false, t, float64(sleep.MinutesAsleep), UnitMinutes)
+		b.add(MetricMinutesAwake, false, t, float64(sleep.MinutesAwake), UnitMinutes)
+		b.add(MetricTimeInBed, false, t, float64(sleep.TimeInBed), UnitMinutes)
+		b.add(MetricSleepEfficiency, false, t, float64(sleep.Efficiency), UnitPercent)
+	}
+	return b.result(), nil
+}
@@ -1,13 +1,12 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"time"
 )
 
-// ! ATTENTION !
-// Functions are untested and may not work as intended.
-
+// SpO2 contains the daily SpO2 summary of a day
 type SpO2 struct {
 	DateTime string `json:"dateTime"`
 	Value    struct {
@@ -18,21 +17,90 @@ type SpO2 struct {
 }
 
 // SleepByDay returns the sleep data for a given date
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) SpO2ByDay(day string) (SpO2, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/spo2/date/%s.json", day))
+	return m.SpO2ByDayContext(context.Background(), day)
+}
+
+// SpO2ByDayContext is like SpO2ByDay but uses ctx for the request
+func (m *Session) SpO2ByDayContext(ctx context.Context, day string) (SpO2, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return SpO2{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/spo2/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return SpO2{}, err
 	}
 
 	spo2 := SpO2{}
-	if err := json.Unmarshal(contents, &spo2); err != nil {
+	if err := m.decode("SpO2ByDay", contents, &spo2); err != nil {
+		return SpO2{}, err
+	}
+
+	return spo2, nil
+}
+
+// SpO2On is like SpO2ByDay but takes a Date
+func (m *Session) SpO2On(day Date) (SpO2, error) {
+	return m.SpO2OnContext(context.Background(), day)
+}
+
+// SpO2OnContext is like SpO2On but uses ctx for the request
+func (m *Session) SpO2OnContext(ctx context.Context, day Date) (SpO2, error) {
+	if err := checkDates(day); err != nil {
 		return SpO2{}, err
 	}
+	return m.SpO2ByDayContext(ctx, day.String())
+}
+
+// SpO2ByDateRange returns the SpO2 summary data for a given date range
+// date must be in the format yyyy-MM-dd
+// ranges longer than MaxDaysSpO2 days are split into multiple requests
+func (m *Session) SpO2ByDateRange(startDay string, endDay string) ([]SpO2, error) {
+	return m.SpO2ByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// SpO2ByDateRangeContext is like SpO2ByDateRange but uses ctx for the request
+func (m *Session) SpO2ByDateRangeContext(ctx context.Context, startDay string, endDay string) ([]SpO2, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysSpO2, m.spO2ByDateRange)
+}
+
+// SpO2Between is like SpO2ByDateRange but takes Dates
+func (m *Session) SpO2Between(startDay Date, endDay Date) ([]SpO2, error) {
+	return m.SpO2BetweenContext(context.Background(), startDay, endDay)
+}
+
+// SpO2BetweenContext is like SpO2Between but uses ctx for the request
+func (m *Session) SpO2BetweenContext(ctx context.Context, startDay Date, endDay Date) ([]SpO2, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return nil, err
+	}
+	return m.SpO2ByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// spO2ByDateRange requests a single date range
+func (m *Session) spO2ByDateRange(ctx context.Context, startDay string, endDay string) ([]SpO2, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/spo2/date/%s/%s.json", m.apiURL, startDay, endDay))
+	if err != nil {
+		return nil, err
+	}
+
+	spo2 := []SpO2{}
+	if err := m.decode("SpO2ByDateRange", contents, &spo2); err != nil {
+		return nil, err
+	}
 
 	return spo2, nil
 }
 
+// SpO2Intraday contains the SpO2 values of a day in minute accuracy
 type SpO2Intraday struct {
 	DateTime string `json:"dateTime"`
 	Minutes  []struct {
@@ -42,20 +110,81 @@ type SpO2Intraday struct {
 }
 
 // SpO2ByDayIntraday returns the sleep data for a given date with intraday accuration
-// date must be in the format yyyy-MM-dd
+// date must be in the format yyyy-MM-dd or today, an empty date is treated as today
 func (m *Session) SpO2ByDayIntraday(day string) (SpO2Intraday, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/spo2/date/%s/all.json", day))
+	return m.SpO2ByDayIntradayContext(context.Background(), day)
+}
+
+// SpO2ByDayIntradayContext is like SpO2ByDayIntraday but uses ctx for the request
+func (m *Session) SpO2ByDayIntradayContext(ctx context.Context, day string) (SpO2Intraday, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return SpO2Intraday{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/spo2/date/%s/all.json", m.apiURL, day))
 	if err != nil {
 		return SpO2Intraday{}, err
 	}
 
 	spo2 := SpO2Intraday{}
-	if err := json.Unmarshal(contents, &spo2); err != nil {
+	if err := m.decode("SpO2ByDayIntraday", contents, &spo2); err != nil {
 		return SpO2Intraday{}, err
 	}
 
 	return spo2, nil
 }
 
+// SpO2IntradayOn is like SpO2ByDayIntraday but takes a Date
+func (m *Session) SpO2IntradayOn(day Date) (SpO2Intraday, error) {
+	return m.SpO2IntradayOnContext(context.Background(), day)
+}
+
+// SpO2IntradayOnContext is like SpO2IntradayOn but uses ctx for the request
+func (m *Session) SpO2IntradayOnContext(ctx context.Context, day Date) (SpO2Intraday, error) {
+	if err := checkDates(day); err != nil {
+		return SpO2Intraday{}, err
+	}
+	return m.SpO2ByDayIntradayContext(ctx, day.String())
+}
+
 // TODO: SpO2ByDayRange
 // TODO: SpO2IntradayByDayRange
+
+// Series returns the average, minimum and maximum SpO2 as normalized time series, loc is the timezone of the user
+func (s SpO2) Series(loc *time.Location, units UnitSystem) ([]Series, error) {
+	return SpO2Series([]SpO2{s}, loc, units)
+}
+
+// SpO2Series returns the average, minimum and maximum SpO2 of multiple days as normalized time series
+// loc is the timezone of the user
+func SpO2Series(days []SpO2, loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, day := range days {
+		// days without data are returned without a date
+		if day.DateTime == "" {
+			continue
+		}
+		if err := b.addAt(MetricSpO2, false, day.DateTime, day.Value.Avg, UnitPercent); err != nil {
+			return nil, err
+		}
+		if err := b.addAt(MetricSpO2Min, false, day.DateTime, day.Value.Min, UnitPercent); err != nil {
+			return nil, err
+		}
+		if err := b.addAt(MetricSpO2Max, false, day.DateTime, day.Value.Max, UnitPercent); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
+
+// Series returns the intraday SpO2 as normalized time series, loc is the timezone of the user
+func (s SpO2Intraday) Series(loc *time.Location, _ UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, minute := range s.Minutes {
+		if err := b.addAt(MetricSpO2, true, minute.Minute, minute.Value, UnitPercent); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
@@ -0,0 +1,96 @@
+package fitbit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// maxNotificationSize limits the size of a notification body read by SubscriberHandler
+const maxNotificationSize = 1 << 20
+
+// SubscriberHandler is a http.Handler receiving subscription notifications of Fitbit
+// It answers verification requests of the subscriber, verifies the X-Fitbit-Signature of notifications
+// and passes the decoded notifications to Callback
+// https://dev.fitbit.com/build/reference/web-api/developer-guide/using-subscriptions/
+type SubscriberHandler struct {
+	// ClientSecret is the client secret of the application used to verify the signature of notifications
+	ClientSecret string
+	// VerificationCode is the verification code of the subscriber shown within the application settings
+	VerificationCode string
+	// Callback is called with all notifications of a request before the request is answered
+	// Fitbit expects an answer within 5 seconds, so long running tasks should be processed asynchronously
+	// If an error is returned, Fitbit is answered with 500 and will retry to send the notifications later
+	Callback func(ctx context.Context, notifications []Subscription) error
+}
+
+// NewSubscriberHandler creates a new handler for subscription notifications
+func NewSubscriberHandler(clientSecret string, verificationCode string, callback func(ctx context.Context, notifications []Subscription) error) *SubscriberHandler {
+	return &SubscriberHandler{
+		ClientSecret:     clientSecret,
+		VerificationCode: verificationCode,
+		Callback:         callback,
+	}
+}
+
+// ServeHTTP handles verification requests and notifications
+func (h *SubscriberHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// verification request, answer with 204 on correct code and 404 otherwise
+	if verify, ok := r.URL.Query()["verify"]; ok {
+		if len(verify) > 0 && h.VerificationCode != "" &&
+			subtle.ConstantTimeCompare([]byte(verify[0]), []byte(h.VerificationCode)) == 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxNotificationSize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// notifications with invalid signatures should be answered with 404 as of the documentation
+	if !VerifySignature(h.ClientSecret, body, r.Header.Get("X-Fitbit-Signature")) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	notifications := []Subscription{}
+	if err := json.Unmarshal(body, &notifications); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if h.Callback != nil {
+		if err := h.Callback(r.Context(), notifications); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifySignature checks the X-Fitbit-Signature of a notification body
+// The signature is the base64 encoded HMAC-SHA1 of the body using the client secret followed by "&" as key
+func VerifySignature(clientSecret string, body []byte, signature string) bool {
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || clientSecret == "" {
+		return false
+	}
+	mac := hmac.New(sha1.New, []byte(clientSecret+"&"))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
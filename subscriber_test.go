@@ -0,0 +1,111 @@
+package fitbit_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+const (
+	testClientSecret     = "secret"
+	testVerificationCode = "verify-me"
+)
+
+// sign returns the X-Fitbit-Signature of body
+func sign(body []byte) string {
+	mac := hmac.New(sha1.New, []byte(testClientSecret+"&"))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// notify sends a request to handler and returns the status code of the response
+func notify(handler http.Handler, method string, target string, body []byte, signature string) int {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	if signature != "" {
+		req.Header.Set("X-Fitbit-Signature", signature)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestSubscriberHandler(t *testing.T) {
+	body := []byte(`[{"collectionType":"activities","date":"2024-01-01","ownerId":"ABC123","ownerType":"user","subscriptionId":"1"}]`)
+	oversized := append(append([]byte{}, body...), bytes.Repeat([]byte(" "), 2<<20)...)
+
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		body      []byte
+		signature string
+		callback  error
+		status    int
+		delivered bool
+	}{
+		{name: "valid signature", method: http.MethodPost, target: "/", body: body, signature: sign(body), status: http.StatusNoContent, delivered: true},
+		{name: "invalid signature", method: http.MethodPost, target: "/", body: body, signature: sign([]byte("other")), status: http.StatusNotFound},
+		{name: "malformed signature", method: http.MethodPost, target: "/", body: body, signature: "not base64!", status: http.StatusNotFound},
+		{name: "missing signature", method: http.MethodPost, target: "/", body: body, status: http.StatusNotFound},
+		{name: "verification code", method: http.MethodGet, target: "/?verify=" + testVerificationCode, status: http.StatusNoContent},
+		{name: "wrong verification code", method: http.MethodGet, target: "/?verify=wrong", status: http.StatusNotFound},
+		{name: "empty verification code", method: http.MethodGet, target: "/?verify=", status: http.StatusNotFound},
+		{name: "not a POST request", method: http.MethodGet, target: "/", body: body, signature: sign(body), status: http.StatusNotFound},
+		// the body is truncated after 1 MiB, so the signature of the full body does not match
+		{name: "oversized body", method: http.MethodPost, target: "/", body: oversized, signature: sign(oversized), status: http.StatusNotFound},
+		{name: "invalid JSON", method: http.MethodPost, target: "/", body: []byte("{"), signature: sign([]byte("{")), status: http.StatusBadRequest},
+		{name: "callback error", method: http.MethodPost, target: "/", body: body, signature: sign(body), callback: errors.New("store unavailable"),
+			status: http.StatusInternalServerError, delivered: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var delivered []fitbit.Subscription
+			handler := fitbit.NewSubscriberHandler(testClientSecret, testVerificationCode, func(ctx context.Context, notifications []fitbit.Subscription) error {
+				delivered = notifications
+				return test.callback
+			})
+
+			if status := notify(handler, test.method, test.target, test.body, test.signature); status != test.status {
+				t.Errorf("status %d, expected %d", status, test.status)
+			}
+			if !test.delivered {
+				if delivered != nil {
+					t.Errorf("notifications %v were delivered", delivered)
+				}
+				return
+			}
+			if len(delivered) != 1 || delivered[0].OwnerID != "ABC123" || delivered[0].Date != "2024-01-01" {
+				t.Errorf("unexpected notifications %+v", delivered)
+			}
+		})
+	}
+}
+
+func TestSubscriberHandlerWithoutVerificationCode(t *testing.T) {
+	handler := fitbit.NewSubscriberHandler(testClientSecret, "", nil)
+	if status := notify(handler, http.MethodGet, "/?verify=", nil, ""); status != http.StatusNotFound {
+		t.Errorf("status %d, expected %d", status, http.StatusNotFound)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`[]`)
+	if !fitbit.VerifySignature(testClientSecret, body, sign(body)) {
+		t.Error("valid signature was rejected")
+	}
+	if fitbit.VerifySignature("other", body, sign(body)) {
+		t.Error("signature of another client secret was accepted")
+	}
+	if fitbit.VerifySignature("", body, sign(body)) {
+		t.Error("signature was accepted without client secret")
+	}
+}
@@ -1,9 +1,12 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
 )
 
 // Subscription contains response and request data of fitbit eventsx
@@ -23,55 +26,200 @@ type SubscriptionList struct {
 
 // AddSubscription adds a new subscription where fitbit sends a request on changes caused by the user
 func (m *Session) AddSubscription(collectionPath string, uniqueID int) (bool, Subscription, error) {
+	return m.AddSubscriptionContext(context.Background(), collectionPath, uniqueID)
+}
+
+// AddSubscriptionContext is like AddSubscription but uses ctx for the request
+func (m *Session) AddSubscriptionContext(ctx context.Context, collectionPath string, uniqueID int) (bool, Subscription, error) {
 	if uniqueID == 0 {
 		return false, Subscription{}, errors.New("no unique subscription id given")
 	}
-	if collectionPath != "" {
-		collectionPath += "/"
-	}
-	contents, err := m.makePOSTRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/%sapiSubscriptions/%d.json", collectionPath, uniqueID), nil)
+	subscription, err := m.AddSubscriptionByIDContext(ctx, collectionPath, strconv.Itoa(uniqueID), "")
 	if err != nil {
 		return false, Subscription{}, err
 	}
+	return true, subscription, nil
+}
+
+// AddSubscriptionByID adds a new subscription with the given subscription id where fitbit sends a request on changes caused by the user
+// subscriberID selects the subscriber of the application receiving the notifications, the default subscriber is used if empty
+func (m *Session) AddSubscriptionByID(collectionPath string, subscriptionID string, subscriberID string) (Subscription, error) {
+	return m.AddSubscriptionByIDContext(context.Background(), collectionPath, subscriptionID, subscriberID)
+}
+
+// AddSubscriptionByIDContext is like AddSubscriptionByID but uses ctx for the request
+func (m *Session) AddSubscriptionByIDContext(ctx context.Context, collectionPath string, subscriptionID string, subscriberID string) (Subscription, error) {
+	if subscriptionID == "" {
+		return Subscription{}, errors.New("no unique subscription id given")
+	}
+	contents, err := m.doRequest(ctx, "POST", m.subscriptionURL(collectionPath, subscriptionID), url.Values{}, subscriberHeader(subscriberID))
+	if err != nil {
+		return Subscription{}, err
+	}
 
 	subscription := Subscription{}
-	if err := json.Unmarshal(contents, &subscription); err != nil {
-		return false, Subscription{}, err
+	if err := m.decode("AddSubscriptionByID", contents, &subscription); err != nil {
+		return Subscription{}, err
 	}
 
-	return true, subscription, nil
+	return subscription, nil
 }
 
 // RemoveSubscription removes a previously added subscription
 func (m *Session) RemoveSubscription(collectionPath string, uniqueID int) (bool, error) {
+	return m.RemoveSubscriptionContext(context.Background(), collectionPath, uniqueID)
+}
+
+// RemoveSubscriptionContext is like RemoveSubscription but uses ctx for the request
+func (m *Session) RemoveSubscriptionContext(ctx context.Context, collectionPath string, uniqueID int) (bool, error) {
 	if uniqueID == 0 {
 		return false, errors.New("no unique subscription id given")
 	}
-	if collectionPath != "" {
-		collectionPath += "/"
-	}
-	_, err := m.makeDELETERequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/%sapiSubscriptions/%d.json", collectionPath, uniqueID))
-	if err != nil {
+	if err := m.RemoveSubscriptionByIDContext(ctx, collectionPath, strconv.Itoa(uniqueID), ""); err != nil {
 		return false, err
 	}
-
 	return true, nil
 }
 
+// RemoveSubscriptionByID removes a previously added subscription with the given subscription id
+// subscriberID selects the subscriber of the application, the default subscriber is used if empty
+func (m *Session) RemoveSubscriptionByID(collectionPath string, subscriptionID string, subscriberID string) error {
+	return m.RemoveSubscriptionByIDContext(context.Background(), collectionPath, subscriptionID, subscriberID)
+}
+
+// RemoveSubscriptionByIDContext is like RemoveSubscriptionByID but uses ctx for the request
+func (m *Session) RemoveSubscriptionByIDContext(ctx context.Context, collectionPath string, subscriptionID string, subscriberID string) error {
+	if subscriptionID == "" {
+		return errors.New("no unique subscription id given")
+	}
+	_, err := m.doRequest(ctx, "DELETE", m.subscriptionURL(collectionPath, subscriptionID), nil, subscriberHeader(subscriberID))
+	return err
+}
+
 // GetSubscriptions get's a list of current subscriptions
 func (m *Session) GetSubscriptions(collectionPath string) (bool, SubscriptionList, error) {
+	return m.GetSubscriptionsContext(context.Background(), collectionPath)
+}
+
+// GetSubscriptionsContext is like GetSubscriptions but uses ctx for the request
+func (m *Session) GetSubscriptionsContext(ctx context.Context, collectionPath string) (bool, SubscriptionList, error) {
 	if collectionPath != "" {
 		collectionPath += "/"
 	}
-	contents, err := m.makePOSTRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/%sapiSubscriptions.json", collectionPath), nil)
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/%sapiSubscriptions.json", m.apiURL, collectionPath))
 	if err != nil {
 		return false, SubscriptionList{}, err
 	}
 
 	subscription := SubscriptionList{}
-	if err := json.Unmarshal(contents, &subscription); err != nil {
+	if err := m.decode("GetSubscriptions", contents, &subscription); err != nil {
 		return false, SubscriptionList{}, err
 	}
 
 	return true, subscription, nil
 }
+
+// subscriptionURL returns the url of a single subscription
+func (m *Session) subscriptionURL(collectionPath string, subscriptionID string) string {
+	if collectionPath != "" {
+		collectionPath += "/"
+	}
+	return fmt.Sprintf("%s/1/user/-/%sapiSubscriptions/%s.json", m.apiURL, collectionPath, url.PathEscape(subscriptionID))
+}
+
+// subscriberHeader returns the header selecting the subscriber, nil for the default subscriber
+func subscriberHeader(subscriberID string) http.Header {
+	if subscriberID == "" {
+		return nil
+	}
+	return http.Header{"X-Fitbit-Subscriber-Id": []string{subscriberID}}
+}
+
+// DesiredSubscription describes a subscription which should exist for a user
+type DesiredSubscription struct {
+	CollectionPath string // CollectionPath is the collection like activities or sleep, empty for all collections
+	SubscriptionID string // SubscriptionID is the unique id of the subscription (required)
+	SubscriberID   string // SubscriberID is the subscriber receiving the notifications, empty for the default subscriber
+}
+
+// SubscriptionReport describes the changes made by EnsureSubscriptions
+type SubscriptionReport struct {
+	Added     []Subscription // Added contains the subscriptions which were missing and are added
+	Removed   []Subscription // Removed contains the existing subscriptions which were not desired and are removed
+	Unchanged []Subscription // Unchanged contains the existing subscriptions which are desired
+}
+
+// EnsureSubscriptions adds all missing desired subscriptions of the user and removes existing subscriptions which are not desired
+// The report contains all changes made, also if an error is returned
+func (m *Session) EnsureSubscriptions(desired []DesiredSubscription) (SubscriptionReport, error) {
+	return m.EnsureSubscriptionsContext(context.Background(), desired)
+}
+
+// EnsureSubscriptionsContext is like EnsureSubscriptions but uses ctx for the requests
+func (m *Session) EnsureSubscriptionsContext(ctx context.Context, desired []DesiredSubscription) (SubscriptionReport, error) {
+	report := SubscriptionReport{}
+
+	_, existing, err := m.GetSubscriptionsContext(ctx, "")
+	if err != nil {
+		return report, err
+	}
+
+	// match existing subscriptions with desired ones
+	found := make([]bool, len(desired))
+	var stale []Subscription
+	for _, subscription := range existing.APISubscriptions {
+		matched := false
+		for i, want := range desired {
+			if subscriptionMatches(want, subscription) {
+				found[i] = true
+				matched = true
+			}
+		}
+		if matched {
+			report.Unchanged = append(report.Unchanged, subscription)
+		} else {
+			stale = append(stale, subscription)
+		}
+	}
+
+	var errs []error
+	// remove stale subscriptions first, subscription ids must be unique per subscriber
+	for _, subscription := range stale {
+		err := m.RemoveSubscriptionByIDContext(ctx, subscriptionCollectionPath(subscription.CollectionType), subscription.SubscriptionID, subscription.SubscriberID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("removing subscription %s: %w", subscription.SubscriptionID, err))
+			continue
+		}
+		report.Removed = append(report.Removed, subscription)
+	}
+
+	for i, want := range desired {
+		if found[i] {
+			continue
+		}
+		subscription, err := m.AddSubscriptionByIDContext(ctx, want.CollectionPath, want.SubscriptionID, want.SubscriberID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("adding subscription %s: %w", want.SubscriptionID, err))
+			continue
+		}
+		report.Added = append(report.Added, subscription)
+	}
+
+	return report, errors.Join(errs...)
+}
+
+// subscriptionCollectionPath returns the collection path of a listed collection type
+// subscriptions for all collections are listed with collection type user
+func subscriptionCollectionPath(collectionType string) string {
+	if collectionType == "user" {
+		return ""
+	}
+	return collectionType
+}
+
+// subscriptionMatches returns true if the existing subscription fulfills the desired subscription
+func subscriptionMatches(want DesiredSubscription, existing Subscription) bool {
+	return want.SubscriptionID == existing.SubscriptionID &&
+		want.CollectionPath == subscriptionCollectionPath(existing.CollectionType) &&
+		(want.SubscriberID == "" || want.SubscriberID == existing.SubscriberID)
+}
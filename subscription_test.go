@@ -0,0 +1,154 @@
+package fitbit_test
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+)
+
+// subscriptionIDs returns the sorted ids of the subscriptions
+func subscriptionIDs(subscriptions []fitbit.Subscription) string {
+	ids := make([]string, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		ids = append(ids, subscription.SubscriptionID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// addSubscriptions adds the given subscriptions to the user of the session
+func addSubscriptions(t *testing.T, session *fitbit.Session, subscriptions ...fitbit.DesiredSubscription) {
+	t.Helper()
+	for _, subscription := range subscriptions {
+		if _, err := session.AddSubscriptionByID(subscription.CollectionPath, subscription.SubscriptionID, subscription.SubscriberID); err != nil {
+			t.Fatalf("adding subscription %s: %v", subscription.SubscriptionID, err)
+		}
+	}
+}
+
+// countChanges returns the number of requests adding or removing subscriptions
+func countChanges(requests []fitbittest.Request) int {
+	changes := 0
+	for _, request := range requests {
+		if request.Method == http.MethodPost || request.Method == http.MethodDelete {
+			changes++
+		}
+	}
+	return changes
+}
+
+func TestEnsureSubscriptions(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	addSubscriptions(t, session,
+		fitbit.DesiredSubscription{CollectionPath: "activities", SubscriptionID: "activities-1"},
+		fitbit.DesiredSubscription{CollectionPath: "sleep", SubscriptionID: "stale-1"},
+		fitbit.DesiredSubscription{SubscriptionID: "all-1"},
+	)
+
+	desired := []fitbit.DesiredSubscription{
+		{CollectionPath: "activities", SubscriptionID: "activities-1"},
+		// subscriptions of all collections are listed with the collection type user
+		{SubscriptionID: "all-1"},
+		{CollectionPath: "body", SubscriptionID: "body-1"},
+	}
+	report, err := session.EnsureSubscriptions(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := subscriptionIDs(report.Added); ids != "body-1" {
+		t.Errorf("added %s, expected body-1", ids)
+	}
+	if ids := subscriptionIDs(report.Removed); ids != "stale-1" {
+		t.Errorf("removed %s, expected stale-1", ids)
+	}
+	if ids := subscriptionIDs(report.Unchanged); ids != "activities-1,all-1" {
+		t.Errorf("unchanged %s, expected activities-1,all-1", ids)
+	}
+	if ids := subscriptionIDs(server.Subscriptions("ABC123")); ids != "activities-1,all-1,body-1" {
+		t.Errorf("server has subscriptions %s", ids)
+	}
+
+	// a second run finds all desired subscriptions and changes nothing
+	requests := len(server.Requests())
+	report, err = session.EnsureSubscriptions(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Added) != 0 || len(report.Removed) != 0 || subscriptionIDs(report.Unchanged) != "activities-1,all-1,body-1" {
+		t.Errorf("unexpected report of the second run %+v", report)
+	}
+	if changes := countChanges(server.Requests()[requests:]); changes != 0 {
+		t.Errorf("second run sent %d changes", changes)
+	}
+}
+
+func TestEnsureSubscriptionsMatchesSubscriber(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	addSubscriptions(t, session,
+		fitbit.DesiredSubscription{CollectionPath: "activities", SubscriptionID: "activities-1", SubscriberID: "first"},
+		fitbit.DesiredSubscription{CollectionPath: "sleep", SubscriptionID: "sleep-1", SubscriberID: "first"},
+	)
+
+	report, err := session.EnsureSubscriptions([]fitbit.DesiredSubscription{
+		// a subscription of another subscriber is replaced
+		{CollectionPath: "activities", SubscriptionID: "activities-1", SubscriberID: "second"},
+		// without subscriber the subscription of every subscriber matches
+		{CollectionPath: "sleep", SubscriptionID: "sleep-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].SubscriberID != "first" {
+		t.Errorf("expected the subscription of the first subscriber to be removed, got %+v", report.Removed)
+	}
+	if len(report.Added) != 1 || report.Added[0].SubscriberID != "second" {
+		t.Errorf("expected the subscription of the second subscriber to be added, got %+v", report.Added)
+	}
+	if ids := subscriptionIDs(report.Unchanged); ids != "sleep-1" {
+		t.Errorf("unchanged %s, expected sleep-1", ids)
+	}
+
+	for _, subscription := range server.Subscriptions("ABC123") {
+		if subscription.SubscriptionID == "activities-1" && subscription.SubscriberID != "second" {
+			t.Errorf("subscription activities-1 belongs to subscriber %s", subscription.SubscriberID)
+		}
+	}
+}
+
+func TestEnsureSubscriptionsCollectsErrors(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	addSubscriptions(t, session,
+		fitbit.DesiredSubscription{CollectionPath: "activities", SubscriptionID: "activities-1"},
+		fitbit.DesiredSubscription{CollectionPath: "sleep", SubscriptionID: "stale-1"},
+		fitbit.DesiredSubscription{CollectionPath: "foods", SubscriptionID: "stale-2"},
+	)
+	server.Inject(fitbittest.Fault{Method: http.MethodDelete, Path: "/1/user/-/sleep/apiSubscriptions/stale-1.json", StatusCode: http.StatusInternalServerError})
+	server.Inject(fitbittest.Fault{Method: http.MethodPost, Path: "/1/user/-/body/apiSubscriptions/body-1.json", StatusCode: http.StatusInternalServerError})
+
+	report, err := session.EnsureSubscriptions([]fitbit.DesiredSubscription{
+		{CollectionPath: "activities", SubscriptionID: "activities-1"},
+		{CollectionPath: "body", SubscriptionID: "body-1"},
+		{CollectionPath: "weight", SubscriptionID: "weight-1"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "removing subscription stale-1") || !strings.Contains(err.Error(), "adding subscription body-1") {
+		t.Fatalf("expected the errors of all failed changes, got %v", err)
+	}
+
+	// the changes which succeeded are reported
+	if ids := subscriptionIDs(report.Removed); ids != "stale-2" {
+		t.Errorf("removed %s, expected stale-2", ids)
+	}
+	if ids := subscriptionIDs(report.Added); ids != "weight-1" {
+		t.Errorf("added %s, expected weight-1", ids)
+	}
+	if ids := subscriptionIDs(report.Unchanged); ids != "activities-1" {
+		t.Errorf("unchanged %s, expected activities-1", ids)
+	}
+	if ids := subscriptionIDs(server.Subscriptions("ABC123")); ids != "activities-1,stale-1,weight-1" {
+		t.Errorf("server has subscriptions %s", ids)
+	}
+}
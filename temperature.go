@@ -1,8 +1,9 @@
 package fitbit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"time"
 )
 
 // ! ATTENTION !
@@ -20,29 +21,81 @@ type TemperatureCore struct {
 // TemperatureCoreByDay returns the core temperature data for a given date
 // date must be in the format yyyy-MM-dd or today
 func (m *Session) TemperatureCoreByDay(day string) (TemperatureCore, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/temp/core/date/%s.json", day))
+	return m.TemperatureCoreByDayContext(context.Background(), day)
+}
+
+// TemperatureCoreByDayContext is like TemperatureCoreByDay but uses ctx for the request
+func (m *Session) TemperatureCoreByDayContext(ctx context.Context, day string) (TemperatureCore, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return TemperatureCore{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/temp/core/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return TemperatureCore{}, err
 	}
 
 	temperature := TemperatureCore{}
-	if err := json.Unmarshal(contents, &temperature); err != nil {
+	if err := m.decode("TemperatureCoreByDay", contents, &temperature); err != nil {
 		return TemperatureCore{}, err
 	}
 
 	return temperature, nil
 }
 
+// TemperatureCoreOn is like TemperatureCoreByDay but takes a Date
+func (m *Session) TemperatureCoreOn(day Date) (TemperatureCore, error) {
+	return m.TemperatureCoreOnContext(context.Background(), day)
+}
+
+// TemperatureCoreOnContext is like TemperatureCoreOn but uses ctx for the request
+func (m *Session) TemperatureCoreOnContext(ctx context.Context, day Date) (TemperatureCore, error) {
+	if err := checkDates(day); err != nil {
+		return TemperatureCore{}, err
+	}
+	return m.TemperatureCoreByDayContext(ctx, day.String())
+}
+
 // TemperatureCoreByDateRange returns the core temperature data for a given date range
 // date must be in the format yyyy-MM-dd or today
+// ranges longer than MaxDaysTemperature days are split into multiple requests
 func (m *Session) TemperatureCoreByDateRange(startDay string, endDay string) (TemperatureCore, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/temp/core/date/%s/%s.json", startDay, endDay))
+	return m.TemperatureCoreByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// TemperatureCoreByDateRangeContext is like TemperatureCoreByDateRange but uses ctx for the request
+func (m *Session) TemperatureCoreByDateRangeContext(ctx context.Context, startDay string, endDay string) (TemperatureCore, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return TemperatureCore{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysTemperature, m.temperatureCoreByDateRange)
+}
+
+// TemperatureCoreBetween is like TemperatureCoreByDateRange but takes Dates
+func (m *Session) TemperatureCoreBetween(startDay Date, endDay Date) (TemperatureCore, error) {
+	return m.TemperatureCoreBetweenContext(context.Background(), startDay, endDay)
+}
+
+// TemperatureCoreBetweenContext is like TemperatureCoreBetween but uses ctx for the request
+func (m *Session) TemperatureCoreBetweenContext(ctx context.Context, startDay Date, endDay Date) (TemperatureCore, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return TemperatureCore{}, err
+	}
+	return m.TemperatureCoreByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// temperatureCoreByDateRange requests a single date range
+func (m *Session) temperatureCoreByDateRange(ctx context.Context, startDay string, endDay string) (TemperatureCore, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/temp/core/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return TemperatureCore{}, err
 	}
 
 	temperature := TemperatureCore{}
-	if err := json.Unmarshal(contents, &temperature); err != nil {
+	if err := m.decode("TemperatureCoreByDateRange", contents, &temperature); err != nil {
 		return TemperatureCore{}, err
 	}
 
@@ -62,31 +115,107 @@ type TemperatureSkin struct {
 // TemperatureSkinByDay returns the skin temperature data for a given date
 // date must be in the format yyyy-MM-dd or today
 func (m *Session) TemperatureSkinByDay(day string) (TemperatureSkin, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/temp/skin/date/%s.json", day))
+	return m.TemperatureSkinByDayContext(context.Background(), day)
+}
+
+// TemperatureSkinByDayContext is like TemperatureSkinByDay but uses ctx for the request
+func (m *Session) TemperatureSkinByDayContext(ctx context.Context, day string) (TemperatureSkin, error) {
+	day, err := checkDate(day)
+	if err != nil {
+		return TemperatureSkin{}, err
+	}
+
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/temp/skin/date/%s.json", m.apiURL, day))
 	if err != nil {
 		return TemperatureSkin{}, err
 	}
 
 	temperature := TemperatureSkin{}
-	if err := json.Unmarshal(contents, &temperature); err != nil {
+	if err := m.decode("TemperatureSkinByDay", contents, &temperature); err != nil {
 		return TemperatureSkin{}, err
 	}
 
 	return temperature, nil
 }
 
+// TemperatureSkinOn is like TemperatureSkinByDay but takes a Date
+func (m *Session) TemperatureSkinOn(day Date) (TemperatureSkin, error) {
+	return m.TemperatureSkinOnContext(context.Background(), day)
+}
+
+// TemperatureSkinOnContext is like TemperatureSkinOn but uses ctx for the request
+func (m *Session) TemperatureSkinOnContext(ctx context.Context, day Date) (TemperatureSkin, error) {
+	if err := checkDates(day); err != nil {
+		return TemperatureSkin{}, err
+	}
+	return m.TemperatureSkinByDayContext(ctx, day.String())
+}
+
 // TemperatureSkinByDateRange returns the skin temperature data for a given date range
 // date must be in the format yyyy-MM-dd or today
+// ranges longer than MaxDaysTemperature days are split into multiple requests
 func (m *Session) TemperatureSkinByDateRange(startDay string, endDay string) (TemperatureSkin, error) {
-	contents, err := m.makeRequest(fmt.Sprintf("https://api.fitbit.com/1/user/-/temp/skin/date/%s/%s.json", startDay, endDay))
+	return m.TemperatureSkinByDateRangeContext(context.Background(), startDay, endDay)
+}
+
+// TemperatureSkinByDateRangeContext is like TemperatureSkinByDateRange but uses ctx for the request
+func (m *Session) TemperatureSkinByDateRangeContext(ctx context.Context, startDay string, endDay string) (TemperatureSkin, error) {
+	startDay, endDay, err := checkDateRange(startDay, endDay)
+	if err != nil {
+		return TemperatureSkin{}, err
+	}
+
+	return fetchDateRange(ctx, m, startDay, endDay, MaxDaysTemperature, m.temperatureSkinByDateRange)
+}
+
+// TemperatureSkinBetween is like TemperatureSkinByDateRange but takes Dates
+func (m *Session) TemperatureSkinBetween(startDay Date, endDay Date) (TemperatureSkin, error) {
+	return m.TemperatureSkinBetweenContext(context.Background(), startDay, endDay)
+}
+
+// TemperatureSkinBetweenContext is like TemperatureSkinBetween but uses ctx for the request
+func (m *Session) TemperatureSkinBetweenContext(ctx context.Context, startDay Date, endDay Date) (TemperatureSkin, error) {
+	if err := checkDates(startDay, endDay); err != nil {
+		return TemperatureSkin{}, err
+	}
+	return m.TemperatureSkinByDateRangeContext(ctx, startDay.String(), endDay.String())
+}
+
+// temperatureSkinByDateRange requests a single date range
+func (m *Session) temperatureSkinByDateRange(ctx context.Context, startDay string, endDay string) (TemperatureSkin, error) {
+	contents, err := m.makeRequest(ctx, fmt.Sprintf("%s/1/user/-/temp/skin/date/%s/%s.json", m.apiURL, startDay, endDay))
 	if err != nil {
 		return TemperatureSkin{}, err
 	}
 
 	temperature := TemperatureSkin{}
-	if err := json.Unmarshal(contents, &temperature); err != nil {
+	if err := m.decode("TemperatureSkinByDateRange", contents, &temperature); err != nil {
 		return TemperatureSkin{}, err
 	}
 
 	return temperature, nil
 }
+
+// Series returns the core temperature as normalized time series
+// loc is the timezone of the user, units the unit system of the session used to request the data
+func (t TemperatureCore) Series(loc *time.Location, units UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, entry := range t.TempCore {
+		if err := b.addAt(MetricTemperatureCore, false, entry.DateTime, entry.Value, units.temperature()); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
+
+// Series returns the skin temperature relative to the baseline of the user as normalized time series
+// loc is the timezone of the user, units the unit system of the session used to request the data
+func (t TemperatureSkin) Series(loc *time.Location, units UnitSystem) ([]Series, error) {
+	b := newSeriesBuilder(loc)
+	for _, entry := range t.TempSkin {
+		if err := b.addAt(MetricTemperatureSkinRelative, false, entry.DateTime, entry.Value.NightlyRelative, units.temperature()); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
@@ -0,0 +1,224 @@
+package fitbit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenStore persists the token of a session
+// Save is called synchronously and in order every time the token is rotated, before the request using the new token is sent
+type TokenStore interface {
+	// Load returns the stored token or ErrTokenNotFound if no token is stored
+	Load() (*oauth2.Token, error)
+	// Save persists the given token
+	Save(token *oauth2.Token) error
+}
+
+// TokenChangeStore adapts a TokenChange function to a TokenStore
+// Loading tokens is not supported and always returns ErrTokenNotFound
+type TokenChangeStore func(token *oauth2.Token)
+
+// Load always returns ErrTokenNotFound
+func (f TokenChangeStore) Load() (*oauth2.Token, error) {
+	return nil, ErrTokenNotFound
+}
+
+// Save calls the TokenChange function
+func (f TokenChangeStore) Save(token *oauth2.Token) error {
+	f(token)
+	return nil
+}
+
+// MemoryTokenStore keeps the token in memory
+type MemoryTokenStore struct {
+	mutex sync.RWMutex
+	token *oauth2.Token
+}
+
+// NewMemoryTokenStore creates a new in-memory token store, token can be nil
+func NewMemoryTokenStore(token *oauth2.Token) *MemoryTokenStore {
+	return &MemoryTokenStore{token: token}
+}
+
+// Load returns a copy of the stored token
+func (s *MemoryTokenStore) Load() (*oauth2.Token, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.token == nil {
+		return nil, ErrTokenNotFound
+	}
+	token := *s.token
+	return &token, nil
+}
+
+// Save stores a copy of the given token
+func (s *MemoryTokenStore) Save(token *oauth2.Token) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if token == nil {
+		s.token = nil
+		return nil
+	}
+	tokenCopy := *token
+	s.token = &tokenCopy
+	return nil
+}
+
+// FileTokenStore persists the token as JSON file
+type FileTokenStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewFileTokenStore creates a new token store writing to the given file path
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Load reads the token from the file
+func (s *FileTokenStore) Load() (*oauth2.Token, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token := oauth2.Token{}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("parsing token file %s: %w", s.path, err)
+	}
+	return &token, nil
+}
+
+// Save writes the token to a temporary file which replaces the previous file
+// to prevent a partially written token on crashes
+func (s *FileTokenStore) Save(token *oauth2.Token) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// SetTokenStore sets the token store used to persist rotated tokens
+func (m *Session) SetTokenStore(store TokenStore) {
+	m.mutex.Lock()
+	m.config.TokenStore = store
+	m.mutex.Unlock()
+}
+
+// tokenStore returns the configured token store or the TokenChange function as adapter
+func (m *Session) tokenStore() TokenStore {
+	m.mutex.RLock()
+	store := m.config.TokenStore
+	m.mutex.RUnlock()
+	if store != nil {
+		return store
+	}
+	if m.TokenChange != nil {
+		return TokenChangeStore(m.TokenChange)
+	}
+	return nil
+}
+
+// TokenDeleter is implemented by token stores which are able to delete the stored token
+type TokenDeleter interface {
+	// Delete removes the stored token
+	Delete() error
+}
+
+// Delete removes the stored token
+func (s *MemoryTokenStore) Delete() error {
+	return s.Save(nil)
+}
+
+// Delete removes the token file
+func (s *FileTokenStore) Delete() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// LoadToken loads the token from the token store and sets it for the session
+func (m *Session) LoadToken() error {
+	store := m.tokenStore()
+	if store == nil {
+		return errTokenStoreNotDefined
+	}
+	token, err := store.Load()
+	if err != nil {
+		return err
+	}
+	m.SetToken(token)
+	return nil
+}
+
+// storeToken persists the given token using the token store and sets it as current token if it is newer than the current one
+// Calls are serialized to persist tokens in order. If saving fails the current token is kept,
+// the token is saved again with the next request
+func (m *Session) storeToken(token *oauth2.Token) error {
+	m.storeMutex.Lock()
+	defer m.storeMutex.Unlock()
+
+	m.mutex.RLock()
+	current := m.token
+	m.mutex.RUnlock()
+
+	// drop tokens of sessions without token, the session was logged out while the request was running
+	if current == nil || (current.AccessToken == token.AccessToken && current.RefreshToken == token.RefreshToken) {
+		return nil
+	}
+	// drop tokens older than the current one, they can appear if multiple requests refreshed at the same time
+	if !current.Expiry.IsZero() && token.Expiry.Before(current.Expiry) {
+		return nil
+	}
+
+	if store := m.tokenStore(); store != nil {
+		if err := store.Save(token); err != nil {
+			return err
+		}
+	}
+
+	m.mutex.Lock()
+	// keep a token set using SetToken or Logout while the token was saved
+	if m.token == current {
+		m.token = token
+	}
+	m.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,169 @@
+package fitbit_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+	"github.com/Thomas2500/go-fitbit/fitbittest"
+	"golang.org/x/oauth2"
+)
+
+// failingStore is a token store which fails saving while fail is set
+type failingStore struct {
+	recordingStore
+	fail atomic.Bool
+}
+
+func (s *failingStore) Save(token *oauth2.Token) error {
+	if s.fail.Load() {
+		return errors.New("disk full")
+	}
+	return s.recordingStore.Save(token)
+}
+
+// expire sets an expired copy of the current token to force a refresh with the next request
+func expire(session *fitbit.Session) *oauth2.Token {
+	token := *session.Token()
+	token.Expiry = time.Now().Add(-time.Minute)
+	session.SetToken(&token)
+	return &token
+}
+
+func TestRotatedTokenIsStoredIfRequestFails(t *testing.T) {
+	var failAPI atomic.Bool
+	store := &recordingStore{}
+	_, session := newTestSession(t, func(c *fitbit.Config) {
+		c.TokenStore = store
+		c.Transport = fitbit.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if failAPI.Load() && !strings.HasPrefix(req.URL.Path, "/oauth2/") {
+				return nil, errors.New("connection reset")
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		})
+	})
+
+	old := expire(session)
+	failAPI.Store(true)
+	if _, err := session.Profile(0); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+
+	rotated := session.Token()
+	if rotated.RefreshToken == old.RefreshToken {
+		t.Fatal("token was not refreshed")
+	}
+	if !store.contains(rotated.RefreshToken) {
+		t.Fatal("rotated token was not stored before the request failed")
+	}
+
+	// a new session using the stored token is able to continue
+	failAPI.Store(false)
+	session.SetToken(rotated)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatalf("request using the stored token: %v", err)
+	}
+}
+
+func TestFailedTokenSaveIsRetried(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	store := &failingStore{}
+	session.SetTokenStore(store)
+
+	old := expire(session)
+	store.fail.Store(true)
+	requests := len(server.Requests())
+	_, err := session.Profile(0)
+	if err == nil || !strings.Contains(err.Error(), "saving rotated token") {
+		t.Fatalf("expected a save error, got %v", err)
+	}
+	for _, request := range server.Requests()[requests:] {
+		if request.Path == "/1/user/-/profile.json" {
+			t.Error("request was sent although the token was not saved")
+		}
+	}
+	if session.Token().RefreshToken != old.RefreshToken {
+		t.Error("unsaved token was set as current token")
+	}
+
+	store.fail.Store(false)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatalf("request after saving succeeded: %v", err)
+	}
+	rotated := session.Token()
+	if rotated.RefreshToken == old.RefreshToken || !store.contains(rotated.RefreshToken) {
+		t.Error("rotated token was not saved on the next request")
+	}
+}
+
+func TestInvalidRefreshTokenReturnsErrInvalidToken(t *testing.T) {
+	_, session := newTestSession(t, nil)
+	token := expire(session)
+	token.RefreshToken = "unknown"
+	session.SetToken(token)
+
+	_, err := session.Profile(0)
+	if !errors.Is(err, fitbit.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestRefreshNetworkErrorIsRetried(t *testing.T) {
+	var fail atomic.Bool
+	_, session := newTestSession(t, func(c *fitbit.Config) {
+		c.Middlewares = []fitbit.Middleware{func(next http.RoundTripper) http.RoundTripper {
+			return fitbit.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == "/oauth2/token" && fail.Swap(false) {
+					return nil, syscall.ECONNRESET
+				}
+				return next.RoundTrip(req)
+			})
+		}}
+	})
+	old := expire(session)
+	fail.Store(true)
+
+	// the refresh fails without reaching the token endpoint, the token is still valid
+	_, err := session.Profile(0)
+	if !errors.Is(err, syscall.ECONNRESET) || errors.Is(err, fitbit.ErrInvalidToken) {
+		t.Fatalf("expected the network error, got %v", err)
+	}
+
+	fail.Store(true)
+	session.SetToken(old)
+	session.SetRetryPolicy(fitbit.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	if _, err := session.Profile(0); err != nil {
+		t.Fatalf("expected the refresh to be retried, got %v", err)
+	}
+	if session.Token().RefreshToken == old.RefreshToken {
+		t.Error("token was not refreshed")
+	}
+}
+
+func TestRefreshServerErrorIsNotInvalidToken(t *testing.T) {
+	server, session := newTestSession(t, nil)
+	expire(session)
+	server.Inject(fitbittest.Fault{Path: "/oauth2/token", StatusCode: http.StatusServiceUnavailable})
+
+	_, err := session.Profile(0)
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) || errors.Is(err, fitbit.ErrInvalidToken) {
+		t.Fatalf("expected the error of the token endpoint, got %v", err)
+	}
+}
+
+func TestExpiredTokenWithoutRefreshTokenReturnsErrInvalidToken(t *testing.T) {
+	_, session := newTestSession(t, nil)
+	token := expire(session)
+	token.RefreshToken = ""
+	session.SetToken(token)
+
+	if _, err := session.Profile(0); !errors.Is(err, fitbit.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
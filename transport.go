@@ -0,0 +1,41 @@
+package fitbit
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// Middleware wraps a http.RoundTripper and is able to inspect or modify every request and response
+// It can be used for metrics, logging, custom headers or fault injection
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// buildTransport builds the transport chain based on the config
+// the first middleware is the outermost one and sees the request first
+func buildTransport(config Config) http.RoundTripper {
+	transport := config.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(config.Middlewares) - 1; i >= 0; i-- {
+		transport = config.Middlewares[i](transport)
+	}
+	return transport
+}
+
+// oAuthContext returns a context which makes oauth2 use the transport of the session for token requests
+func (m *Session) oAuthContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: m.transport,
+		Timeout:   m.config.Timeout,
+	})
+}
@@ -0,0 +1,115 @@
+package fitbit_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Thomas2500/go-fitbit"
+)
+
+// callLog records the order in which middlewares and the transport handle requests
+type callLog struct {
+	mutex sync.Mutex
+	calls map[string][]string
+}
+
+func (l *callLog) add(path string, call string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.calls == nil {
+		l.calls = make(map[string][]string)
+	}
+	l.calls[path] = append(l.calls[path], call)
+}
+
+func (l *callLog) reset() {
+	l.mutex.Lock()
+	l.calls = nil
+	l.mutex.Unlock()
+}
+
+// middleware returns a middleware logging when a request enters and its response leaves it
+func (l *callLog) middleware(name string) fitbit.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return fitbit.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			l.add(req.URL.Path, name+" request")
+			resp, err := next.RoundTrip(req)
+			l.add(req.URL.Path, name+" response")
+			return resp, err
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	log := &callLog{}
+	_, session := newTestSession(t, func(c *fitbit.Config) {
+		c.Transport = fitbit.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			log.add(req.URL.Path, "transport")
+			return http.DefaultTransport.RoundTrip(req)
+		})
+		c.Middlewares = []fitbit.Middleware{log.middleware("first"), log.middleware("second")}
+	})
+	log.reset()
+
+	// the expired token is refreshed before the API request is sent
+	expire(session)
+	if _, err := session.Profile(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"first request", "second request", "transport", "second response", "first response"}
+	for _, path := range []string{"/oauth2/token", "/1/user/-/profile.json"} {
+		if calls := log.calls[path]; !reflect.DeepEqual(calls, expected) {
+			t.Errorf("%s was handled in order %v, expected %v", path, calls, expected)
+		}
+	}
+}
+
+// slowMiddleware delays the requests of the given path while slow is set until the request is canceled or delay passed
+func slowMiddleware(path string, delay time.Duration, slow *atomic.Bool) fitbit.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return fitbit.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == path && slow.Load() {
+				select {
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				case <-time.After(delay):
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func TestConfigTimeout(t *testing.T) {
+	for _, path := range []string{"/1/user/-/profile.json", "/oauth2/token"} {
+		t.Run(path, func(t *testing.T) {
+			var slow atomic.Bool
+			_, session := newTestSession(t, func(c *fitbit.Config) {
+				c.Timeout = 50 * time.Millisecond
+				c.Middlewares = []fitbit.Middleware{slowMiddleware(path, time.Second, &slow)}
+			})
+			expire(session)
+			slow.Store(true)
+
+			start := time.Now()
+			_, err := session.Profile(0)
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				t.Fatalf("expected a timeout, got %v", err)
+			}
+			if errors.Is(err, fitbit.ErrInvalidToken) {
+				t.Errorf("timeout was reported as invalid token: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("request took %s despite the timeout", elapsed)
+			}
+		})
+	}
+}